@@ -1,41 +1,120 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 
 	"github.com/topboyasante/trunc8/internal/config"
-	"github.com/topboyasante/trunc8/internal/database"
+	"github.com/topboyasante/trunc8/internal/logging"
+	"github.com/topboyasante/trunc8/internal/metrics"
+	"github.com/topboyasante/trunc8/internal/repositories"
 	"github.com/topboyasante/trunc8/internal/server"
+	"github.com/topboyasante/trunc8/internal/tracing"
 )
 
 func main() {
 	config, err := config.LoadConfig()
 	if err != nil {
-		log.Fatalf("Failed to start server - configuration error: %v", err)
+		// The logger is configured by the config we failed to load, so this goes through the default one
+		slog.Error("Failed to start server - configuration error", "error", err)
+		os.Exit(1)
 	}
 
-	err = database.ConnectToMongo(config)
+	// Also picks up what is still logged through the log package, e.g. by net/http
+	slog.SetDefault(logging.New(os.Stderr, config.Log))
+
+	if err := run(config); err != nil {
+		slog.Error("Server failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+// run serves requests until the process is asked to stop, then shuts down in order:
+// stop accepting connections, let the requests in flight finish, write the clicks
+// still queued and close the storage.
+func run(cfg *config.Config) error {
+	// Cancelled on the first SIGINT or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing, os.Stdout)
 	if err != nil {
-		log.Fatalf("Server failed to start: %v", err)
+		return err
 	}
+	// Deferred before the storage is closed, so it runs after: writing the queued clicks makes spans too
+	defer flushTraces(shutdownTracing, cfg)
 
-	// Handle graceful shutdown
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	var repo repositories.Repository
+	repo, err = repositories.New(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	// Under the click queue, so the clicks it writes in the background are measured too
+	m := metrics.New()
+	repo = repositories.NewInstrumentedRepository(repo, m)
+	if cfg.Server.ClickBuffer > 0 {
+		repo = repositories.NewAsyncClickRepository(repo, cfg.Server.ClickBuffer)
+	}
+	defer closeStorage(repo, cfg)
+
+	srv, err := server.InitServer(ctx, cfg, repo, m)
+	if err != nil {
+		return err
+	}
+
+	serveErr := make(chan error, 1)
 	go func() {
-		<-c
-		log.Println("Shutting down...")
-		database.DisconnectMongo()
-		os.Exit(0)
+		slog.Info("Starting server", "port", cfg.Server.Port)
+		serveErr <- srv.ListenAndServe()
 	}()
 
-	srv := server.InitServer(config)
-	log.Printf("Starting server on port: %s", config.Server.Port)
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	// From here on a second signal kills the process right away
+	stop()
+	slog.Info("Shutting down, waiting for requests in flight", "timeout", cfg.Server.ShutdownTimeout.String())
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("Requests still in flight were cut off", "error", err)
+	}
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// closeStorage writes the queued clicks and closes the storage. It gets its own deadline,
+// so a slow drain of requests doesn't leave it no time to flush.
+func closeStorage(repo repositories.Repository, cfg *config.Config) {
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	if err := repo.Close(ctx); err != nil {
+		slog.Error("Failed to close storage", "error", err)
+		return
+	}
+	slog.Info("Server stopped")
+}
+
+// flushTraces exports the spans that are still buffered.
+func flushTraces(shutdown func(context.Context) error, cfg *config.Config) {
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
 
-	if err := srv.ListenAndServe(); err != nil {
-		log.Fatalf("Server failed to start: %v", err)
+	if err := shutdown(ctx); err != nil {
+		slog.Error("Failed to export the last traces", "error", err)
 	}
 }
@@ -0,0 +1,88 @@
+// Package auth creates API keys and carries the key a request was made with.
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+
+	"github.com/topboyasante/trunc8/internal/models"
+)
+
+const (
+	// keyPrefix makes our keys easy to spot, e.g. by secret scanners
+	keyPrefix = "t8_"
+	keyLength = 32 // random characters after the prefix, about 190 bits
+
+	// PrefixLength is how much of a key we keep in clear to recognize it
+	PrefixLength = len(keyPrefix) + 6
+
+	alphabet = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+)
+
+// NoWorkspace is the workspace of keys that should belong to one but don't, like keys created
+// before workspaces existed. No link lives there, so such a key sees nothing instead of everything.
+const NoWorkspace = "!none"
+
+// GenerateKey returns a new random API key like "t8_4fQ...".
+func GenerateKey() (string, error) {
+	key := make([]byte, keyLength)
+	max := big.NewInt(int64(len(alphabet)))
+
+	for i := range key {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		key[i] = alphabet[n.Int64()]
+	}
+
+	return keyPrefix + string(key), nil
+}
+
+// HashKey returns the hash we store and look keys up by.
+// Keys are long and random, so unlike passwords a single fast hash is enough:
+// there is nothing to gain from guessing them one by one.
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+type contextKey struct{}
+
+// WithKey returns a copy of ctx that carries the API key of the request.
+func WithKey(ctx context.Context, key *models.APIKey) context.Context {
+	return context.WithValue(ctx, contextKey{}, key)
+}
+
+// KeyFromContext returns the API key the request was made with.
+// ok is false for public routes, which don't need a key.
+func KeyFromContext(ctx context.Context) (key *models.APIKey, ok bool) {
+	key, ok = ctx.Value(contextKey{}).(*models.APIKey)
+	return key, ok
+}
+
+// WorkspaceID returns the workspace the request is limited to.
+// It's empty when the request may see every workspace: admin keys without a workspace,
+// and calls that aren't made on behalf of an API key at all. Repositories treat "" as every
+// workspace, so any other key without a workspace gets NoWorkspace.
+func WorkspaceID(ctx context.Context) string {
+	key, ok := KeyFromContext(ctx)
+	if !ok {
+		return ""
+	}
+	if key.WorkspaceID == "" && !key.HasScope(models.ScopeAdmin) {
+		return NoWorkspace
+	}
+	return key.WorkspaceID
+}
+
+// UserID returns the user the request was made by, if any.
+func UserID(ctx context.Context) string {
+	if key, ok := KeyFromContext(ctx); ok {
+		return key.UserID
+	}
+	return ""
+}
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/topboyasante/trunc8/internal/models"
+)
+
+func TestGenerateKey(t *testing.T) {
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if !strings.HasPrefix(key, "t8_") {
+		t.Errorf("Expected key to start with 't8_', got '%s'", key)
+	}
+
+	if len(key) != len("t8_")+32 {
+		t.Errorf("Expected key of %d characters, got %d", len("t8_")+32, len(key))
+	}
+
+	other, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if key == other {
+		t.Error("Expected two keys to be different")
+	}
+}
+
+func TestHashKey(t *testing.T) {
+	hash := HashKey("t8_secret")
+
+	if hash != HashKey("t8_secret") {
+		t.Error("Expected the same key to give the same hash")
+	}
+
+	if hash == HashKey("t8_other") {
+		t.Error("Expected different keys to give different hashes")
+	}
+
+	if strings.Contains(hash, "secret") || len(hash) != 64 {
+		t.Errorf("Expected a hex sha256 hash, got '%s'", hash)
+	}
+}
+
+func TestKeyFromContext(t *testing.T) {
+	if _, ok := KeyFromContext(context.Background()); ok {
+		t.Error("Expected no key in an empty context")
+	}
+
+	key := &models.APIKey{ID: "key-1", Scopes: []string{models.ScopeLinksRead}}
+	got, ok := KeyFromContext(WithKey(context.Background(), key))
+
+	if !ok || got != key {
+		t.Errorf("Expected the key stored in the context, got %v", got)
+	}
+}
+
+func TestWorkspaceID(t *testing.T) {
+	if WorkspaceID(context.Background()) != "" || UserID(context.Background()) != "" {
+		t.Error("Expected no workspace or user without a key")
+	}
+
+	ctx := WithKey(context.Background(), &models.APIKey{UserID: "user-1", WorkspaceID: "ws-1"})
+
+	if WorkspaceID(ctx) != "ws-1" {
+		t.Errorf("Expected workspace 'ws-1', got '%s'", WorkspaceID(ctx))
+	}
+
+	if UserID(ctx) != "user-1" {
+		t.Errorf("Expected user 'user-1', got '%s'", UserID(ctx))
+	}
+
+	admin := WithKey(context.Background(), &models.APIKey{Scopes: []string{models.ScopeAdmin}})
+	if WorkspaceID(admin) != "" {
+		t.Errorf("Expected admin keys to see every workspace, got '%s'", WorkspaceID(admin))
+	}
+
+	// A key that should have a workspace but doesn't must not see every workspace
+	unscoped := WithKey(context.Background(), &models.APIKey{Scopes: []string{models.ScopeLinksRead}})
+	if WorkspaceID(unscoped) != NoWorkspace {
+		t.Errorf("Expected NoWorkspace, got '%s'", WorkspaceID(unscoped))
+	}
+}
@@ -0,0 +1,106 @@
+// Package cache implements the size-bounded LRU cache with expiring entries used in front of the redirect lookups.
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// LRU holds up to size entries. Each entry expires after the TTL it was stored with;
+// when the cache is full, the entry that was used least recently is evicted.
+type LRU[V any] struct {
+	size int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // front is the most recently used
+
+	now func() time.Time // replaced in tests
+}
+
+type entry[V any] struct {
+	key       string
+	value     V
+	expiresAt time.Time
+}
+
+// New returns a cache that holds up to size entries.
+func New[V any](size int) *LRU[V] {
+	if size < 1 {
+		size = 1
+	}
+	return &LRU[V]{
+		size:    size,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+		now:     time.Now,
+	}
+}
+
+// Get returns the value stored under key, unless it's missing or expired.
+func (c *LRU[V]) Get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero V
+	element, ok := c.entries[key]
+	if !ok {
+		return zero, false
+	}
+
+	e := element.Value.(*entry[V])
+	if !c.now().Before(e.expiresAt) {
+		c.remove(element)
+		return zero, false
+	}
+
+	c.order.MoveToFront(element)
+	return e.value, true
+}
+
+// Set stores value under key for ttl, evicting the least recently used entry if the cache is full.
+func (c *LRU[V]) Set(key string, value V, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := c.now().Add(ttl)
+
+	if element, ok := c.entries[key]; ok {
+		e := element.Value.(*entry[V])
+		e.value = value
+		e.expiresAt = expiresAt
+		c.order.MoveToFront(element)
+		return
+	}
+
+	if c.order.Len() >= c.size {
+		c.remove(c.order.Back())
+	}
+
+	c.entries[key] = c.order.PushFront(&entry[V]{key: key, value: value, expiresAt: expiresAt})
+}
+
+// Delete drops the entry stored under key, if there is one.
+func (c *LRU[V]) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+}
+
+// Len returns the number of entries, including expired ones that haven't been evicted yet.
+func (c *LRU[V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+// remove drops element from both the list and the map. The caller must hold the lock.
+func (c *LRU[V]) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*entry[V]).key)
+}
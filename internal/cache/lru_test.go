@@ -0,0 +1,93 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+// clock is a fake time source the tests move forward by hand
+type clock struct {
+	t time.Time
+}
+
+func (c *clock) now() time.Time {
+	return c.t
+}
+
+func newTestCache(size int) (*LRU[string], *clock) {
+	c := &clock{t: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)}
+	l := New[string](size)
+	l.now = c.now
+	return l, c
+}
+
+func TestLRU_GetSet(t *testing.T) {
+	l, _ := newTestCache(2)
+
+	if _, ok := l.Get("a"); ok {
+		t.Fatal("Expected a miss on an empty cache")
+	}
+
+	l.Set("a", "1", time.Minute)
+	l.Set("a", "2", time.Minute)
+
+	value, ok := l.Get("a")
+	if !ok || value != "2" {
+		t.Errorf("Expected '2', got %q, %v", value, ok)
+	}
+
+	if l.Len() != 1 {
+		t.Errorf("Expected 1 entry, got %d", l.Len())
+	}
+}
+
+func TestLRU_EvictsLeastRecentlyUsed(t *testing.T) {
+	l, _ := newTestCache(2)
+
+	l.Set("a", "1", time.Minute)
+	l.Set("b", "2", time.Minute)
+	l.Get("a") // b is now the least recently used
+	l.Set("c", "3", time.Minute)
+
+	if _, ok := l.Get("b"); ok {
+		t.Error("Expected b to be evicted")
+	}
+	if _, ok := l.Get("a"); !ok {
+		t.Error("Expected a to be kept")
+	}
+	if _, ok := l.Get("c"); !ok {
+		t.Error("Expected c to be kept")
+	}
+}
+
+func TestLRU_Expiry(t *testing.T) {
+	l, c := newTestCache(2)
+
+	l.Set("a", "1", 10*time.Second)
+
+	c.t = c.t.Add(9 * time.Second)
+	if _, ok := l.Get("a"); !ok {
+		t.Fatal("Expected a hit before the TTL")
+	}
+
+	c.t = c.t.Add(time.Second)
+	if _, ok := l.Get("a"); ok {
+		t.Fatal("Expected a miss once the TTL has passed")
+	}
+
+	if l.Len() != 0 {
+		t.Errorf("Expected the expired entry to be dropped, got %d entries", l.Len())
+	}
+}
+
+func TestLRU_Delete(t *testing.T) {
+	l, _ := newTestCache(2)
+
+	l.Set("a", "1", time.Minute)
+	l.Delete("a")
+	l.Delete("missing")
+
+	if _, ok := l.Get("a"); ok {
+		t.Error("Expected a miss after Delete")
+	}
+}
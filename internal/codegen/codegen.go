@@ -0,0 +1,175 @@
+// Package codegen contains the strategies we can use to create short codes.
+// The strategy is picked with the CODE_STRATEGY environment variable.
+package codegen
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"math/big"
+	"strconv"
+)
+
+const base62 = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// Sequence hands out increasing numbers that survive restarts.
+// It's implemented by the repository, so every server instance shares the same counter.
+type Sequence interface {
+	NextSequence(ctx context.Context, name string) (int64, error)
+}
+
+// sequenceName is the counter used for link codes
+const sequenceName = "links"
+
+// Random creates unguessable codes from a cryptographically secure random source.
+// It's the right choice for private links, because codes can't be enumerated.
+type Random struct{}
+
+func NewRandom() *Random {
+	return &Random{}
+}
+
+func (g *Random) Generate(ctx context.Context, originalURL string, length, attempt int) (string, error) {
+	code := make([]byte, length)
+	max := big.NewInt(int64(len(base62)))
+
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = base62[n.Int64()]
+	}
+
+	return string(code), nil
+}
+
+// Counter encodes an ever increasing number in base62.
+// It gives the shortest possible codes (62 links fit in one character), which is nice for SMS,
+// but anyone can guess the next code.
+type Counter struct {
+	sequence Sequence
+}
+
+func NewCounter(sequence Sequence) *Counter {
+	return &Counter{sequence: sequence}
+}
+
+// Generate ignores length, the code is as long as the counter needs.
+func (g *Counter) Generate(ctx context.Context, originalURL string, length, attempt int) (string, error) {
+	n, err := g.sequence.NextSequence(ctx, sequenceName)
+	if err != nil {
+		return "", err
+	}
+	return encode(uint64(n), base62, 0), nil
+}
+
+// Hashids obfuscates a counter in the style of hashids: codes are short and guaranteed unique
+// like Counter's, but consecutive links don't get consecutive codes.
+// It's obfuscation, not security: someone who knows the salt can decode them.
+type Hashids struct {
+	sequence  Sequence
+	salt      string
+	alphabet  string
+	minLength int
+}
+
+func NewHashids(sequence Sequence, salt string, minLength int) *Hashids {
+	return &Hashids{
+		sequence:  sequence,
+		salt:      salt,
+		alphabet:  shuffle(base62, salt),
+		minLength: minLength,
+	}
+}
+
+func (g *Hashids) Generate(ctx context.Context, originalURL string, length, attempt int) (string, error) {
+	n, err := g.sequence.NextSequence(ctx, sequenceName)
+	if err != nil {
+		return "", err
+	}
+	return g.encode(uint64(n)), nil
+}
+
+// encode writes n as a "lottery" character followed by n in an alphabet that is shuffled
+// with that lottery character. Since the lottery character depends on n, neighbouring numbers
+// end up in completely different alphabets. The result can always be decoded back into n,
+// so two numbers never share a code.
+func (g *Hashids) encode(n uint64) string {
+	lottery := g.alphabet[n%uint64(len(g.alphabet))]
+	alphabet := shuffle(g.alphabet, string(lottery)+g.salt)
+
+	return string(lottery) + encode(n, alphabet, g.minLength-1)
+}
+
+// URLHash derives the code from a hash of the original URL,
+// so the same URL always gets the same code on every instance.
+type URLHash struct{}
+
+func NewURLHash() *URLHash {
+	return &URLHash{}
+}
+
+// Generate returns the first length characters of the base62 encoded SHA-256 of the URL.
+// The first code of a URL always collides with its earlier links, like when it's shortened again
+// with tags, so later attempts hash the URL with the attempt number to get a different code.
+func (g *URLHash) Generate(ctx context.Context, originalURL string, length, attempt int) (string, error) {
+	input := originalURL
+	if attempt > 0 {
+		// A NUL byte can't be part of a URL, so no other URL hashes to the same input
+		input += "\x00" + strconv.Itoa(attempt)
+	}
+	sum := sha256.Sum256([]byte(input))
+
+	digits := new(big.Int).SetBytes(sum[:]).Text(62)
+	if length > len(digits) {
+		length = len(digits)
+	}
+	return digits[:length], nil
+}
+
+// DerivedFromURL tells the service that codes come from the URL rather than from the keyspace:
+// a collision is usually the URL meeting its own earlier link, not a sign that short codes are running out.
+func (g *URLHash) DerivedFromURL() bool {
+	return true
+}
+
+// encode writes n in the base of the given alphabet, padded on the left with the
+// alphabet's "zero" until it is at least minLength characters long.
+func encode(n uint64, alphabet string, minLength int) string {
+	base := uint64(len(alphabet))
+
+	var code []byte
+	for {
+		code = append(code, alphabet[n%base])
+		n /= base
+		if n == 0 {
+			break
+		}
+	}
+	for len(code) < minLength {
+		code = append(code, alphabet[0])
+	}
+
+	// We built the digits from least to most significant, so flip them around
+	for i, j := 0, len(code)-1; i < j; i, j = i+1, j-1 {
+		code[i], code[j] = code[j], code[i]
+	}
+	return string(code)
+}
+
+// shuffle deterministically reorders alphabet based on key, using a Fisher-Yates shuffle
+// driven by the SHA-256 of the key. The same key always gives the same order.
+func shuffle(alphabet, key string) string {
+	if key == "" {
+		return alphabet
+	}
+
+	result := []byte(alphabet)
+	sum := sha256.Sum256([]byte(key))
+	for i := len(result) - 1; i > 0; i-- {
+		j := int(sum[i%len(sum)]+byte(i)) % (i + 1)
+		result[i], result[j] = result[j], result[i]
+	}
+	return string(result)
+}
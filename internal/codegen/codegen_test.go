@@ -0,0 +1,165 @@
+package codegen
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// In-memory sequence for testing
+type mockSequence struct {
+	n   int64
+	err error
+}
+
+func (m *mockSequence) NextSequence(ctx context.Context, name string) (int64, error) {
+	if m.err != nil {
+		return 0, m.err
+	}
+	m.n++
+	return m.n, nil
+}
+
+func TestRandom_Generate(t *testing.T) {
+	generator := NewRandom()
+	codes := make(map[string]bool)
+
+	for i := 0; i < 1000; i++ {
+		code, err := generator.Generate(context.Background(), "https://example.com", 8, 0)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if len(code) != 8 {
+			t.Errorf("Expected code length to be 8, got %d", len(code))
+		}
+
+		for _, char := range code {
+			if !strings.ContainsRune(base62, char) {
+				t.Errorf("Generated code contains invalid character: %c", char)
+			}
+		}
+
+		// With 62^8 possible codes, a collision here means the generator is broken
+		if codes[code] {
+			t.Errorf("Generated duplicate code %s", code)
+		}
+		codes[code] = true
+	}
+}
+
+func TestCounter_Generate(t *testing.T) {
+	generator := NewCounter(&mockSequence{n: 59})
+
+	expected := []string{"Y", "Z", "10", "11"}
+	for _, want := range expected {
+		code, err := generator.Generate(context.Background(), "https://example.com", 4, 0)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if code != want {
+			t.Errorf("Expected code '%s', got '%s'", want, code)
+		}
+	}
+}
+
+func TestCounter_SequenceError(t *testing.T) {
+	generator := NewCounter(&mockSequence{err: errors.New("database error")})
+
+	if _, err := generator.Generate(context.Background(), "https://example.com", 4, 0); err == nil {
+		t.Fatal("Expected error from sequence, got nil")
+	}
+}
+
+func TestHashids_Generate(t *testing.T) {
+	generator := NewHashids(&mockSequence{}, "pepper", 5)
+	codes := make(map[string]bool)
+
+	var previous string
+	for i := 0; i < 5000; i++ {
+		code, err := generator.Generate(context.Background(), "https://example.com", 0, 0)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if len(code) < 5 {
+			t.Errorf("Expected code to be at least 5 characters, got '%s'", code)
+		}
+
+		// Every number must map to its own code
+		if codes[code] {
+			t.Fatalf("Generated duplicate code %s", code)
+		}
+		codes[code] = true
+
+		if previous != "" && code[1:] == previous[1:] {
+			t.Errorf("Expected consecutive codes to look unrelated, got '%s' and '%s'", previous, code)
+		}
+		previous = code
+	}
+}
+
+func TestHashids_SaltChangesCodes(t *testing.T) {
+	a := NewHashids(&mockSequence{}, "pepper", 5)
+	b := NewHashids(&mockSequence{}, "paprika", 5)
+
+	codeA, _ := a.Generate(context.Background(), "", 0, 0)
+	codeB, _ := b.Generate(context.Background(), "", 0, 0)
+
+	if codeA == codeB {
+		t.Errorf("Expected different salts to produce different codes, both got '%s'", codeA)
+	}
+}
+
+func TestURLHash_Generate(t *testing.T) {
+	generator := NewURLHash()
+
+	first, err := generator.Generate(context.Background(), "https://example.com", 6, 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(first) != 6 {
+		t.Errorf("Expected code length to be 6, got %d", len(first))
+	}
+
+	second, _ := generator.Generate(context.Background(), "https://example.com", 6, 0)
+	if first != second {
+		t.Errorf("Expected the same URL to get the same code, got '%s' and '%s'", first, second)
+	}
+
+	longer, _ := generator.Generate(context.Background(), "https://example.com", 8, 0)
+	if !strings.HasPrefix(longer, first) {
+		t.Errorf("Expected longer codes to extend shorter ones, got '%s' and '%s'", first, longer)
+	}
+
+	other, _ := generator.Generate(context.Background(), "https://example.org", 6, 0)
+	if other == first {
+		t.Errorf("Expected different URLs to get different codes, both got '%s'", first)
+	}
+
+	retry, _ := generator.Generate(context.Background(), "https://example.com", 6, 1)
+	if len(retry) != 6 || retry == first {
+		t.Errorf("Expected another attempt to get another code of the same length, got '%s' and '%s'", first, retry)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		n         uint64
+		minLength int
+		expected  string
+	}{
+		{0, 0, "0"},
+		{61, 0, "Z"},
+		{62, 0, "10"},
+		{62, 4, "0010"},
+	}
+
+	for _, tt := range tests {
+		if code := encode(tt.n, base62, tt.minLength); code != tt.expected {
+			t.Errorf("encode(%d, %d): expected '%s', got '%s'", tt.n, tt.minLength, tt.expected, code)
+		}
+	}
+}
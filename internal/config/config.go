@@ -2,22 +2,97 @@ package config
 
 import (
 	"fmt"
+	"log/slog"
+	"net/netip"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
+	Server    ServerConfig
+	Database  DatabaseConfig
+	Shortener ShortenerConfig
+	Auth      AuthConfig
+	RateLimit RateLimitConfig
+	Cache     CacheConfig
+	Log       LogConfig
+	Tracing   TracingConfig
+	Analytics AnalyticsConfig
 }
 
 type ServerConfig struct {
 	Port string // env variables are read as strings, so we keep it as a string
+
+	DefaultRedirectType int // HTTP status for links that don't pick their own
+
+	ShutdownTimeout time.Duration // how long requests in flight get to finish on shutdown
+	ClickBuffer     int           // clicks queued for writing in the background, 0 writes them during the redirect
+
+	TrustedProxies []netip.Prefix // proxies whose X-Forwarded-For and Forwarded headers we believe
 }
 
 type DatabaseConfig struct {
-	Url string
+	Url  string // the scheme picks the storage backend, e.g. mongodb:// or memory://
+	Name string // MongoDB database, used when the URL doesn't name one
+}
+
+type ShortenerConfig struct {
+	CodeStrategy string // random, counter, hashids or hash
+	CodeLength   int    // starting length for random and hash codes, minimum length for hashids
+	HashidsSalt  string
+
+	AllowedSchemes []string // URL schemes we are willing to shorten, e.g. http and https
+
+	BulkLimit int // most links a single POST /api/links/bulk can create
+}
+
+type AuthConfig struct {
+	AdminAPIKey string // a key with the admin scope that doesn't live in the database
+}
+
+type AnalyticsConfig struct {
+	IPHashSecret string // key of the HMAC visitor IPs are stored as
+}
+
+// RateLimitConfig holds the limits per client, in requests per minute with bursts of up to Burst requests.
+// A limit of 0 turns rate limiting off for that endpoint.
+type RateLimitConfig struct {
+	Shorten      int
+	ShortenBurst int
+
+	Redirect      int
+	RedirectBurst int
+}
+
+// CacheConfig sizes the cache of redirect lookups. A size of 0 turns it off.
+type CacheConfig struct {
+	Size        int
+	TTL         time.Duration
+	NegativeTTL time.Duration // how long a code that doesn't exist is remembered
+}
+
+type LogConfig struct {
+	Level  slog.Level // messages below this level are dropped
+	Format string     // json, or text for reading logs in a terminal
+}
+
+// TracingConfig picks where spans go. The OTLP exporter reads its endpoint and headers
+// from the standard OTEL_EXPORTER_OTLP_* environment variables.
+type TracingConfig struct {
+	Exporter    string  // none, stdout or otlp
+	SampleRatio float64 // share of new traces that are recorded; requests that come with a trace follow its decision
+}
+
+// The code strategies we know how to build
+var codeStrategies = map[string]bool{
+	"random":  true,
+	"counter": true,
+	"hashids": true,
+	"hash":    true,
 }
 
 func getRequiredEnv(key string) (string, error) {
@@ -36,6 +111,90 @@ func getEnvWithDefault(key, defaultValue string) string {
 	return value
 }
 
+// getIntEnvWithDefault works like getEnvWithDefault, but for numbers.
+// A value that isn't a number is an error instead of silently falling back to the default.
+func getIntEnvWithDefault(key string, defaultValue int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("environment variable %s must be a number, got %q", key, value)
+	}
+	return n, nil
+}
+
+// getDurationEnvWithDefault reads a duration like "30s" or "5m".
+func getDurationEnvWithDefault(key string, defaultValue time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("environment variable %s must be a duration like 30s or 5m, got %q", key, value)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("%s cannot be negative, got %s", key, value)
+	}
+	return d, nil
+}
+
+// getFloatEnvWithDefault works like getIntEnvWithDefault, for numbers like 0.25.
+func getFloatEnvWithDefault(key string, defaultValue float64) (float64, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("environment variable %s must be a number, got %q", key, value)
+	}
+	return f, nil
+}
+
+// getListEnvWithDefault reads a comma separated list like "http,https".
+// Items are trimmed and lowercased, empty items are dropped.
+func getListEnvWithDefault(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// getPrefixListEnv reads a comma separated list of IP addresses and CIDR ranges like "10.0.0.0/8,192.0.2.1".
+// An address is read as the range holding only that address.
+func getPrefixListEnv(key string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, item := range getListEnvWithDefault(key, nil) {
+		if !strings.Contains(item, "/") {
+			addr, err := netip.ParseAddr(item)
+			if err != nil {
+				return nil, fmt.Errorf("%s must list IP addresses or CIDR ranges, got %q", key, item)
+			}
+			addr = addr.Unmap()
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(item)
+		if err != nil {
+			return nil, fmt.Errorf("%s must list IP addresses or CIDR ranges, got %q", key, item)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
 // Config vs *Config
 // func LoadConfig() (Config, error) - Returns the actual struct
 
@@ -58,14 +217,173 @@ func LoadConfig() (*Config, error) {
 		return nil, err
 	}
 
-	fmt.Println("Loaded environment variables successfully")
+	codeStrategy := getEnvWithDefault("CODE_STRATEGY", "random")
+	if !codeStrategies[codeStrategy] {
+		return nil, fmt.Errorf("unknown CODE_STRATEGY %q, expected random, counter, hashids or hash", codeStrategy)
+	}
+
+	codeLength, err := getIntEnvWithDefault("CODE_LENGTH", 4)
+	if err != nil {
+		return nil, err
+	}
+	if codeLength < 1 || codeLength > 12 {
+		return nil, fmt.Errorf("CODE_LENGTH must be between 1 and 12, got %d", codeLength)
+	}
+
+	bulkLimit, err := getIntEnvWithDefault("BULK_MAX_LINKS", 500)
+	if err != nil {
+		return nil, err
+	}
+	if bulkLimit < 1 {
+		return nil, fmt.Errorf("BULK_MAX_LINKS must be at least 1, got %d", bulkLimit)
+	}
+
+	// 302 by default: browsers cache 301s forever, which hides clicks and pins the destination
+	defaultRedirectType, err := getIntEnvWithDefault("DEFAULT_REDIRECT_TYPE", 302)
+	if err != nil {
+		return nil, err
+	}
+	switch defaultRedirectType {
+	case 301, 302, 307, 308:
+	default:
+		return nil, fmt.Errorf("DEFAULT_REDIRECT_TYPE must be 301, 302, 307 or 308, got %d", defaultRedirectType)
+	}
+
+	rateLimit := RateLimitConfig{}
+	for _, setting := range []struct {
+		key          string
+		defaultValue int
+		value        *int
+	}{
+		{"RATE_LIMIT_SHORTEN", 60, &rateLimit.Shorten},
+		{"RATE_LIMIT_SHORTEN_BURST", 10, &rateLimit.ShortenBurst},
+		{"RATE_LIMIT_REDIRECT", 600, &rateLimit.Redirect},
+		{"RATE_LIMIT_REDIRECT_BURST", 100, &rateLimit.RedirectBurst},
+	} {
+		if *setting.value, err = getIntEnvWithDefault(setting.key, setting.defaultValue); err != nil {
+			return nil, err
+		}
+		if *setting.value < 0 {
+			return nil, fmt.Errorf("%s cannot be negative, got %d", setting.key, *setting.value)
+		}
+	}
+
+	// Orchestrators usually kill the process 30 seconds after asking it to stop, so we stop a bit earlier
+	shutdownTimeout, err := getDurationEnvWithDefault("SHUTDOWN_TIMEOUT", 25*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	// Without any time, requests in flight are cut off and queued clicks are lost on every deploy
+	if shutdownTimeout == 0 {
+		return nil, fmt.Errorf("SHUTDOWN_TIMEOUT must be positive, got %s", os.Getenv("SHUTDOWN_TIMEOUT"))
+	}
+	clickBuffer, err := getIntEnvWithDefault("CLICK_BUFFER", 1000)
+	if err != nil {
+		return nil, err
+	}
+	if clickBuffer < 0 {
+		return nil, fmt.Errorf("CLICK_BUFFER cannot be negative, got %d", clickBuffer)
+	}
+
+	cacheSize, err := getIntEnvWithDefault("CACHE_SIZE", 10000)
+	if err != nil {
+		return nil, err
+	}
+	if cacheSize < 0 {
+		return nil, fmt.Errorf("CACHE_SIZE cannot be negative, got %d", cacheSize)
+	}
+	cacheTTL, err := getDurationEnvWithDefault("CACHE_TTL", time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	cacheNegativeTTL, err := getDurationEnvWithDefault("CACHE_NEGATIVE_TTL", 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	// Anyone holding this key can do anything, so it shouldn't be guessable
+	adminAPIKey := os.Getenv("ADMIN_API_KEY")
+	if adminAPIKey != "" && len(adminAPIKey) < 20 {
+		return nil, fmt.Errorf("ADMIN_API_KEY must be at least 20 characters long")
+	}
+
+	// Anyone holding this secret can recover visitor IPs from their hashes by trying them all
+	ipHashSecret := os.Getenv("IP_HASH_SECRET")
+	if ipHashSecret != "" && len(ipHashSecret) < 20 {
+		return nil, fmt.Errorf("IP_HASH_SECRET must be at least 20 characters long")
+	}
+
+	trustedProxies, err := getPrefixListEnv("TRUSTED_PROXIES")
+	if err != nil {
+		return nil, err
+	}
+
+	var logLevel slog.Level
+	if err := logLevel.UnmarshalText([]byte(getEnvWithDefault("LOG_LEVEL", "info"))); err != nil {
+		return nil, fmt.Errorf("LOG_LEVEL must be debug, info, warn or error, got %q", os.Getenv("LOG_LEVEL"))
+	}
+	logFormat := getEnvWithDefault("LOG_FORMAT", "json")
+	if logFormat != "json" && logFormat != "text" {
+		return nil, fmt.Errorf("LOG_FORMAT must be json or text, got %q", logFormat)
+	}
+
+	tracingExporter := getEnvWithDefault("TRACING_EXPORTER", "none")
+	switch tracingExporter {
+	case "none", "stdout", "otlp":
+	default:
+		return nil, fmt.Errorf("TRACING_EXPORTER must be none, stdout or otlp, got %q", tracingExporter)
+	}
+	sampleRatio, err := getFloatEnvWithDefault("TRACING_SAMPLE_RATIO", 1)
+	if err != nil {
+		return nil, err
+	}
+	if sampleRatio < 0 || sampleRatio > 1 {
+		return nil, fmt.Errorf("TRACING_SAMPLE_RATIO must be between 0 and 1, got %v", sampleRatio)
+	}
 
 	return &Config{
 		Server: ServerConfig{
 			Port: getEnvWithDefault("SERVER_PORT", "8080"),
+
+			DefaultRedirectType: defaultRedirectType,
+
+			ShutdownTimeout: shutdownTimeout,
+			ClickBuffer:     clickBuffer,
+
+			TrustedProxies: trustedProxies,
 		},
 		Database: DatabaseConfig{
-			Url: dbURL,
+			Url:  dbURL,
+			Name: getEnvWithDefault("DATABASE_NAME", "trunc8-db"),
+		},
+		Shortener: ShortenerConfig{
+			CodeStrategy: codeStrategy,
+			CodeLength:   codeLength,
+			HashidsSalt:  os.Getenv("HASHIDS_SALT"),
+
+			AllowedSchemes: getListEnvWithDefault("ALLOWED_SCHEMES", []string{"http", "https"}),
+
+			BulkLimit: bulkLimit,
+		},
+		Auth: AuthConfig{
+			AdminAPIKey: adminAPIKey,
+		},
+		RateLimit: rateLimit,
+		Cache: CacheConfig{
+			Size:        cacheSize,
+			TTL:         cacheTTL,
+			NegativeTTL: cacheNegativeTTL,
+		},
+		Log: LogConfig{
+			Level:  logLevel,
+			Format: logFormat,
+		},
+		Tracing: TracingConfig{
+			Exporter:    tracingExporter,
+			SampleRatio: sampleRatio,
+		},
+		Analytics: AnalyticsConfig{
+			IPHashSecret: ipHashSecret,
 		},
 	}, nil
 }
@@ -1,8 +1,12 @@
 package config
 
 import (
+	"log/slog"
+	"net/netip"
 	"os"
+	"reflect"
 	"testing"
+	"time"
 )
 
 func TestLoadConfig_Success(t *testing.T) {
@@ -23,6 +27,10 @@ func TestLoadConfig_Success(t *testing.T) {
 	if config.Server.Port != "8080" {
 		t.Errorf("Expected default port '8080', got '%s'", config.Server.Port)
 	}
+
+	if config.Server.DefaultRedirectType != 302 {
+		t.Errorf("Expected default redirect type 302, got %d", config.Server.DefaultRedirectType)
+	}
 }
 
 func TestLoadConfig_CustomPort(t *testing.T) {
@@ -99,3 +107,301 @@ func TestGetRequiredEnv(t *testing.T) {
 		t.Fatal("Expected error for missing required env var, got nil")
 	}
 }
+
+func TestLoadConfig_ShortenerDefaults(t *testing.T) {
+	os.Setenv("DATABASE_URL", "postgres://localhost:5432/testdb")
+	defer os.Unsetenv("DATABASE_URL")
+
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if config.Shortener.CodeStrategy != "random" {
+		t.Errorf("Expected default code strategy 'random', got '%s'", config.Shortener.CodeStrategy)
+	}
+
+	if config.Shortener.CodeLength != 4 {
+		t.Errorf("Expected default code length 4, got %d", config.Shortener.CodeLength)
+	}
+
+	if !reflect.DeepEqual(config.Shortener.AllowedSchemes, []string{"http", "https"}) {
+		t.Errorf("Expected default allowed schemes [http https], got %v", config.Shortener.AllowedSchemes)
+	}
+
+	if config.Shortener.BulkLimit != 500 {
+		t.Errorf("Expected default bulk limit 500, got %d", config.Shortener.BulkLimit)
+	}
+}
+
+func TestLoadConfig_CodeStrategy(t *testing.T) {
+	os.Setenv("DATABASE_URL", "postgres://localhost:5432/testdb")
+	os.Setenv("CODE_STRATEGY", "hashids")
+	os.Setenv("CODE_LENGTH", "6")
+	os.Setenv("HASHIDS_SALT", "pepper")
+	defer func() {
+		os.Unsetenv("DATABASE_URL")
+		os.Unsetenv("CODE_STRATEGY")
+		os.Unsetenv("CODE_LENGTH")
+		os.Unsetenv("HASHIDS_SALT")
+	}()
+
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if config.Shortener.CodeStrategy != "hashids" {
+		t.Errorf("Expected code strategy 'hashids', got '%s'", config.Shortener.CodeStrategy)
+	}
+
+	if config.Shortener.CodeLength != 6 {
+		t.Errorf("Expected code length 6, got %d", config.Shortener.CodeLength)
+	}
+
+	if config.Shortener.HashidsSalt != "pepper" {
+		t.Errorf("Expected salt 'pepper', got '%s'", config.Shortener.HashidsSalt)
+	}
+}
+
+func TestLoadConfig_InvalidShortenerSettings(t *testing.T) {
+	os.Setenv("DATABASE_URL", "postgres://localhost:5432/testdb")
+	defer os.Unsetenv("DATABASE_URL")
+
+	tests := map[string]string{
+		"CODE_STRATEGY":         "uuid",
+		"CODE_LENGTH":           "abc",
+		"DEFAULT_REDIRECT_TYPE": "303",
+		"ADMIN_API_KEY":         "short",
+		"RATE_LIMIT_SHORTEN":    "-1",
+		"BULK_MAX_LINKS":        "0",
+		"IP_HASH_SECRET":        "short",
+		"TRUSTED_PROXIES":       "10.0.0.0/33",
+		"SHUTDOWN_TIMEOUT":      "0s",
+	}
+
+	for key, value := range tests {
+		os.Setenv(key, value)
+
+		config, err := LoadConfig()
+		if err == nil {
+			t.Errorf("Expected error for %s=%s, got nil", key, value)
+		}
+		if config != nil {
+			t.Errorf("Expected config to be nil for %s=%s", key, value)
+		}
+
+		os.Unsetenv(key)
+	}
+
+	os.Setenv("CODE_LENGTH", "0")
+	defer os.Unsetenv("CODE_LENGTH")
+
+	if _, err := LoadConfig(); err == nil {
+		t.Error("Expected error for CODE_LENGTH=0, got nil")
+	}
+}
+
+func TestGetIntEnvWithDefault(t *testing.T) {
+	os.Setenv("INT_TEST_VAR", "42")
+	defer os.Unsetenv("INT_TEST_VAR")
+
+	value, err := getIntEnvWithDefault("INT_TEST_VAR", 7)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if value != 42 {
+		t.Errorf("Expected 42, got %d", value)
+	}
+
+	value, err = getIntEnvWithDefault("NONEXISTENT_INT_VAR", 7)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if value != 7 {
+		t.Errorf("Expected default 7, got %d", value)
+	}
+
+	os.Setenv("INT_TEST_VAR", "forty-two")
+	if _, err := getIntEnvWithDefault("INT_TEST_VAR", 7); err == nil {
+		t.Error("Expected error for a value that isn't a number, got nil")
+	}
+}
+
+func TestGetListEnvWithDefault(t *testing.T) {
+	os.Setenv("LIST_TEST_VAR", " HTTPS, ftp,,")
+	defer os.Unsetenv("LIST_TEST_VAR")
+
+	result := getListEnvWithDefault("LIST_TEST_VAR", []string{"http"})
+	if !reflect.DeepEqual(result, []string{"https", "ftp"}) {
+		t.Errorf("Expected [https ftp], got %v", result)
+	}
+
+	result = getListEnvWithDefault("NONEXISTENT_LIST_VAR", []string{"http"})
+	if !reflect.DeepEqual(result, []string{"http"}) {
+		t.Errorf("Expected default [http], got %v", result)
+	}
+}
+
+func TestGetPrefixListEnv(t *testing.T) {
+	os.Setenv("PREFIX_TEST_VAR", "10.0.0.0/8, 192.0.2.1,2001:db8::/32")
+	defer os.Unsetenv("PREFIX_TEST_VAR")
+
+	result, err := getPrefixListEnv("PREFIX_TEST_VAR")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("192.0.2.1/32"),
+		netip.MustParsePrefix("2001:db8::/32"),
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+
+	os.Setenv("PREFIX_TEST_VAR", "10.0.0.0/8,proxy.internal")
+	if _, err := getPrefixListEnv("PREFIX_TEST_VAR"); err == nil {
+		t.Error("Expected error for a host name, got nil")
+	}
+}
+
+func TestLoadConfig_DefaultRedirectType(t *testing.T) {
+	os.Setenv("DATABASE_URL", "postgres://localhost:5432/testdb")
+	os.Setenv("DEFAULT_REDIRECT_TYPE", "308")
+	defer func() {
+		os.Unsetenv("DATABASE_URL")
+		os.Unsetenv("DEFAULT_REDIRECT_TYPE")
+	}()
+
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if config.Server.DefaultRedirectType != 308 {
+		t.Errorf("Expected redirect type 308, got %d", config.Server.DefaultRedirectType)
+	}
+}
+
+func TestLoadConfig_RateLimit(t *testing.T) {
+	os.Setenv("DATABASE_URL", "postgres://localhost:5432/testdb")
+	os.Setenv("RATE_LIMIT_SHORTEN", "0")
+	os.Setenv("RATE_LIMIT_REDIRECT_BURST", "20")
+	defer func() {
+		os.Unsetenv("DATABASE_URL")
+		os.Unsetenv("RATE_LIMIT_SHORTEN")
+		os.Unsetenv("RATE_LIMIT_REDIRECT_BURST")
+	}()
+
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := RateLimitConfig{Shorten: 0, ShortenBurst: 10, Redirect: 600, RedirectBurst: 20}
+	if config.RateLimit != expected {
+		t.Errorf("Expected %+v, got %+v", expected, config.RateLimit)
+	}
+}
+
+func TestLoadConfig_Cache(t *testing.T) {
+	os.Setenv("DATABASE_URL", "postgres://localhost:5432/testdb")
+	os.Setenv("CACHE_TTL", "30s")
+	defer func() {
+		os.Unsetenv("DATABASE_URL")
+		os.Unsetenv("CACHE_TTL")
+	}()
+
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := CacheConfig{Size: 10000, TTL: 30 * time.Second, NegativeTTL: 10 * time.Second}
+	if config.Cache != expected {
+		t.Errorf("Expected %+v, got %+v", expected, config.Cache)
+	}
+
+	os.Setenv("CACHE_TTL", "soon")
+	if _, err := LoadConfig(); err == nil {
+		t.Error("Expected an error for a CACHE_TTL that isn't a duration")
+	}
+}
+
+func TestLoadConfig_Log(t *testing.T) {
+	os.Setenv("DATABASE_URL", "postgres://localhost:5432/testdb")
+	defer os.Unsetenv("DATABASE_URL")
+
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if config.Log.Level != slog.LevelInfo || config.Log.Format != "json" {
+		t.Errorf("Expected info level JSON logs by default, got %+v", config.Log)
+	}
+
+	os.Setenv("LOG_LEVEL", "DEBUG")
+	os.Setenv("LOG_FORMAT", "text")
+	defer func() {
+		os.Unsetenv("LOG_LEVEL")
+		os.Unsetenv("LOG_FORMAT")
+	}()
+
+	config, err = LoadConfig()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if config.Log.Level != slog.LevelDebug || config.Log.Format != "text" {
+		t.Errorf("Expected debug level text logs, got %+v", config.Log)
+	}
+
+	os.Setenv("LOG_LEVEL", "verbose")
+	if _, err := LoadConfig(); err == nil {
+		t.Error("Expected an error for an unknown LOG_LEVEL")
+	}
+
+	os.Setenv("LOG_LEVEL", "info")
+	os.Setenv("LOG_FORMAT", "xml")
+	if _, err := LoadConfig(); err == nil {
+		t.Error("Expected an error for an unknown LOG_FORMAT")
+	}
+}
+
+func TestLoadConfig_Tracing(t *testing.T) {
+	os.Setenv("DATABASE_URL", "postgres://localhost:5432/testdb")
+	defer os.Unsetenv("DATABASE_URL")
+
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected := TracingConfig{Exporter: "none", SampleRatio: 1}
+	if config.Tracing != expected {
+		t.Errorf("Expected %+v, got %+v", expected, config.Tracing)
+	}
+
+	os.Setenv("TRACING_EXPORTER", "otlp")
+	os.Setenv("TRACING_SAMPLE_RATIO", "0.1")
+	defer func() {
+		os.Unsetenv("TRACING_EXPORTER")
+		os.Unsetenv("TRACING_SAMPLE_RATIO")
+	}()
+
+	config, err = LoadConfig()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected = TracingConfig{Exporter: "otlp", SampleRatio: 0.1}
+	if config.Tracing != expected {
+		t.Errorf("Expected %+v, got %+v", expected, config.Tracing)
+	}
+
+	for key, value := range map[string]string{"TRACING_EXPORTER": "jaeger", "TRACING_SAMPLE_RATIO": "2"} {
+		os.Setenv(key, value)
+		if _, err := LoadConfig(); err == nil {
+			t.Errorf("Expected an error for %s=%s", key, value)
+		}
+		os.Unsetenv(key)
+	}
+}
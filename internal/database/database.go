@@ -2,31 +2,24 @@ package database
 
 import (
 	"context"
+	"fmt"
 
-	"github.com/topboyasante/trunc8/internal/config"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-var DBClient *mongo.Client
-
-func ConnectToMongo(cfg *config.Config) error {
-	ctx := context.TODO()
-	dbClientOptions := options.Client().ApplyURI(cfg.Database.Url)
-
-	client, err := mongo.Connect(ctx, dbClientOptions)
-
+// ConnectToMongo connects to the MongoDB server at uri.
+// mongo.Connect doesn't talk to the server, so we ping it to fail at startup instead of on the first request.
+func ConnectToMongo(ctx context.Context, uri string) (*mongo.Client, error) {
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	DBClient = client
-	return nil
-}
-
-func DisconnectMongo() error {
-	if DBClient != nil {
-		return DBClient.Disconnect(context.TODO())
+	if err := client.Ping(ctx, nil); err != nil {
+		client.Disconnect(ctx)
+		return nil, fmt.Errorf("pinging MongoDB: %w", err)
 	}
-	return nil
+
+	return client, nil
 }
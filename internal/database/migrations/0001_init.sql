@@ -0,0 +1,81 @@
+-- IDs of other rows are stored as text, like in the other backends, and '' means none.
+
+CREATE TABLE links (
+    id            BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    code          TEXT        NOT NULL,
+    original_url  TEXT        NOT NULL,
+    click_count   INTEGER     NOT NULL DEFAULT 0,
+    expires_at    TIMESTAMPTZ,                    -- NULL means the link never expires
+    max_clicks    INTEGER     NOT NULL DEFAULT 0, -- 0 means there is no limit
+    disabled      BOOLEAN     NOT NULL DEFAULT FALSE,
+    redirect_type INTEGER     NOT NULL DEFAULT 0, -- 0 means the server wide default
+    owner_id      TEXT        NOT NULL DEFAULT '',
+    workspace_id  TEXT        NOT NULL DEFAULT '',
+    tags          TEXT[]      NOT NULL DEFAULT '{}',
+    domain        TEXT        NOT NULL DEFAULT '',
+    created_at    TIMESTAMPTZ NOT NULL,
+
+    -- What guarantees two links never share a code
+    CONSTRAINT links_code_key UNIQUE (code)
+);
+
+-- Used to find an existing link for a URL before creating a new one
+CREATE INDEX links_original_url_idx ON links (original_url, workspace_id);
+
+-- Listing links, newest or most clicked first. id breaks ties for the cursor.
+CREATE INDEX links_created_at_idx ON links (created_at DESC, id DESC);
+CREATE INDEX links_click_count_idx ON links (click_count DESC, id DESC);
+CREATE INDEX links_workspace_idx ON links (workspace_id, created_at DESC);
+CREATE INDEX links_owner_idx ON links (owner_id, created_at DESC);
+CREATE INDEX links_tags_idx ON links USING GIN (tags);
+CREATE INDEX links_domain_idx ON links (domain);
+
+CREATE TABLE clicks (
+    id         BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    code       TEXT        NOT NULL,
+    clicked_at TIMESTAMPTZ NOT NULL,
+    referrer   TEXT        NOT NULL DEFAULT '',
+    user_agent TEXT        NOT NULL DEFAULT '',
+    browser    TEXT        NOT NULL DEFAULT '',
+    ip_hash    TEXT        NOT NULL DEFAULT ''
+);
+
+CREATE INDEX clicks_code_clicked_at_idx ON clicks (code, clicked_at);
+
+-- Sequences of the counter and hashids code strategies
+CREATE TABLE counters (
+    name TEXT   PRIMARY KEY,
+    seq  BIGINT NOT NULL
+);
+
+CREATE TABLE api_keys (
+    id           BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    name         TEXT        NOT NULL,
+    prefix       TEXT        NOT NULL,
+    key_hash     TEXT        NOT NULL,
+    scopes       TEXT[]      NOT NULL DEFAULT '{}',
+    user_id      TEXT        NOT NULL DEFAULT '',
+    workspace_id TEXT        NOT NULL DEFAULT '',
+    created_at   TIMESTAMPTZ NOT NULL,
+
+    CONSTRAINT api_keys_key_hash_key UNIQUE (key_hash)
+);
+
+CREATE TABLE workspaces (
+    id         BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    name       TEXT        NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE users (
+    id           BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    workspace_id TEXT        NOT NULL,
+    name         TEXT        NOT NULL,
+    email        TEXT        NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL,
+
+    -- A person can't end up in two workspaces by accident
+    CONSTRAINT users_email_key UNIQUE (email)
+);
+
+CREATE INDEX users_workspace_idx ON users (workspace_id);
@@ -0,0 +1,151 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+
+	_ "github.com/lib/pq" // registers the "postgres" driver
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// migrationLock is the key of the advisory lock held while migrating,
+// so two instances starting at the same time don't both apply a migration.
+const migrationLock = 0x7472756e6338 // "trunc8"
+
+// ConnectToPostgres opens a connection pool to the Postgres server at url and pings it.
+func ConnectToPostgres(ctx context.Context, url string) (*sql.DB, error) {
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("pinging Postgres: %w", err)
+	}
+
+	return db, nil
+}
+
+// Migrate applies the migrations in migrations/ that haven't been applied yet, in the order of their file names.
+// Applied migrations are recorded in schema_migrations. Postgres can roll back schema changes,
+// so they all run in one transaction: either the schema is fully up to date or nothing changed.
+func Migrate(ctx context.Context, db *sql.DB) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", migrationLock); err != nil {
+		return fmt.Errorf("locking migrations: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    TEXT        PRIMARY KEY,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`)
+	if err != nil {
+		return fmt.Errorf("creating schema_migrations: %w", err)
+	}
+
+	applied, err := appliedMigrations(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	versions, err := migrationVersions()
+	if err != nil {
+		return err
+	}
+
+	for _, version := range versions {
+		if applied[version] {
+			continue
+		}
+
+		script, err := migrations.ReadFile("migrations/" + version + ".sql")
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, string(script)); err != nil {
+			return fmt.Errorf("applying migration %s: %w", version, err)
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (version) VALUES ($1)", version); err != nil {
+			return fmt.Errorf("recording migration %s: %w", version, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// migrationVersions returns the names of the embedded migrations without the .sql extension, sorted.
+func migrationVersions() ([]string, error) {
+	files, err := fs.Glob(migrations, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]string, 0, len(files))
+	for _, file := range files {
+		versions = append(versions, strings.TrimSuffix(strings.TrimPrefix(file, "migrations/"), ".sql"))
+	}
+	sort.Strings(versions)
+
+	return versions, nil
+}
+
+// MigrationStatus returns the versions of the embedded migrations that have been applied, and the ones that haven't.
+func MigrationStatus(ctx context.Context, db *sql.DB) (applied, pending []string, err error) {
+	done, err := appliedMigrations(ctx, db)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	versions, err := migrationVersions()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	applied, pending = []string{}, []string{}
+	for _, version := range versions {
+		if done[version] {
+			applied = append(applied, version)
+		} else {
+			pending = append(pending, version)
+		}
+	}
+
+	return applied, pending, nil
+}
+
+// querier is what *sql.DB and *sql.Tx have in common.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func appliedMigrations(ctx context.Context, q querier) (map[string]bool, error) {
+	rows, err := q.QueryContext(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[string]bool{}
+	for rows.Next() {
+		var version string
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied[version] = true
+	}
+
+	return applied, rows.Err()
+}
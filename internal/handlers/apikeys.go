@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/topboyasante/trunc8/internal/httpjson"
+	"github.com/topboyasante/trunc8/internal/models"
+	"github.com/topboyasante/trunc8/internal/types"
+)
+
+// APIKeyServiceInterface defines the interface for API key management
+type APIKeyServiceInterface interface {
+	CreateAPIKey(ctx context.Context, req types.CreateAPIKeyRequest) (*types.CreateAPIKeyResponse, error)
+	ListAPIKeys(ctx context.Context) ([]*models.APIKey, error)
+	DeleteAPIKey(ctx context.Context, id string) error
+}
+
+type APIKeyHandler struct {
+	service APIKeyServiceInterface
+}
+
+func NewAPIKeyHandler(service APIKeyServiceInterface) *APIKeyHandler {
+	return &APIKeyHandler{
+		service: service,
+	}
+}
+
+// APIKeys handles GET and POST on /api/keys.
+func (h *APIKeyHandler) APIKeys(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		keys, err := h.service.ListAPIKeys(r.Context())
+		if err != nil {
+			writeServiceError(w, r, err, http.StatusInternalServerError, "Unable to list API keys")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, keys)
+	case http.MethodPost:
+		defer r.Body.Close()
+
+		var payload types.CreateAPIKeyRequest
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			httpjson.WriteError(w, http.StatusBadRequest, "Invalid JSON")
+			return
+		}
+
+		res, err := h.service.CreateAPIKey(r.Context(), payload)
+		if err != nil {
+			writeServiceError(w, r, err, http.StatusInternalServerError, "Unable to create API key")
+			return
+		}
+
+		writeJSON(w, http.StatusCreated, res)
+	default:
+		httpjson.WriteError(w, http.StatusNotFound, "Not found")
+		slog.DebugContext(r.Context(), "Received request with unsupported method", "method", r.Method, "route", "/api/keys")
+	}
+}
+
+// APIKey handles DELETE /api/keys/{id}, which revokes the key.
+func (h *APIKeyHandler) APIKey(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodDelete:
+		if err := h.service.DeleteAPIKey(r.Context(), r.PathValue("id")); err != nil {
+			writeServiceError(w, r, err, http.StatusInternalServerError, "Unable to delete API key")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		httpjson.WriteError(w, http.StatusNotFound, "Not found")
+		slog.DebugContext(r.Context(), "Received request with unsupported method", "method", r.Method, "route", "/api/keys/{id}")
+	}
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/topboyasante/trunc8/internal/models"
+	"github.com/topboyasante/trunc8/internal/types"
+)
+
+// Mock API key service for testing
+type mockAPIKeyService struct {
+	createAPIKeyFunc func(ctx context.Context, req types.CreateAPIKeyRequest) (*types.CreateAPIKeyResponse, error)
+	listAPIKeysFunc  func(ctx context.Context) ([]*models.APIKey, error)
+	deleteAPIKeyFunc func(ctx context.Context, id string) error
+}
+
+func (m *mockAPIKeyService) CreateAPIKey(ctx context.Context, req types.CreateAPIKeyRequest) (*types.CreateAPIKeyResponse, error) {
+	if m.createAPIKeyFunc != nil {
+		return m.createAPIKeyFunc(ctx, req)
+	}
+	return &types.CreateAPIKeyResponse{
+		APIKey: &models.APIKey{ID: "key-id", Name: req.Name, Prefix: "t8_abcdef", KeyHash: "secret-hash", Scopes: req.Scopes},
+		Key:    "t8_abcdefghijklmnopqrstuvwxyz012345",
+	}, nil
+}
+
+func (m *mockAPIKeyService) ListAPIKeys(ctx context.Context) ([]*models.APIKey, error) {
+	if m.listAPIKeysFunc != nil {
+		return m.listAPIKeysFunc(ctx)
+	}
+	return []*models.APIKey{{ID: "key-id", Name: "ci", KeyHash: "secret-hash"}}, nil
+}
+
+func (m *mockAPIKeyService) DeleteAPIKey(ctx context.Context, id string) error {
+	if m.deleteAPIKeyFunc != nil {
+		return m.deleteAPIKeyFunc(ctx, id)
+	}
+	return nil
+}
+
+func TestAPIKeys_Create(t *testing.T) {
+	handler := NewAPIKeyHandler(&mockAPIKeyService{})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/keys", strings.NewReader(`{"name":"ci","scopes":["links:write"]}`))
+	w := httptest.NewRecorder()
+
+	handler.APIKeys(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d", http.StatusCreated, w.Code)
+	}
+
+	var response struct {
+		ID     string   `json:"id"`
+		Key    string   `json:"key"`
+		Scopes []string `json:"scopes"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Error unmarshaling response: %v", err)
+	}
+
+	if response.ID != "key-id" || response.Key == "" || len(response.Scopes) != 1 {
+		t.Errorf("Unexpected response: %s", w.Body.String())
+	}
+
+	if strings.Contains(w.Body.String(), "secret-hash") {
+		t.Error("Expected the key hash not to be in the response")
+	}
+}
+
+func TestAPIKeys_List(t *testing.T) {
+	handler := NewAPIKeyHandler(&mockAPIKeyService{})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/keys", nil)
+	w := httptest.NewRecorder()
+
+	handler.APIKeys(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+
+	if !strings.Contains(w.Body.String(), `"name":"ci"`) || strings.Contains(w.Body.String(), "secret-hash") {
+		t.Errorf("Unexpected response: %s", w.Body.String())
+	}
+}
+
+func TestAPIKeys_Errors(t *testing.T) {
+	mockService := &mockAPIKeyService{
+		createAPIKeyFunc: func(ctx context.Context, req types.CreateAPIKeyRequest) (*types.CreateAPIKeyResponse, error) {
+			return nil, fmt.Errorf("%w: unknown scope \"links:delete\"", models.ErrInvalidAPIKey)
+		},
+		deleteAPIKeyFunc: func(ctx context.Context, id string) error {
+			return fmt.Errorf("%w: %s", models.ErrAPIKeyNotFound, id)
+		},
+	}
+
+	handler := NewAPIKeyHandler(mockService)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/keys", handler.APIKeys)
+	mux.HandleFunc("/api/keys/{id}", handler.APIKey)
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{http.MethodPost, "/api/keys", "invalid json", http.StatusBadRequest},
+		{http.MethodPost, "/api/keys", `{"name":"ci","scopes":["links:delete"]}`, http.StatusBadRequest},
+		{http.MethodDelete, "/api/keys/missing", "", http.StatusNotFound},
+		{http.MethodPut, "/api/keys", "", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		w := httptest.NewRecorder()
+
+		mux.ServeHTTP(w, req)
+
+		if w.Code != tt.status {
+			t.Errorf("%s %s: expected status code %d, got %d", tt.method, tt.path, tt.status, w.Code)
+		}
+	}
+}
+
+func TestAPIKey_Delete(t *testing.T) {
+	deleted := ""
+	mockService := &mockAPIKeyService{
+		deleteAPIKeyFunc: func(ctx context.Context, id string) error {
+			deleted = id
+			return nil
+		},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/keys/{id}", NewAPIKeyHandler(mockService).APIKey)
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/keys/key-id", nil)
+	w := httptest.NewRecorder()
+
+	mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Expected status code %d, got %d", http.StatusNoContent, w.Code)
+	}
+
+	if deleted != "key-id" {
+		t.Errorf("Expected 'key-id' to be deleted, got '%s'", deleted)
+	}
+}
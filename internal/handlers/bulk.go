@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/topboyasante/trunc8/internal/httpjson"
+	"github.com/topboyasante/trunc8/internal/ratelimit"
+	"github.com/topboyasante/trunc8/internal/types"
+)
+
+// maxBulkBodySize caps the body of a bulk request. A few hundred links take a few dozen kilobytes.
+const maxBulkBodySize = 10 << 20
+
+// bulkColumns are the columns a bulk CSV file can have, only url is required
+var bulkColumns = map[string]bool{"url": true, "alias": true, "tags": true, "expires_at": true}
+
+var errUnsupportedBulkType = errors.New("Content-Type must be application/json, text/csv or multipart/form-data")
+
+// BulkShortenURLs creates many links at once. The body is either a JSON array of /shorten requests,
+// or a CSV file, sent as text/csv or as the "file" field of a multipart form, whose header row names
+// its columns among url, alias, tags and expires_at. Every link is created like /shorten would,
+// so the response is a 200 that tells for each of them whether it was created, unless the body can't be read.
+func (h *ShortnerHandler) BulkShortenURLs(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBulkBodySize)
+	defer r.Body.Close()
+
+	reqs, err := readBulkRequest(r)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			httpjson.WriteError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Body must be at most %d bytes", tooLarge.Limit))
+		case errors.Is(err, errUnsupportedBulkType):
+			httpjson.WriteError(w, http.StatusUnsupportedMediaType, err.Error())
+		default:
+			httpjson.WriteError(w, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+
+	// Refused batches aren't charged, or a single one too big could use up the rate limit
+	if err := h.service.CheckBulkSize(len(reqs)); err != nil {
+		writeServiceError(w, r, err, http.StatusBadRequest, "Invalid batch")
+		return
+	}
+
+	// Each link counts against the rate limit of /shorten, so bulk requests can't be used to get around it
+	if result, ok := ratelimit.Charge(r.Context(), len(reqs)); ok && !result.Allowed {
+		httpjson.WriteError(w, http.StatusTooManyRequests, fmt.Sprintf("Rate limit exceeded, retry in %d seconds or send fewer links", int(math.Ceil(result.RetryAfter.Seconds()))))
+		return
+	}
+
+	links, errs, err := h.service.ShortenMany(r.Context(), reqs)
+	if err != nil {
+		writeServiceError(w, r, err, http.StatusInternalServerError, "Error shortening urls")
+		return
+	}
+
+	res := types.BulkShortenResponse{Results: make([]types.BulkShortenResult, len(reqs))}
+	for i := range reqs {
+		result := types.BulkShortenResult{Row: i + 1}
+		if errs[i] == nil {
+			result.Link = links[i]
+			res.Created++
+		} else {
+			status, message := serviceError(r, errs[i], http.StatusInternalServerError, "Error shortening url")
+			result.Error = &types.ErrorResponse{Error: httpjson.ErrorCode(status), Message: message}
+			res.Failed++
+		}
+		res.Results[i] = result
+	}
+
+	writeJSON(w, http.StatusOK, res)
+}
+
+// readBulkRequest reads the links of a bulk request in whichever format it was sent.
+// JSON is assumed when there is no Content-Type, like /shorten does.
+func readBulkRequest(r *http.Request) ([]types.ShortenRequest, error) {
+	mediaType := "application/json"
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		var err error
+		if mediaType, _, err = mime.ParseMediaType(contentType); err != nil {
+			return nil, errUnsupportedBulkType
+		}
+	}
+
+	switch mediaType {
+	case "application/json":
+		var reqs []types.ShortenRequest
+		if err := json.NewDecoder(r.Body).Decode(&reqs); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				return nil, err
+			}
+			return nil, errors.New("Invalid JSON, expected an array of links")
+		}
+		return reqs, nil
+	case "text/csv":
+		return readBulkCSV(r.Body)
+	case "multipart/form-data":
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				return nil, err
+			}
+			return nil, errors.New(`Expected a CSV file in the "file" field`)
+		}
+		defer file.Close()
+		return readBulkCSV(file)
+	default:
+		return nil, errUnsupportedBulkType
+	}
+}
+
+// readBulkCSV reads links from a CSV file with a header row. Tags are separated by commas,
+// semicolons or spaces within their cell, and expires_at is an RFC 3339 time like 2026-12-31T23:59:59Z.
+func readBulkCSV(r io.Reader) ([]types.ShortenRequest, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("The CSV file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Invalid CSV: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		// Spreadsheets like Excel start the files they export with a byte order mark
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !bulkColumns[name] {
+			return nil, fmt.Errorf("Unknown CSV column %q, expected url, alias, tags or expires_at", name)
+		}
+		if _, ok := columns[name]; ok {
+			return nil, fmt.Errorf("CSV column %q appears twice", name)
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["url"]; !ok {
+		return nil, errors.New("The CSV file needs a url column")
+	}
+
+	var reqs []types.ShortenRequest
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return reqs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Invalid CSV: %w", err)
+		}
+
+		field := func(name string) string {
+			if i, ok := columns[name]; ok {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		req := types.ShortenRequest{URL: field("url"), Alias: field("alias")}
+		if tags := field("tags"); tags != "" {
+			req.Tags = strings.FieldsFunc(tags, func(r rune) bool {
+				return r == ',' || r == ';' || unicode.IsSpace(r)
+			})
+		}
+		if expiresAt := field("expires_at"); expiresAt != "" {
+			t, err := time.Parse(time.RFC3339, expiresAt)
+			if err != nil {
+				return nil, fmt.Errorf("Row %d: expires_at must be a time like 2026-12-31T23:59:59Z, got %q", len(reqs)+1, expiresAt)
+			}
+			req.ExpiresAt = &t
+		}
+		reqs = append(reqs, req)
+	}
+}
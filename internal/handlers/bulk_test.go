@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/topboyasante/trunc8/internal/models"
+	"github.com/topboyasante/trunc8/internal/ratelimit"
+	"github.com/topboyasante/trunc8/internal/types"
+)
+
+// bulkTestService fails links whose alias is "taken", like the real service would
+func bulkTestService(got *[]types.ShortenRequest) *mockShortnerService {
+	return &mockShortnerService{
+		shortenURLFunc: func(ctx context.Context, req types.ShortenRequest) (*models.URL, error) {
+			*got = append(*got, req)
+			if req.Alias == "taken" {
+				return nil, fmt.Errorf("%w: %s", models.ErrAliasTaken, req.Alias)
+			}
+			return &models.URL{ID: "test-id", OriginalURL: req.URL, Code: "TEST"}, nil
+		},
+	}
+}
+
+func TestBulkShortenURLs_JSON(t *testing.T) {
+	var got []types.ShortenRequest
+	handler := NewShortnerHandler(bulkTestService(&got))
+
+	body := `[{"url": "https://example.com"}, {"url": "https://example.org", "alias": "taken"}]`
+	req := httptest.NewRequest(http.MethodPost, "/api/links/bulk", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	handler.BulkShortenURLs(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var res types.BulkShortenResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	if res.Created != 1 || res.Failed != 1 || len(res.Results) != 2 {
+		t.Fatalf("Expected 1 created and 1 failed link, got %+v", res)
+	}
+	if res.Results[0].Row != 1 || res.Results[0].Link == nil || res.Results[0].Error != nil {
+		t.Errorf("Expected row 1 to be created, got %+v", res.Results[0])
+	}
+	if res.Results[1].Row != 2 || res.Results[1].Link != nil || res.Results[1].Error == nil || res.Results[1].Error.Error != "conflict" {
+		t.Errorf("Expected row 2 to conflict, got %+v", res.Results[1])
+	}
+}
+
+func TestBulkShortenURLs_CSV(t *testing.T) {
+	var got []types.ShortenRequest
+	handler := NewShortnerHandler(bulkTestService(&got))
+
+	body := "\ufeffURL,alias,tags,expires_at\n" +
+		"https://example.com,,,\n" +
+		"https://example.org,launch-2026,\"newsletter, launch\",2030-01-01T00:00:00Z\n"
+	req := httptest.NewRequest(http.MethodPost, "/api/links/bulk", strings.NewReader(body))
+	req.Header.Set("Content-Type", "text/csv; charset=utf-8")
+	w := httptest.NewRecorder()
+
+	handler.BulkShortenURLs(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 links, got %+v", got)
+	}
+	if got[0].URL != "https://example.com" || got[0].Alias != "" || got[0].Tags != nil || got[0].ExpiresAt != nil {
+		t.Errorf("Expected a plain link, got %+v", got[0])
+	}
+	expiresAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	if got[1].Alias != "launch-2026" || len(got[1].Tags) != 2 || got[1].Tags[1] != "launch" || !got[1].ExpiresAt.Equal(expiresAt) {
+		t.Errorf("Expected the alias, tags and expiration of row 2, got %+v", got[1])
+	}
+}
+
+func TestBulkShortenURLs_MultipartCSV(t *testing.T) {
+	var got []types.ShortenRequest
+	handler := NewShortnerHandler(bulkTestService(&got))
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	file, _ := form.CreateFormFile("file", "links.csv")
+	file.Write([]byte("url\nhttps://example.com\nhttps://example.org\n"))
+	form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/links/bulk", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	w := httptest.NewRecorder()
+
+	handler.BulkShortenURLs(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(got) != 2 || got[1].URL != "https://example.org" {
+		t.Errorf("Expected the 2 links of the file, got %+v", got)
+	}
+}
+
+func TestBulkShortenURLs_BadRequest(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		status      int
+	}{
+		{"invalid JSON", "application/json", `{"url": "https://example.com"}`, http.StatusBadRequest},
+		{"empty CSV", "text/csv", "", http.StatusBadRequest},
+		{"unknown column", "text/csv", "url,clicks\nhttps://example.com,3\n", http.StatusBadRequest},
+		{"no url column", "text/csv", "alias\nlaunch\n", http.StatusBadRequest},
+		{"missing field", "text/csv", "url,alias\nhttps://example.com\n", http.StatusBadRequest},
+		{"invalid expiration", "text/csv", "url,expires_at\nhttps://example.com,tomorrow\n", http.StatusBadRequest},
+		{"no file", "multipart/form-data; boundary=x", "--x--\r\n", http.StatusBadRequest},
+		{"unsupported type", "application/xml", "<links/>", http.StatusUnsupportedMediaType},
+		{"too large", "text/csv", "url\n" + strings.Repeat("https://example.com/\n", maxBulkBodySize/20+1), http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []types.ShortenRequest
+			handler := NewShortnerHandler(bulkTestService(&got))
+
+			req := httptest.NewRequest(http.MethodPost, "/api/links/bulk", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+
+			handler.BulkShortenURLs(w, req)
+
+			if w.Code != tt.status {
+				t.Errorf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+			if len(got) != 0 {
+				t.Errorf("Expected no link to be created, got %+v", got)
+			}
+		})
+	}
+}
+
+func TestBulkShortenURLs_RateLimited(t *testing.T) {
+	var got []types.ShortenRequest
+	handler := NewShortnerHandler(bulkTestService(&got))
+
+	var charged int
+	body := `[{"url": "https://example.com"}, {"url": "https://example.org"}, {"url": "https://example.net"}]`
+	req := httptest.NewRequest(http.MethodPost, "/api/links/bulk", strings.NewReader(body))
+	req = req.WithContext(ratelimit.WithCharge(req.Context(), func(n int) ratelimit.Result {
+		charged = n
+		return ratelimit.Result{Allowed: false, RetryAfter: 1500 * time.Millisecond}
+	}))
+	w := httptest.NewRecorder()
+
+	handler.BulkShortenURLs(w, req)
+
+	if charged != 3 {
+		t.Errorf("Expected one token per link, got %d", charged)
+	}
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(got) != 0 {
+		t.Errorf("Expected no link to be created, got %+v", got)
+	}
+}
+
+func TestBulkShortenURLs_InvalidBatch(t *testing.T) {
+	var got []types.ShortenRequest
+	service := bulkTestService(&got)
+	service.checkBulkSizeFunc = func(n int) error {
+		return fmt.Errorf("%w: at most 1 links can be shortened at once, got %d", models.ErrInvalidBulk, n)
+	}
+	handler := NewShortnerHandler(service)
+
+	limiter := ratelimit.New(60, 5)
+	body := `[{"url": "https://example.com"}, {"url": "https://example.org"}, {"url": "https://example.net"}]`
+	req := httptest.NewRequest(http.MethodPost, "/api/links/bulk", strings.NewReader(body))
+	req = req.WithContext(ratelimit.WithCharge(req.Context(), func(n int) ratelimit.Result {
+		return limiter.AllowN("1.2.3.4", n)
+	}))
+	w := httptest.NewRecorder()
+
+	handler.BulkShortenURLs(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+	if len(got) != 0 {
+		t.Errorf("Expected no link to be created, got %+v", got)
+	}
+	// A refused batch costs nothing: the bucket still has its 5 tokens
+	if result := limiter.AllowN("1.2.3.4", 5); !result.Allowed {
+		t.Errorf("Expected the bucket to be untouched, got %+v", result)
+	}
+}
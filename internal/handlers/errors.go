@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/topboyasante/trunc8/internal/httpjson"
+	"github.com/topboyasante/trunc8/internal/models"
+)
+
+// errorStatuses maps the domain errors to the HTTP status we answer with.
+// The order matters when an error wraps more than one of them.
+var errorStatuses = []struct {
+	err    error
+	status int
+}{
+	{models.ErrNotFound, http.StatusNotFound},
+	{models.ErrAPIKeyNotFound, http.StatusNotFound},
+	{models.ErrWorkspaceNotFound, http.StatusNotFound},
+	{models.ErrUserNotFound, http.StatusNotFound},
+	{models.ErrExpired, http.StatusGone},
+	{models.ErrDisabled, http.StatusGone},
+	{models.ErrAliasTaken, http.StatusConflict},
+	{models.ErrEmailTaken, http.StatusConflict},
+	{models.ErrInvalidURL, http.StatusBadRequest},
+	{models.ErrInvalidAlias, http.StatusBadRequest},
+	{models.ErrInvalidExpiration, http.StatusBadRequest},
+	{models.ErrInvalidRedirect, http.StatusBadRequest},
+	{models.ErrInvalidStatsQuery, http.StatusBadRequest},
+	{models.ErrInvalidTags, http.StatusBadRequest},
+	{models.ErrInvalidListQuery, http.StatusBadRequest},
+	{models.ErrInvalidAPIKey, http.StatusBadRequest},
+	{models.ErrInvalidWorkspace, http.StatusBadRequest},
+	{models.ErrInvalidUser, http.StatusBadRequest},
+	{models.ErrInvalidBulk, http.StatusBadRequest},
+}
+
+// writeServiceError answers with the status of a domain error.
+// Anything else is unexpected: we log it and answer with the fallback status and message,
+// so internals like database errors never leak to the client.
+func writeServiceError(w http.ResponseWriter, r *http.Request, err error, fallbackStatus int, fallbackMessage string) {
+	status, message := serviceError(r, err, fallbackStatus, fallbackMessage)
+	httpjson.WriteError(w, status, message)
+}
+
+// serviceError returns the status and message writeServiceError would answer err with.
+func serviceError(r *http.Request, err error, fallbackStatus int, fallbackMessage string) (int, string) {
+	for _, e := range errorStatuses {
+		if errors.Is(err, e.err) {
+			return e.status, err.Error()
+		}
+	}
+
+	slog.ErrorContext(r.Context(), fallbackMessage, "error", err, "method", r.Method, "path", r.URL.Path)
+	return fallbackStatus, fallbackMessage
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/topboyasante/trunc8/internal/httpjson"
+	"github.com/topboyasante/trunc8/internal/models"
+	"github.com/topboyasante/trunc8/internal/types"
+)
+
+// readinessTimeout bounds the checks of /readyz, so a hung storage fails the probe instead of stalling it
+const readinessTimeout = 2 * time.Second
+
+// HealthChecker is the storage as seen by the readiness probe
+type HealthChecker interface {
+	Ping(ctx context.Context) error
+	Migrations(ctx context.Context) (*models.MigrationState, error)
+}
+
+// CacheStatsReporter is the redirect cache as seen by the readiness probe
+type CacheStatsReporter interface {
+	CacheStats() models.CacheStats
+}
+
+type HealthHandler struct {
+	storage HealthChecker
+	cache   CacheStatsReporter // nil when the cache is disabled
+}
+
+// NewHealthHandler reports on storage, and on cache unless it is nil.
+func NewHealthHandler(storage HealthChecker, cache CacheStatsReporter) *HealthHandler {
+	return &HealthHandler{
+		storage: storage,
+		cache:   cache,
+	}
+}
+
+// Healthz handles GET on /healthz. It only tells the process is up and serving, whatever the state
+// of the storage, so an orchestrator doesn't restart the server because the database is down.
+func (h *HealthHandler) Healthz(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		writeJSON(w, http.StatusOK, types.HealthResponse{Status: "ok"})
+	default:
+		httpjson.WriteError(w, http.StatusNotFound, "Not found")
+		slog.DebugContext(r.Context(), "Received request with unsupported method", "method", r.Method, "route", "/healthz")
+	}
+}
+
+// Readyz handles GET on /readyz. The server is ready when the storage answers and its schema
+// is up to date; otherwise it answers 503 so load balancers send traffic elsewhere.
+// The cache is only reported on: a cold or disabled cache doesn't stop us from serving.
+func (h *HealthHandler) Readyz(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+		defer cancel()
+
+		checks := map[string]types.HealthCheck{
+			"storage":    h.checkStorage(ctx),
+			"migrations": h.checkMigrations(ctx),
+			"cache":      h.checkCache(),
+		}
+
+		res := types.HealthResponse{Status: "ready", Checks: checks}
+		status := http.StatusOK
+		if checks["storage"].Status != "ok" || checks["migrations"].Status != "ok" {
+			res.Status = "not_ready"
+			status = http.StatusServiceUnavailable
+		}
+
+		writeJSON(w, status, res)
+	default:
+		httpjson.WriteError(w, http.StatusNotFound, "Not found")
+		slog.DebugContext(r.Context(), "Received request with unsupported method", "method", r.Method, "route", "/readyz")
+	}
+}
+
+func (h *HealthHandler) checkStorage(ctx context.Context) types.HealthCheck {
+	if err := h.storage.Ping(ctx); err != nil {
+		slog.ErrorContext(ctx, "Readiness check failed, storage is unreachable", "error", err)
+		return types.HealthCheck{Status: "error", Error: "storage is unreachable"}
+	}
+	return types.HealthCheck{Status: "ok"}
+}
+
+func (h *HealthHandler) checkMigrations(ctx context.Context) types.HealthCheck {
+	state, err := h.storage.Migrations(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Readiness check failed, unable to read migrations", "error", err)
+		return types.HealthCheck{Status: "error", Error: "unable to read migrations"}
+	}
+	if len(state.Pending) > 0 {
+		return types.HealthCheck{Status: "pending", Migrations: state}
+	}
+	return types.HealthCheck{Status: "ok", Migrations: state}
+}
+
+func (h *HealthHandler) checkCache() types.HealthCheck {
+	if h.cache == nil {
+		return types.HealthCheck{Status: "disabled"}
+	}
+	stats := h.cache.CacheStats()
+	return types.HealthCheck{Status: "ok", Cache: &stats}
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/topboyasante/trunc8/internal/models"
+	"github.com/topboyasante/trunc8/internal/types"
+)
+
+// Mock storage for the readiness checks
+type mockHealthChecker struct {
+	pingFunc       func(ctx context.Context) error
+	migrationsFunc func(ctx context.Context) (*models.MigrationState, error)
+}
+
+func (m *mockHealthChecker) Ping(ctx context.Context) error {
+	if m.pingFunc != nil {
+		return m.pingFunc(ctx)
+	}
+	return nil
+}
+
+func (m *mockHealthChecker) Migrations(ctx context.Context) (*models.MigrationState, error) {
+	if m.migrationsFunc != nil {
+		return m.migrationsFunc(ctx)
+	}
+	return &models.MigrationState{Applied: []string{"0001_init"}, Pending: []string{}}, nil
+}
+
+type mockCacheStats struct {
+	stats models.CacheStats
+}
+
+func (m *mockCacheStats) CacheStats() models.CacheStats {
+	return m.stats
+}
+
+func getReadyz(t *testing.T, handler *HealthHandler) (int, types.HealthResponse) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+	w := httptest.NewRecorder()
+
+	handler.Readyz(w, req)
+
+	var response types.HealthResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Error unmarshaling response: %v", err)
+	}
+	return w.Code, response
+}
+
+func TestHealthz(t *testing.T) {
+	handler := NewHealthHandler(&mockHealthChecker{
+		pingFunc: func(ctx context.Context) error { return errors.New("connection refused") },
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	w := httptest.NewRecorder()
+
+	handler.Healthz(w, req)
+
+	// Liveness doesn't depend on the storage
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	if w.Body.String() != `{"status":"ok"}` {
+		t.Errorf("Unexpected body: %s", w.Body.String())
+	}
+}
+
+func TestReadyz_Ready(t *testing.T) {
+	cache := &mockCacheStats{stats: models.CacheStats{Hits: 3, Misses: 1, Entries: 1}}
+	status, response := getReadyz(t, NewHealthHandler(&mockHealthChecker{}, cache))
+
+	if status != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, status)
+	}
+	if response.Status != "ready" {
+		t.Errorf("Expected status 'ready', got '%s'", response.Status)
+	}
+	if check := response.Checks["storage"]; check.Status != "ok" {
+		t.Errorf("Unexpected storage check: %+v", check)
+	}
+	if check := response.Checks["migrations"]; check.Status != "ok" || len(check.Migrations.Applied) != 1 {
+		t.Errorf("Unexpected migrations check: %+v", check)
+	}
+	if check := response.Checks["cache"]; check.Status != "ok" || check.Cache == nil || check.Cache.Hits != 3 {
+		t.Errorf("Unexpected cache check: %+v", check)
+	}
+}
+
+func TestReadyz_CacheDisabled(t *testing.T) {
+	status, response := getReadyz(t, NewHealthHandler(&mockHealthChecker{}, nil))
+
+	if status != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, status)
+	}
+	if check := response.Checks["cache"]; check.Status != "disabled" {
+		t.Errorf("Expected cache check 'disabled', got '%s'", check.Status)
+	}
+}
+
+func TestReadyz_NotReady(t *testing.T) {
+	tests := []struct {
+		name    string
+		storage *mockHealthChecker
+		check   string
+		status  string
+	}{
+		{
+			name: "storage unreachable",
+			storage: &mockHealthChecker{
+				pingFunc: func(ctx context.Context) error { return errors.New("connection refused") },
+			},
+			check:  "storage",
+			status: "error",
+		},
+		{
+			name: "migrations pending",
+			storage: &mockHealthChecker{
+				migrationsFunc: func(ctx context.Context) (*models.MigrationState, error) {
+					return &models.MigrationState{Applied: []string{"0001_init"}, Pending: []string{"0002_next"}}, nil
+				},
+			},
+			check:  "migrations",
+			status: "pending",
+		},
+		{
+			name: "migrations unreadable",
+			storage: &mockHealthChecker{
+				migrationsFunc: func(ctx context.Context) (*models.MigrationState, error) {
+					return nil, errors.New("relation \"schema_migrations\" does not exist")
+				},
+			},
+			check:  "migrations",
+			status: "error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, response := getReadyz(t, NewHealthHandler(tt.storage, nil))
+
+			if status != http.StatusServiceUnavailable {
+				t.Fatalf("Expected status code %d, got %d", http.StatusServiceUnavailable, status)
+			}
+			if response.Status != "not_ready" {
+				t.Errorf("Expected status 'not_ready', got '%s'", response.Status)
+			}
+			if check := response.Checks[tt.check]; check.Status != tt.status {
+				t.Errorf("Expected %s check '%s', got '%s'", tt.check, tt.status, check.Status)
+			}
+		})
+	}
+}
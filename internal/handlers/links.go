@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/topboyasante/trunc8/internal/httpjson"
+	"github.com/topboyasante/trunc8/internal/models"
+	"github.com/topboyasante/trunc8/internal/types"
+)
+
+// ListLinks handles GET /api/links.
+// Optional query parameters: owner, tag, domain, status, q (part of the destination),
+// sort (created_at or clicks), order (asc or desc, default desc), limit and cursor.
+func (h *ShortnerHandler) ListLinks(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		query, err := parseListQuery(r)
+		if err != nil {
+			httpjson.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		page, err := h.service.ListLinks(r.Context(), query)
+		if err != nil {
+			writeServiceError(w, r, err, http.StatusInternalServerError, "Unable to list links")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, page)
+	default:
+		httpjson.WriteError(w, http.StatusNotFound, "Not found")
+		slog.DebugContext(r.Context(), "Received request with unsupported method", "method", r.Method, "route", "/api/links")
+	}
+}
+
+func parseListQuery(r *http.Request) (models.ListQuery, error) {
+	params := r.URL.Query()
+	query := models.ListQuery{
+		OwnerID: params.Get("owner"),
+		Tag:     params.Get("tag"),
+		Domain:  params.Get("domain"),
+		Status:  params.Get("status"),
+		Search:  params.Get("q"),
+		Sort:    params.Get("sort"),
+		Cursor:  params.Get("cursor"),
+	}
+
+	switch params.Get("order") {
+	case "", "desc":
+		query.Descending = true
+	case "asc":
+	default:
+		return query, errors.New("order must be asc or desc")
+	}
+
+	if v := params.Get("limit"); v != "" {
+		var err error
+		if query.Limit, err = strconv.Atoi(v); err != nil || query.Limit < 1 {
+			return query, errors.New("limit must be a positive number")
+		}
+	}
+
+	return query, nil
+}
+
+// Link handles GET, PATCH and DELETE on /api/links/{code}.
+func (h *ShortnerHandler) Link(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+
+	switch r.Method {
+	case http.MethodGet:
+		url, err := h.service.GetLink(r.Context(), code)
+		if err != nil {
+			writeServiceError(w, r, err, http.StatusInternalServerError, "Unable to retrieve link")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, url)
+	case http.MethodPatch:
+		defer r.Body.Close()
+
+		var payload types.UpdateLinkRequest
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			httpjson.WriteError(w, http.StatusBadRequest, "Invalid JSON")
+			return
+		}
+
+		url, err := h.service.UpdateLink(r.Context(), code, payload)
+		if err != nil {
+			writeServiceError(w, r, err, http.StatusInternalServerError, "Unable to update link")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, url)
+	case http.MethodDelete:
+		if err := h.service.DeleteLink(r.Context(), code); err != nil {
+			writeServiceError(w, r, err, http.StatusInternalServerError, "Unable to delete link")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		httpjson.WriteError(w, http.StatusNotFound, "Not found")
+		slog.DebugContext(r.Context(), "Received request with unsupported method", "method", r.Method, "route", "/api/links/{code}")
+	}
+}
+
+// DisableLink handles POST /api/links/{code}/disable.
+func (h *ShortnerHandler) DisableLink(w http.ResponseWriter, r *http.Request) {
+	h.setLinkDisabled(w, r, true)
+}
+
+// EnableLink handles POST /api/links/{code}/enable.
+func (h *ShortnerHandler) EnableLink(w http.ResponseWriter, r *http.Request) {
+	h.setLinkDisabled(w, r, false)
+}
+
+func (h *ShortnerHandler) setLinkDisabled(w http.ResponseWriter, r *http.Request, disabled bool) {
+	switch r.Method {
+	case http.MethodPost:
+		url, err := h.service.SetLinkDisabled(r.Context(), r.PathValue("code"), disabled)
+		if err != nil {
+			writeServiceError(w, r, err, http.StatusInternalServerError, "Unable to update link")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, url)
+	default:
+		httpjson.WriteError(w, http.StatusNotFound, "Not found")
+		slog.DebugContext(r.Context(), "Received request with unsupported method", "method", r.Method, "path", r.URL.Path)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	jsonRes, err := json.Marshal(v)
+	if err != nil {
+		httpjson.WriteError(w, http.StatusInternalServerError, "Error encoding response")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(jsonRes)
+}
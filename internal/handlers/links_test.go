@@ -0,0 +1,336 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/topboyasante/trunc8/internal/models"
+	"github.com/topboyasante/trunc8/internal/types"
+)
+
+func newLinksMux(handler *ShortnerHandler) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/links/{code}", handler.Link)
+	mux.HandleFunc("/api/links/{code}/disable", handler.DisableLink)
+	mux.HandleFunc("/api/links/{code}/enable", handler.EnableLink)
+	return mux
+}
+
+func TestLink_Get(t *testing.T) {
+	mux := newLinksMux(NewShortnerHandler(&mockShortnerService{}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/links/TEST", nil)
+	w := httptest.NewRecorder()
+
+	mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var response models.URL
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Error unmarshaling response: %v", err)
+	}
+
+	if response.Code != "TEST" {
+		t.Errorf("Expected code 'TEST', got '%s'", response.Code)
+	}
+}
+
+func TestLink_GetFieldNames(t *testing.T) {
+	expiresAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	mux := newLinksMux(NewShortnerHandler(&mockShortnerService{
+		getLinkFunc: func(ctx context.Context, code string) (*models.URL, error) {
+			return &models.URL{
+				ID:           "test-id",
+				OriginalURL:  "https://example.com",
+				Code:         code,
+				ClickCount:   3,
+				ExpiresAt:    &expiresAt,
+				MaxClicks:    10,
+				RedirectType: http.StatusMovedPermanently,
+				Tags:         []string{"newsletter"},
+				Domain:       "example.com",
+				OwnerID:      "user-1",
+				WorkspaceID:  "ws-1",
+			}, nil
+		},
+	}))
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/links/TEST", nil))
+
+	var response map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Error unmarshaling response: %v", err)
+	}
+
+	for _, field := range []string{"original_url", "code", "click_count", "expires_at", "max_clicks", "disabled", "redirect_type", "tags", "domain", "created_at", "workspace_id"} {
+		if _, ok := response[field]; !ok {
+			t.Errorf("Expected field '%s' in %v", field, response)
+		}
+	}
+	for _, field := range []string{"ID", "id", "OriginalURL", "owner_id", "OwnerID", "WorkspaceID"} {
+		if _, ok := response[field]; ok {
+			t.Errorf("Expected no field '%s' in %v", field, response)
+		}
+	}
+}
+
+func TestLink_Patch(t *testing.T) {
+	mockService := &mockShortnerService{
+		updateLinkFunc: func(ctx context.Context, code string, req types.UpdateLinkRequest) (*models.URL, error) {
+			if code != "TEST" {
+				t.Errorf("Expected code 'TEST', got '%s'", code)
+			}
+			if req.URL == nil || *req.URL != "https://example.com/new" {
+				t.Errorf("Expected the new URL to be passed on, got %v", req.URL)
+			}
+			if req.ExpiresAt != nil || req.RedirectType != nil {
+				t.Error("Expected missing fields to stay nil")
+			}
+			return &models.URL{Code: code, OriginalURL: *req.URL}, nil
+		},
+	}
+
+	mux := newLinksMux(NewShortnerHandler(mockService))
+
+	req := httptest.NewRequest(http.MethodPatch, "/api/links/TEST", strings.NewReader(`{"url":"https://example.com/new"}`))
+	w := httptest.NewRecorder()
+
+	mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+
+	if !strings.Contains(w.Body.String(), "https://example.com/new") {
+		t.Errorf("Expected the updated link in the response, got %s", w.Body.String())
+	}
+}
+
+func TestLink_PatchInvalidJSON(t *testing.T) {
+	mux := newLinksMux(NewShortnerHandler(&mockShortnerService{}))
+
+	req := httptest.NewRequest(http.MethodPatch, "/api/links/TEST", strings.NewReader("invalid json"))
+	w := httptest.NewRecorder()
+
+	mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestLink_Delete(t *testing.T) {
+	deleted := ""
+	mockService := &mockShortnerService{
+		deleteLinkFunc: func(ctx context.Context, code string) error {
+			deleted = code
+			return nil
+		},
+	}
+
+	mux := newLinksMux(NewShortnerHandler(mockService))
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/links/TEST", nil)
+	w := httptest.NewRecorder()
+
+	mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Expected status code %d, got %d", http.StatusNoContent, w.Code)
+	}
+
+	if deleted != "TEST" {
+		t.Errorf("Expected 'TEST' to be deleted, got '%s'", deleted)
+	}
+}
+
+func TestLink_ServiceErrors(t *testing.T) {
+	tests := []struct {
+		method string
+		body   string
+		err    error
+		status int
+	}{
+		{http.MethodGet, "", fmt.Errorf("%w: TEST", models.ErrNotFound), http.StatusNotFound},
+		{http.MethodPatch, `{"redirect_type":303}`, fmt.Errorf("%w: must be 301, 302, 307 or 308", models.ErrInvalidRedirect), http.StatusBadRequest},
+		{http.MethodPatch, `{}`, fmt.Errorf("%w: TEST", models.ErrNotFound), http.StatusNotFound},
+		{http.MethodDelete, "", fmt.Errorf("%w: TEST", models.ErrNotFound), http.StatusNotFound},
+		{http.MethodDelete, "", fmt.Errorf("connection refused"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		mockService := &mockShortnerService{
+			getLinkFunc: func(ctx context.Context, code string) (*models.URL, error) {
+				return nil, tt.err
+			},
+			updateLinkFunc: func(ctx context.Context, code string, req types.UpdateLinkRequest) (*models.URL, error) {
+				return nil, tt.err
+			},
+			deleteLinkFunc: func(ctx context.Context, code string) error {
+				return tt.err
+			},
+		}
+
+		mux := newLinksMux(NewShortnerHandler(mockService))
+
+		req := httptest.NewRequest(tt.method, "/api/links/TEST", strings.NewReader(tt.body))
+		w := httptest.NewRecorder()
+
+		mux.ServeHTTP(w, req)
+
+		if w.Code != tt.status {
+			t.Errorf("%s '%v': expected status code %d, got %d", tt.method, tt.err, tt.status, w.Code)
+		}
+	}
+}
+
+func TestDisableAndEnableLink(t *testing.T) {
+	tests := []struct {
+		path     string
+		disabled bool
+	}{
+		{"/api/links/TEST/disable", true},
+		{"/api/links/TEST/enable", false},
+	}
+
+	for _, tt := range tests {
+		mux := newLinksMux(NewShortnerHandler(&mockShortnerService{}))
+
+		req := httptest.NewRequest(http.MethodPost, tt.path, nil)
+		w := httptest.NewRecorder()
+
+		mux.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d for %s, got %d", http.StatusOK, tt.path, w.Code)
+		}
+
+		var response models.URL
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Error unmarshaling response: %v", err)
+		}
+
+		if response.Disabled != tt.disabled {
+			t.Errorf("Expected disabled %v for %s, got %v", tt.disabled, tt.path, response.Disabled)
+		}
+	}
+}
+
+func TestLink_UnsupportedMethod(t *testing.T) {
+	mux := newLinksMux(NewShortnerHandler(&mockShortnerService{}))
+
+	for _, r := range []*http.Request{
+		httptest.NewRequest(http.MethodPost, "/api/links/TEST", nil),
+		httptest.NewRequest(http.MethodGet, "/api/links/TEST/disable", nil),
+	} {
+		w := httptest.NewRecorder()
+
+		mux.ServeHTTP(w, r)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected status code %d for %s %s, got %d", http.StatusNotFound, r.Method, r.URL.Path, w.Code)
+		}
+	}
+}
+
+func TestListLinks_Success(t *testing.T) {
+	mockService := &mockShortnerService{
+		listLinksFunc: func(ctx context.Context, query models.ListQuery) (*models.LinkPage, error) {
+			if query.Tag != "newsletter" || query.Domain != "example.com" || query.Status != "active" || query.Search != "sale" {
+				t.Errorf("Expected filters to be passed on, got %+v", query)
+			}
+			if query.Sort != "clicks" || query.Descending {
+				t.Errorf("Expected ascending sort by clicks, got %s descending=%v", query.Sort, query.Descending)
+			}
+			if query.Limit != 5 || query.Cursor != "abc" {
+				t.Errorf("Expected limit 5 and cursor 'abc', got %d and '%s'", query.Limit, query.Cursor)
+			}
+			return &models.LinkPage{
+				Links:      []*models.URL{{Code: "TEST", OriginalURL: "https://example.com/sale"}},
+				NextCursor: "def",
+			}, nil
+		},
+	}
+
+	handler := NewShortnerHandler(mockService)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/links?tag=newsletter&domain=example.com&status=active&q=sale&sort=clicks&order=asc&limit=5&cursor=abc", nil)
+	w := httptest.NewRecorder()
+
+	handler.ListLinks(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var response models.LinkPage
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Error unmarshaling response: %v", err)
+	}
+
+	if len(response.Links) != 1 || response.NextCursor != "def" {
+		t.Errorf("Unexpected page: %+v", response)
+	}
+}
+
+func TestListLinks_DefaultOrder(t *testing.T) {
+	mockService := &mockShortnerService{
+		listLinksFunc: func(ctx context.Context, query models.ListQuery) (*models.LinkPage, error) {
+			if !query.Descending {
+				t.Error("Expected descending order by default")
+			}
+			return &models.LinkPage{Links: []*models.URL{}}, nil
+		},
+	}
+
+	handler := NewShortnerHandler(mockService)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/links", nil)
+	w := httptest.NewRecorder()
+
+	handler.ListLinks(w, req)
+
+	if !strings.Contains(w.Body.String(), `"links":[]`) {
+		t.Errorf("Expected an empty list, got %s", w.Body.String())
+	}
+}
+
+func TestListLinks_InvalidParameters(t *testing.T) {
+	for _, query := range []string{"order=up", "limit=abc", "limit=0"} {
+		handler := NewShortnerHandler(&mockShortnerService{})
+
+		req := httptest.NewRequest(http.MethodGet, "/api/links?"+query, nil)
+		w := httptest.NewRecorder()
+
+		handler.ListLinks(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code %d for '%s', got %d", http.StatusBadRequest, query, w.Code)
+		}
+	}
+
+	mockService := &mockShortnerService{
+		listLinksFunc: func(ctx context.Context, query models.ListQuery) (*models.LinkPage, error) {
+			return nil, fmt.Errorf("%w: malformed cursor", models.ErrInvalidListQuery)
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/links?cursor=junk", nil)
+	w := httptest.NewRecorder()
+
+	NewShortnerHandler(mockService).ListLinks(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for a bad cursor, got %d", http.StatusBadRequest, w.Code)
+	}
+}
@@ -3,18 +3,27 @@ package handlers
 import (
 	"context"
 	"encoding/json"
-	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 
+	"github.com/topboyasante/trunc8/internal/httpjson"
 	"github.com/topboyasante/trunc8/internal/models"
 	"github.com/topboyasante/trunc8/internal/types"
+	"github.com/topboyasante/trunc8/internal/utils"
 )
 
 // ShortenerServiceInterface defines the interface for shortener service operations
 type ShortenerServiceInterface interface {
-	ShortenURL(ctx context.Context, originalURL string) (*models.URL, error)
-	RedirectURL(ctx context.Context, code string) (string, error)
+	ShortenURL(ctx context.Context, req types.ShortenRequest) (*models.URL, error)
+	CheckBulkSize(n int) error
+	ShortenMany(ctx context.Context, reqs []types.ShortenRequest) ([]*models.URL, []error, error)
+	RedirectURL(ctx context.Context, req types.RedirectRequest) (*models.URL, error)
+	LinkStats(ctx context.Context, query models.StatsQuery) (*models.LinkStats, error)
+	GetLink(ctx context.Context, code string) (*models.URL, error)
+	UpdateLink(ctx context.Context, code string, req types.UpdateLinkRequest) (*models.URL, error)
+	SetLinkDisabled(ctx context.Context, code string, disabled bool) (*models.URL, error)
+	DeleteLink(ctx context.Context, code string) error
+	ListLinks(ctx context.Context, query models.ListQuery) (*models.LinkPage, error)
 }
 
 type ShortnerHandler struct {
@@ -35,22 +44,21 @@ func (h *ShortnerHandler) ShortenURL(w http.ResponseWriter, r *http.Request) {
 		// read and decode the body into a struct
 		err := json.NewDecoder(r.Body).Decode(&payload)
 		if err != nil {
-			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			httpjson.WriteError(w, http.StatusBadRequest, "Invalid JSON")
 			return
 		}
 
 		// this expects some context
-		res, err := h.service.ShortenURL(r.Context(), payload.URL)
+		res, err := h.service.ShortenURL(r.Context(), payload)
 		if err != nil {
-			fmt.Print(err)
-			http.Error(w, "Error shortening url", http.StatusBadRequest)
+			writeServiceError(w, r, err, http.StatusBadRequest, "Error shortening url")
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
 		jsonRes, err := json.Marshal(res)
 		if err != nil {
-			http.Error(w, "Error encoding response", http.StatusInternalServerError)
+			httpjson.WriteError(w, http.StatusInternalServerError, "Error encoding response")
 			return
 		}
 
@@ -59,31 +67,32 @@ func (h *ShortnerHandler) ShortenURL(w http.ResponseWriter, r *http.Request) {
 		// Always close the body with defer r.Body.Close() to prevent resource leaks
 		defer r.Body.Close()
 	default:
-		w.WriteHeader(http.StatusNotFound)
-		w.Write([]byte("Not found"))
-		log.Println("Received request with unsupported method on /shorten")
+		httpjson.WriteError(w, http.StatusNotFound, "Not found")
+		slog.DebugContext(r.Context(), "Received request with unsupported method", "method", r.Method, "route", "/shorten")
 	}
 }
 
+// RedirectURL sends the visitor to the link's destination.
+// It accepts every method, since 307 and 308 links let API clients POST through them;
+// the service decides whether the link can be followed with the request's method.
 func (h *ShortnerHandler) RedirectURL(w http.ResponseWriter, r *http.Request) {
-	switch r.Method {
-	case http.MethodGet:
-		shortCode := r.URL.Path[1:] // Remove leading "/" to get "someCODE"
-		if shortCode == "" {
-			http.Error(w, "No short code provided", http.StatusBadRequest)
-			return
-		}
-
-		url, err := h.service.RedirectURL(r.Context(), shortCode)
-		if err != nil {
-			http.Error(w, "Unable to retrieve full url", http.StatusInternalServerError)
-			return
-		}
+	shortCode := r.URL.Path[1:] // Remove leading "/" to get "someCODE"
+	if shortCode == "" {
+		httpjson.WriteError(w, http.StatusBadRequest, "No short code provided")
+		return
+	}
 
-		http.Redirect(w, r, url, http.StatusMovedPermanently)
-	default:
-		w.WriteHeader(http.StatusNotFound)
-		w.Write([]byte("Not found"))
-		log.Println("Received request with unsupported method on /shorten")
+	url, err := h.service.RedirectURL(r.Context(), types.RedirectRequest{
+		Code:      shortCode,
+		Method:    r.Method,
+		Referrer:  r.Referer(),
+		UserAgent: r.UserAgent(),
+		ClientIP:  utils.ClientIP(r),
+	})
+	if err != nil {
+		writeServiceError(w, r, err, http.StatusInternalServerError, "Unable to retrieve full url")
+		return
 	}
+
+	http.Redirect(w, r, url.OriginalURL, url.RedirectType)
 }
@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -16,37 +17,116 @@ import (
 
 // Mock service for testing
 type mockShortnerService struct {
-	shortenURLFunc  func(ctx context.Context, originalURL string) (*models.URL, error)
-	redirectURLFunc func(ctx context.Context, code string) (string, error)
+	shortenURLFunc    func(ctx context.Context, req types.ShortenRequest) (*models.URL, error)
+	checkBulkSizeFunc func(n int) error
+	shortenManyFunc   func(ctx context.Context, reqs []types.ShortenRequest) ([]*models.URL, []error, error)
+	redirectURLFunc   func(ctx context.Context, req types.RedirectRequest) (*models.URL, error)
+	linkStatsFunc     func(ctx context.Context, query models.StatsQuery) (*models.LinkStats, error)
+	getLinkFunc       func(ctx context.Context, code string) (*models.URL, error)
+	updateLinkFunc    func(ctx context.Context, code string, req types.UpdateLinkRequest) (*models.URL, error)
+	setDisabledFunc   func(ctx context.Context, code string, disabled bool) (*models.URL, error)
+	deleteLinkFunc    func(ctx context.Context, code string) error
+	listLinksFunc     func(ctx context.Context, query models.ListQuery) (*models.LinkPage, error)
 }
 
-func (m *mockShortnerService) ShortenURL(ctx context.Context, originalURL string) (*models.URL, error) {
+func (m *mockShortnerService) ShortenURL(ctx context.Context, req types.ShortenRequest) (*models.URL, error) {
 	if m.shortenURLFunc != nil {
-		return m.shortenURLFunc(ctx, originalURL)
+		return m.shortenURLFunc(ctx, req)
 	}
 	return &models.URL{
 		ID:          "test-id",
-		OriginalURL: originalURL,
+		OriginalURL: req.URL,
 		Code:        "TEST",
 		ClickCount:  0,
 	}, nil
 }
 
-func (m *mockShortnerService) RedirectURL(ctx context.Context, code string) (string, error) {
+// ShortenMany falls back to ShortenURL for every request
+func (m *mockShortnerService) CheckBulkSize(n int) error {
+	if m.checkBulkSizeFunc != nil {
+		return m.checkBulkSizeFunc(n)
+	}
+	return nil
+}
+
+func (m *mockShortnerService) ShortenMany(ctx context.Context, reqs []types.ShortenRequest) ([]*models.URL, []error, error) {
+	if m.shortenManyFunc != nil {
+		return m.shortenManyFunc(ctx, reqs)
+	}
+	links := make([]*models.URL, len(reqs))
+	errs := make([]error, len(reqs))
+	for i, req := range reqs {
+		links[i], errs[i] = m.ShortenURL(ctx, req)
+	}
+	return links, errs, nil
+}
+
+func (m *mockShortnerService) RedirectURL(ctx context.Context, req types.RedirectRequest) (*models.URL, error) {
 	if m.redirectURLFunc != nil {
-		return m.redirectURLFunc(ctx, code)
+		return m.redirectURLFunc(ctx, req)
+	}
+	url := &models.URL{
+		OriginalURL:  "https://example.com",
+		Code:         req.Code,
+		RedirectType: http.StatusMovedPermanently,
+	}
+	// Like the real service, only 307/308 links can be followed with other methods than GET
+	if !url.AcceptsMethod(req.Method) {
+		return nil, fmt.Errorf("%w: %s does not accept %s requests", models.ErrNotFound, req.Code, req.Method)
+	}
+	return url, nil
+}
+
+func (m *mockShortnerService) LinkStats(ctx context.Context, query models.StatsQuery) (*models.LinkStats, error) {
+	if m.linkStatsFunc != nil {
+		return m.linkStatsFunc(ctx, query)
+	}
+	return &models.LinkStats{Code: query.Code}, nil
+}
+
+func (m *mockShortnerService) GetLink(ctx context.Context, code string) (*models.URL, error) {
+	if m.getLinkFunc != nil {
+		return m.getLinkFunc(ctx, code)
+	}
+	return &models.URL{ID: "test-id", OriginalURL: "https://example.com", Code: code}, nil
+}
+
+func (m *mockShortnerService) UpdateLink(ctx context.Context, code string, req types.UpdateLinkRequest) (*models.URL, error) {
+	if m.updateLinkFunc != nil {
+		return m.updateLinkFunc(ctx, code, req)
+	}
+	return &models.URL{ID: "test-id", OriginalURL: "https://example.com", Code: code}, nil
+}
+
+func (m *mockShortnerService) SetLinkDisabled(ctx context.Context, code string, disabled bool) (*models.URL, error) {
+	if m.setDisabledFunc != nil {
+		return m.setDisabledFunc(ctx, code, disabled)
+	}
+	return &models.URL{ID: "test-id", OriginalURL: "https://example.com", Code: code, Disabled: disabled}, nil
+}
+
+func (m *mockShortnerService) DeleteLink(ctx context.Context, code string) error {
+	if m.deleteLinkFunc != nil {
+		return m.deleteLinkFunc(ctx, code)
+	}
+	return nil
+}
+
+func (m *mockShortnerService) ListLinks(ctx context.Context, query models.ListQuery) (*models.LinkPage, error) {
+	if m.listLinksFunc != nil {
+		return m.listLinksFunc(ctx, query)
 	}
-	return "https://example.com", nil
+	return &models.LinkPage{Links: []*models.URL{}}, nil
 }
 
 func TestNewShortnerHandler(t *testing.T) {
 	mockService := &mockShortnerService{}
 	handler := NewShortnerHandler(mockService)
-	
+
 	if handler == nil {
 		t.Error("Expected handler to be non-nil")
 	}
-	
+
 	if handler.service == nil {
 		t.Error("Expected handler service to be non-nil")
 	}
@@ -54,44 +134,44 @@ func TestNewShortnerHandler(t *testing.T) {
 
 func TestShortenURL_Success(t *testing.T) {
 	mockService := &mockShortnerService{
-		shortenURLFunc: func(ctx context.Context, originalURL string) (*models.URL, error) {
-			if originalURL != "https://example.com" {
-				t.Errorf("Expected URL 'https://example.com', got '%s'", originalURL)
+		shortenURLFunc: func(ctx context.Context, req types.ShortenRequest) (*models.URL, error) {
+			if req.URL != "https://example.com" {
+				t.Errorf("Expected URL 'https://example.com', got '%s'", req.URL)
 			}
 			return &models.URL{
 				ID:          "test-id",
-				OriginalURL: originalURL,
+				OriginalURL: req.URL,
 				Code:        "TEST",
 				ClickCount:  0,
 			}, nil
 		},
 	}
-	
+
 	handler := NewShortnerHandler(mockService)
-	
+
 	payload := types.ShortenRequest{URL: "https://example.com"}
 	jsonPayload, _ := json.Marshal(payload)
-	
+
 	req := httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBuffer(jsonPayload))
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
-	
+
 	handler.ShortenURL(w, req)
-	
+
 	if w.Code != http.StatusOK {
 		t.Errorf("Expected status code %d, got %d", http.StatusOK, w.Code)
 	}
-	
+
 	var response models.URL
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	if err != nil {
 		t.Fatalf("Error unmarshaling response: %v", err)
 	}
-	
+
 	if response.OriginalURL != "https://example.com" {
 		t.Errorf("Expected original URL 'https://example.com', got '%s'", response.OriginalURL)
 	}
-	
+
 	if response.Code != "TEST" {
 		t.Errorf("Expected code 'TEST', got '%s'", response.Code)
 	}
@@ -99,17 +179,17 @@ func TestShortenURL_Success(t *testing.T) {
 
 func TestShortenURL_InvalidJSON(t *testing.T) {
 	handler := NewShortnerHandler(&mockShortnerService{})
-	
+
 	req := httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader("invalid json"))
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
-	
+
 	handler.ShortenURL(w, req)
-	
+
 	if w.Code != http.StatusBadRequest {
 		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, w.Code)
 	}
-	
+
 	if !strings.Contains(w.Body.String(), "Invalid JSON") {
 		t.Error("Expected 'Invalid JSON' in response body")
 	}
@@ -117,43 +197,109 @@ func TestShortenURL_InvalidJSON(t *testing.T) {
 
 func TestShortenURL_ServiceError(t *testing.T) {
 	mockService := &mockShortnerService{
-		shortenURLFunc: func(ctx context.Context, originalURL string) (*models.URL, error) {
+		shortenURLFunc: func(ctx context.Context, req types.ShortenRequest) (*models.URL, error) {
 			return nil, errors.New("service error")
 		},
 	}
-	
+
 	handler := NewShortnerHandler(mockService)
-	
+
 	payload := types.ShortenRequest{URL: "https://example.com"}
 	jsonPayload, _ := json.Marshal(payload)
-	
+
 	req := httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBuffer(jsonPayload))
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
-	
+
 	handler.ShortenURL(w, req)
-	
+
 	if w.Code != http.StatusBadRequest {
 		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, w.Code)
 	}
-	
+
 	if !strings.Contains(w.Body.String(), "Error shortening url") {
 		t.Error("Expected 'Error shortening url' in response body")
 	}
 }
 
+func TestShortenURL_WithAlias(t *testing.T) {
+	mockService := &mockShortnerService{
+		shortenURLFunc: func(ctx context.Context, req types.ShortenRequest) (*models.URL, error) {
+			if req.Alias != "launch-2026" {
+				t.Errorf("Expected alias 'launch-2026', got '%s'", req.Alias)
+			}
+			return &models.URL{ID: "test-id", OriginalURL: req.URL, Code: req.Alias}, nil
+		},
+	}
+
+	handler := NewShortnerHandler(mockService)
+
+	req := httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(`{"url": "https://example.com", "alias": "launch-2026"}`))
+	w := httptest.NewRecorder()
+
+	handler.ShortenURL(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+
+	if !strings.Contains(w.Body.String(), "launch-2026") {
+		t.Error("Expected the alias in response body")
+	}
+}
+
+func TestShortenURL_ValidationErrors(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+	}{
+		{fmt.Errorf("%w: \"api\" is reserved", models.ErrInvalidAlias), http.StatusBadRequest},
+		{fmt.Errorf("%w: launch-2026", models.ErrAliasTaken), http.StatusConflict},
+		{fmt.Errorf("%w: expires_at must be in the future", models.ErrInvalidExpiration), http.StatusBadRequest},
+		{fmt.Errorf("%w: must be 301, 302, 307 or 308", models.ErrInvalidRedirect), http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		mockService := &mockShortnerService{
+			shortenURLFunc: func(ctx context.Context, req types.ShortenRequest) (*models.URL, error) {
+				return nil, tt.err
+			},
+		}
+
+		handler := NewShortnerHandler(mockService)
+
+		req := httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(`{"url": "https://example.com", "alias": "launch-2026"}`))
+		w := httptest.NewRecorder()
+
+		handler.ShortenURL(w, req)
+
+		if w.Code != tt.status {
+			t.Errorf("Expected status code %d for '%v', got %d", tt.status, tt.err, w.Code)
+		}
+
+		var response types.ErrorResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Error unmarshaling response: %v", err)
+		}
+
+		if response.Message != tt.err.Error() {
+			t.Errorf("Expected message '%v', got '%s'", tt.err, response.Message)
+		}
+	}
+}
+
 func TestShortenURL_UnsupportedMethod(t *testing.T) {
 	handler := NewShortnerHandler(&mockShortnerService{})
-	
+
 	req := httptest.NewRequest(http.MethodGet, "/shorten", nil)
 	w := httptest.NewRecorder()
-	
+
 	handler.ShortenURL(w, req)
-	
+
 	if w.Code != http.StatusNotFound {
 		t.Errorf("Expected status code %d, got %d", http.StatusNotFound, w.Code)
 	}
-	
+
 	if !strings.Contains(w.Body.String(), "Not found") {
 		t.Error("Expected 'Not found' in response body")
 	}
@@ -161,25 +307,37 @@ func TestShortenURL_UnsupportedMethod(t *testing.T) {
 
 func TestRedirectURL_Success(t *testing.T) {
 	mockService := &mockShortnerService{
-		redirectURLFunc: func(ctx context.Context, code string) (string, error) {
-			if code != "TEST" {
-				t.Errorf("Expected code 'TEST', got '%s'", code)
+		redirectURLFunc: func(ctx context.Context, req types.RedirectRequest) (*models.URL, error) {
+			if req.Code != "TEST" {
+				t.Errorf("Expected code 'TEST', got '%s'", req.Code)
+			}
+			if req.Referrer != "https://news.example.org" {
+				t.Errorf("Expected referrer 'https://news.example.org', got '%s'", req.Referrer)
+			}
+			if req.UserAgent != "test-agent" {
+				t.Errorf("Expected user agent 'test-agent', got '%s'", req.UserAgent)
+			}
+			if req.ClientIP != "203.0.113.7" {
+				t.Errorf("Expected client IP '203.0.113.7', got '%s'", req.ClientIP)
 			}
-			return "https://example.com", nil
+			return &models.URL{OriginalURL: "https://example.com", Code: req.Code, RedirectType: http.StatusMovedPermanently}, nil
 		},
 	}
-	
+
 	handler := NewShortnerHandler(mockService)
-	
+
 	req := httptest.NewRequest(http.MethodGet, "/TEST", nil)
+	req.Header.Set("Referer", "https://news.example.org")
+	req.Header.Set("User-Agent", "test-agent")
+	req.RemoteAddr = "203.0.113.7:51234"
 	w := httptest.NewRecorder()
-	
+
 	handler.RedirectURL(w, req)
-	
+
 	if w.Code != http.StatusMovedPermanently {
 		t.Errorf("Expected status code %d, got %d", http.StatusMovedPermanently, w.Code)
 	}
-	
+
 	location := w.Header().Get("Location")
 	if location != "https://example.com" {
 		t.Errorf("Expected location 'https://example.com', got '%s'", location)
@@ -188,16 +346,16 @@ func TestRedirectURL_Success(t *testing.T) {
 
 func TestRedirectURL_NoShortCode(t *testing.T) {
 	handler := NewShortnerHandler(&mockShortnerService{})
-	
+
 	req := httptest.NewRequest(http.MethodGet, "/", nil)
 	w := httptest.NewRecorder()
-	
+
 	handler.RedirectURL(w, req)
-	
+
 	if w.Code != http.StatusBadRequest {
 		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, w.Code)
 	}
-	
+
 	if !strings.Contains(w.Body.String(), "No short code provided") {
 		t.Error("Expected 'No short code provided' in response body")
 	}
@@ -205,40 +363,196 @@ func TestRedirectURL_NoShortCode(t *testing.T) {
 
 func TestRedirectURL_ServiceError(t *testing.T) {
 	mockService := &mockShortnerService{
-		redirectURLFunc: func(ctx context.Context, code string) (string, error) {
-			return "", errors.New("not found")
+		redirectURLFunc: func(ctx context.Context, req types.RedirectRequest) (*models.URL, error) {
+			return nil, errors.New("not found")
 		},
 	}
-	
+
 	handler := NewShortnerHandler(mockService)
-	
+
 	req := httptest.NewRequest(http.MethodGet, "/NOTFOUND", nil)
 	w := httptest.NewRecorder()
-	
+
 	handler.RedirectURL(w, req)
-	
+
 	if w.Code != http.StatusInternalServerError {
 		t.Errorf("Expected status code %d, got %d", http.StatusInternalServerError, w.Code)
 	}
-	
+
 	if !strings.Contains(w.Body.String(), "Unable to retrieve full url") {
 		t.Error("Expected 'Unable to retrieve full url' in response body")
 	}
 }
 
+func TestRedirectURL_Expired(t *testing.T) {
+	mockService := &mockShortnerService{
+		redirectURLFunc: func(ctx context.Context, req types.RedirectRequest) (*models.URL, error) {
+			return nil, fmt.Errorf("%w: %s", models.ErrExpired, req.Code)
+		},
+	}
+
+	handler := NewShortnerHandler(mockService)
+
+	req := httptest.NewRequest(http.MethodGet, "/TEST", nil)
+	w := httptest.NewRecorder()
+
+	handler.RedirectURL(w, req)
+
+	if w.Code != http.StatusGone {
+		t.Errorf("Expected status code %d, got %d", http.StatusGone, w.Code)
+	}
+
+	if !strings.Contains(w.Body.String(), "link has expired") {
+		t.Error("Expected 'link has expired' in response body")
+	}
+}
+
+func TestRedirectURL_DomainErrors(t *testing.T) {
+	tests := []struct {
+		err       error
+		status    int
+		errorCode string
+	}{
+		{fmt.Errorf("%w: NOTFOUND", models.ErrNotFound), http.StatusNotFound, "not_found"},
+		{fmt.Errorf("%w: TEST", models.ErrDisabled), http.StatusGone, "gone"},
+		{fmt.Errorf("%w: TEST", models.ErrExpired), http.StatusGone, "gone"},
+	}
+
+	for _, tt := range tests {
+		mockService := &mockShortnerService{
+			redirectURLFunc: func(ctx context.Context, req types.RedirectRequest) (*models.URL, error) {
+				return nil, tt.err
+			},
+		}
+
+		handler := NewShortnerHandler(mockService)
+
+		req := httptest.NewRequest(http.MethodGet, "/TEST", nil)
+		w := httptest.NewRecorder()
+
+		handler.RedirectURL(w, req)
+
+		if w.Code != tt.status {
+			t.Errorf("Expected status code %d for '%v', got %d", tt.status, tt.err, w.Code)
+		}
+
+		if contentType := w.Header().Get("Content-Type"); contentType != "application/json" {
+			t.Errorf("Expected JSON error body, got Content-Type '%s'", contentType)
+		}
+
+		var response types.ErrorResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Error unmarshaling response: %v", err)
+		}
+
+		if response.Error != tt.errorCode {
+			t.Errorf("Expected error '%s', got '%s'", tt.errorCode, response.Error)
+		}
+
+		if response.Message != tt.err.Error() {
+			t.Errorf("Expected message '%v', got '%s'", tt.err, response.Message)
+		}
+	}
+}
+
+func TestShortenURL_InvalidURL(t *testing.T) {
+	mockService := &mockShortnerService{
+		shortenURLFunc: func(ctx context.Context, req types.ShortenRequest) (*models.URL, error) {
+			return nil, fmt.Errorf("%w: original URL cannot be empty", models.ErrInvalidURL)
+		},
+	}
+
+	handler := NewShortnerHandler(mockService)
+
+	req := httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(`{"url": ""}`))
+	w := httptest.NewRecorder()
+
+	handler.ShortenURL(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	if !strings.Contains(w.Body.String(), "original URL cannot be empty") {
+		t.Error("Expected 'original URL cannot be empty' in response body")
+	}
+}
+
+func TestRedirectURL_ServiceErrorHidesDetails(t *testing.T) {
+	mockService := &mockShortnerService{
+		redirectURLFunc: func(ctx context.Context, req types.RedirectRequest) (*models.URL, error) {
+			return nil, errors.New("connection refused by 10.0.0.12:27017")
+		},
+	}
+
+	handler := NewShortnerHandler(mockService)
+
+	req := httptest.NewRequest(http.MethodGet, "/TEST", nil)
+	w := httptest.NewRecorder()
+
+	handler.RedirectURL(w, req)
+
+	if strings.Contains(w.Body.String(), "10.0.0.12") {
+		t.Error("Expected internal error details not to leak into the response")
+	}
+}
+
 func TestRedirectURL_UnsupportedMethod(t *testing.T) {
 	handler := NewShortnerHandler(&mockShortnerService{})
-	
+
 	req := httptest.NewRequest(http.MethodPost, "/TEST", nil)
 	w := httptest.NewRecorder()
-	
+
 	handler.RedirectURL(w, req)
-	
+
 	if w.Code != http.StatusNotFound {
 		t.Errorf("Expected status code %d, got %d", http.StatusNotFound, w.Code)
 	}
-	
-	if !strings.Contains(w.Body.String(), "Not found") {
-		t.Error("Expected 'Not found' in response body")
+
+	var response types.ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal error response: %v", err)
+	}
+
+	if response.Error != "not_found" {
+		t.Errorf("Expected error 'not_found', got '%s'", response.Error)
 	}
-}
\ No newline at end of file
+}
+
+func TestRedirectURL_RedirectTypes(t *testing.T) {
+	tests := []struct {
+		method       string
+		redirectType int
+	}{
+		{http.MethodGet, http.StatusFound},
+		{http.MethodGet, http.StatusMovedPermanently},
+		{http.MethodPost, http.StatusTemporaryRedirect},
+		{http.MethodPut, http.StatusPermanentRedirect},
+	}
+
+	for _, tt := range tests {
+		mockService := &mockShortnerService{
+			redirectURLFunc: func(ctx context.Context, req types.RedirectRequest) (*models.URL, error) {
+				if req.Method != tt.method {
+					t.Errorf("Expected method %s to be passed to the service, got %s", tt.method, req.Method)
+				}
+				return &models.URL{OriginalURL: "https://api.example.com/hook", Code: req.Code, RedirectType: tt.redirectType}, nil
+			},
+		}
+
+		handler := NewShortnerHandler(mockService)
+
+		req := httptest.NewRequest(tt.method, "/TEST", strings.NewReader(`{"event":"push"}`))
+		w := httptest.NewRecorder()
+
+		handler.RedirectURL(w, req)
+
+		if w.Code != tt.redirectType {
+			t.Errorf("Expected status code %d for %s, got %d", tt.redirectType, tt.method, w.Code)
+		}
+
+		if location := w.Header().Get("Location"); location != "https://api.example.com/hook" {
+			t.Errorf("Expected Location 'https://api.example.com/hook', got '%s'", location)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/topboyasante/trunc8/internal/httpjson"
+	"github.com/topboyasante/trunc8/internal/models"
+)
+
+// LinkStats handles GET /api/links/{code}/stats.
+// Optional query parameters: from and to (RFC 3339), interval (hour or day) and limit.
+func (h *ShortnerHandler) LinkStats(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		query, err := parseStatsQuery(r)
+		if err != nil {
+			httpjson.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		stats, err := h.service.LinkStats(r.Context(), query)
+		if err != nil {
+			writeServiceError(w, r, err, http.StatusInternalServerError, "Unable to retrieve stats")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		jsonRes, err := json.Marshal(stats)
+		if err != nil {
+			httpjson.WriteError(w, http.StatusInternalServerError, "Error encoding response")
+			return
+		}
+
+		w.Write(jsonRes)
+	default:
+		httpjson.WriteError(w, http.StatusNotFound, "Not found")
+		slog.DebugContext(r.Context(), "Received request with unsupported method", "method", r.Method, "route", "/api/links/{code}/stats")
+	}
+}
+
+func parseStatsQuery(r *http.Request) (models.StatsQuery, error) {
+	params := r.URL.Query()
+	query := models.StatsQuery{
+		Code:     r.PathValue("code"),
+		Interval: params.Get("interval"),
+	}
+
+	var err error
+	if v := params.Get("from"); v != "" {
+		if query.From, err = time.Parse(time.RFC3339, v); err != nil {
+			return query, errors.New("from must be an RFC 3339 timestamp")
+		}
+	}
+	if v := params.Get("to"); v != "" {
+		if query.To, err = time.Parse(time.RFC3339, v); err != nil {
+			return query, errors.New("to must be an RFC 3339 timestamp")
+		}
+	}
+	if v := params.Get("limit"); v != "" {
+		if query.Limit, err = strconv.Atoi(v); err != nil || query.Limit < 1 || query.Limit > 100 {
+			return query, errors.New("limit must be a number between 1 and 100")
+		}
+	}
+
+	return query, nil
+}
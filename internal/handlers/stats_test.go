@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/topboyasante/trunc8/internal/models"
+)
+
+func TestLinkStats_Success(t *testing.T) {
+	mockService := &mockShortnerService{
+		linkStatsFunc: func(ctx context.Context, query models.StatsQuery) (*models.LinkStats, error) {
+			if query.Code != "TEST" {
+				t.Errorf("Expected code 'TEST', got '%s'", query.Code)
+			}
+			if !query.From.Equal(time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)) {
+				t.Errorf("Unexpected from: %v", query.From)
+			}
+			if query.Interval != "day" {
+				t.Errorf("Expected interval 'day', got '%s'", query.Interval)
+			}
+			return &models.LinkStats{
+				Code:           query.Code,
+				TotalClicks:    42,
+				UniqueVisitors: 7,
+				TopReferrers:   []models.StatsCount{{Value: "(direct)", Clicks: 40}},
+			}, nil
+		},
+	}
+
+	handler := NewShortnerHandler(mockService)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/links/{code}/stats", handler.LinkStats)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/links/TEST/stats?from=2026-03-01T00:00:00Z&interval=day", nil)
+	w := httptest.NewRecorder()
+
+	mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var response models.LinkStats
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Error unmarshaling response: %v", err)
+	}
+
+	if response.TotalClicks != 42 {
+		t.Errorf("Expected 42 total clicks, got %d", response.TotalClicks)
+	}
+
+	if response.UniqueVisitors != 7 {
+		t.Errorf("Expected 7 unique visitors, got %d", response.UniqueVisitors)
+	}
+
+	if len(response.TopReferrers) != 1 || response.TopReferrers[0].Value != "(direct)" {
+		t.Errorf("Unexpected top referrers: %v", response.TopReferrers)
+	}
+}
+
+func TestLinkStats_InvalidParameters(t *testing.T) {
+	tests := []string{
+		"from=yesterday",
+		"to=2026-13-01",
+		"limit=0",
+		"limit=abc",
+	}
+
+	for _, params := range tests {
+		t.Run(params, func(t *testing.T) {
+			handler := NewShortnerHandler(&mockShortnerService{})
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/links/{code}/stats", handler.LinkStats)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/links/TEST/stats?"+params, nil)
+			w := httptest.NewRecorder()
+
+			mux.ServeHTTP(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, w.Code)
+			}
+		})
+	}
+}
+
+func TestLinkStats_ServiceErrors(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+	}{
+		{fmt.Errorf("%w: from must be before to", models.ErrInvalidStatsQuery), http.StatusBadRequest},
+		{fmt.Errorf("%w: NOTFOUND", models.ErrNotFound), http.StatusNotFound},
+		{errors.New("database error"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		mockService := &mockShortnerService{
+			linkStatsFunc: func(ctx context.Context, query models.StatsQuery) (*models.LinkStats, error) {
+				return nil, tt.err
+			},
+		}
+
+		handler := NewShortnerHandler(mockService)
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/api/links/{code}/stats", handler.LinkStats)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/links/TEST/stats", nil)
+		w := httptest.NewRecorder()
+
+		mux.ServeHTTP(w, req)
+
+		if w.Code != tt.status {
+			t.Errorf("Expected status code %d for '%v', got %d", tt.status, tt.err, w.Code)
+		}
+	}
+}
+
+func TestLinkStats_UnsupportedMethod(t *testing.T) {
+	handler := NewShortnerHandler(&mockShortnerService{})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/links/TEST/stats", nil)
+	w := httptest.NewRecorder()
+
+	handler.LinkStats(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status code %d, got %d", http.StatusNotFound, w.Code)
+	}
+
+	if !strings.Contains(w.Body.String(), "Not found") {
+		t.Error("Expected 'Not found' in response body")
+	}
+}
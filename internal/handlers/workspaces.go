@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/topboyasante/trunc8/internal/httpjson"
+	"github.com/topboyasante/trunc8/internal/models"
+	"github.com/topboyasante/trunc8/internal/types"
+)
+
+// WorkspaceServiceInterface defines the interface for workspace and user management
+type WorkspaceServiceInterface interface {
+	CreateWorkspace(ctx context.Context, req types.CreateWorkspaceRequest) (*models.Workspace, error)
+	ListWorkspaces(ctx context.Context) ([]*models.Workspace, error)
+	CreateUser(ctx context.Context, workspaceID string, req types.CreateUserRequest) (*models.User, error)
+	ListUsers(ctx context.Context, workspaceID string) ([]*models.User, error)
+}
+
+type WorkspaceHandler struct {
+	service WorkspaceServiceInterface
+}
+
+func NewWorkspaceHandler(service WorkspaceServiceInterface) *WorkspaceHandler {
+	return &WorkspaceHandler{
+		service: service,
+	}
+}
+
+// Workspaces handles GET and POST on /api/workspaces.
+func (h *WorkspaceHandler) Workspaces(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		workspaces, err := h.service.ListWorkspaces(r.Context())
+		if err != nil {
+			writeServiceError(w, r, err, http.StatusInternalServerError, "Unable to list workspaces")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, workspaces)
+	case http.MethodPost:
+		defer r.Body.Close()
+
+		var payload types.CreateWorkspaceRequest
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			httpjson.WriteError(w, http.StatusBadRequest, "Invalid JSON")
+			return
+		}
+
+		workspace, err := h.service.CreateWorkspace(r.Context(), payload)
+		if err != nil {
+			writeServiceError(w, r, err, http.StatusInternalServerError, "Unable to create workspace")
+			return
+		}
+
+		writeJSON(w, http.StatusCreated, workspace)
+	default:
+		httpjson.WriteError(w, http.StatusNotFound, "Not found")
+		slog.DebugContext(r.Context(), "Received request with unsupported method", "method", r.Method, "route", "/api/workspaces")
+	}
+}
+
+// Users handles GET and POST on /api/workspaces/{id}/users.
+func (h *WorkspaceHandler) Users(w http.ResponseWriter, r *http.Request) {
+	workspaceID := r.PathValue("id")
+
+	switch r.Method {
+	case http.MethodGet:
+		users, err := h.service.ListUsers(r.Context(), workspaceID)
+		if err != nil {
+			writeServiceError(w, r, err, http.StatusInternalServerError, "Unable to list users")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, users)
+	case http.MethodPost:
+		defer r.Body.Close()
+
+		var payload types.CreateUserRequest
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			httpjson.WriteError(w, http.StatusBadRequest, "Invalid JSON")
+			return
+		}
+
+		user, err := h.service.CreateUser(r.Context(), workspaceID, payload)
+		if err != nil {
+			writeServiceError(w, r, err, http.StatusInternalServerError, "Unable to create user")
+			return
+		}
+
+		writeJSON(w, http.StatusCreated, user)
+	default:
+		httpjson.WriteError(w, http.StatusNotFound, "Not found")
+		slog.DebugContext(r.Context(), "Received request with unsupported method", "method", r.Method, "route", "/api/workspaces/{id}/users")
+	}
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/topboyasante/trunc8/internal/models"
+	"github.com/topboyasante/trunc8/internal/types"
+)
+
+// Mock workspace service for testing
+type mockWorkspaceService struct {
+	createWorkspaceFunc func(ctx context.Context, req types.CreateWorkspaceRequest) (*models.Workspace, error)
+	listWorkspacesFunc  func(ctx context.Context) ([]*models.Workspace, error)
+	createUserFunc      func(ctx context.Context, workspaceID string, req types.CreateUserRequest) (*models.User, error)
+	listUsersFunc       func(ctx context.Context, workspaceID string) ([]*models.User, error)
+}
+
+func (m *mockWorkspaceService) CreateWorkspace(ctx context.Context, req types.CreateWorkspaceRequest) (*models.Workspace, error) {
+	if m.createWorkspaceFunc != nil {
+		return m.createWorkspaceFunc(ctx, req)
+	}
+	return &models.Workspace{ID: "ws-1", Name: req.Name}, nil
+}
+
+func (m *mockWorkspaceService) ListWorkspaces(ctx context.Context) ([]*models.Workspace, error) {
+	if m.listWorkspacesFunc != nil {
+		return m.listWorkspacesFunc(ctx)
+	}
+	return []*models.Workspace{{ID: "ws-1", Name: "Growth"}}, nil
+}
+
+func (m *mockWorkspaceService) CreateUser(ctx context.Context, workspaceID string, req types.CreateUserRequest) (*models.User, error) {
+	if m.createUserFunc != nil {
+		return m.createUserFunc(ctx, workspaceID, req)
+	}
+	return &models.User{ID: "user-1", WorkspaceID: workspaceID, Name: req.Name, Email: req.Email}, nil
+}
+
+func (m *mockWorkspaceService) ListUsers(ctx context.Context, workspaceID string) ([]*models.User, error) {
+	if m.listUsersFunc != nil {
+		return m.listUsersFunc(ctx, workspaceID)
+	}
+	return []*models.User{}, nil
+}
+
+func newWorkspacesMux(handler *WorkspaceHandler) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/workspaces", handler.Workspaces)
+	mux.HandleFunc("/api/workspaces/{id}/users", handler.Users)
+	return mux
+}
+
+func TestWorkspaces_Create(t *testing.T) {
+	mux := newWorkspacesMux(NewWorkspaceHandler(&mockWorkspaceService{}))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/workspaces", strings.NewReader(`{"name":"Growth"}`))
+	w := httptest.NewRecorder()
+
+	mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d", http.StatusCreated, w.Code)
+	}
+
+	var response models.Workspace
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Error unmarshaling response: %v", err)
+	}
+
+	if response.ID != "ws-1" || response.Name != "Growth" {
+		t.Errorf("Unexpected workspace: %+v", response)
+	}
+}
+
+func TestUsers_Create(t *testing.T) {
+	mux := newWorkspacesMux(NewWorkspaceHandler(&mockWorkspaceService{}))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/workspaces/ws-1/users", strings.NewReader(`{"name":"Ama","email":"ama@example.com"}`))
+	w := httptest.NewRecorder()
+
+	mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d", http.StatusCreated, w.Code)
+	}
+
+	var response models.User
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Error unmarshaling response: %v", err)
+	}
+
+	if response.WorkspaceID != "ws-1" {
+		t.Errorf("Expected workspace 'ws-1' from the path, got '%s'", response.WorkspaceID)
+	}
+}
+
+func TestWorkspaces_Errors(t *testing.T) {
+	mockService := &mockWorkspaceService{
+		createWorkspaceFunc: func(ctx context.Context, req types.CreateWorkspaceRequest) (*models.Workspace, error) {
+			return nil, fmt.Errorf("%w: name must be 1 to 100 characters", models.ErrInvalidWorkspace)
+		},
+		createUserFunc: func(ctx context.Context, workspaceID string, req types.CreateUserRequest) (*models.User, error) {
+			return nil, fmt.Errorf("%w: %s", models.ErrEmailTaken, req.Email)
+		},
+		listUsersFunc: func(ctx context.Context, workspaceID string) ([]*models.User, error) {
+			return nil, fmt.Errorf("%w: %s", models.ErrWorkspaceNotFound, workspaceID)
+		},
+	}
+
+	mux := newWorkspacesMux(NewWorkspaceHandler(mockService))
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{http.MethodPost, "/api/workspaces", `{"name":""}`, http.StatusBadRequest},
+		{http.MethodPost, "/api/workspaces", `invalid json`, http.StatusBadRequest},
+		{http.MethodPost, "/api/workspaces/ws-1/users", `{"name":"Ama","email":"ama@example.com"}`, http.StatusConflict},
+		{http.MethodGet, "/api/workspaces/missing/users", "", http.StatusNotFound},
+		{http.MethodDelete, "/api/workspaces", "", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		w := httptest.NewRecorder()
+
+		mux.ServeHTTP(w, req)
+
+		if w.Code != tt.status {
+			t.Errorf("%s %s: expected status code %d, got %d", tt.method, tt.path, tt.status, w.Code)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+// Package httpjson writes the JSON error body every part of the API answers with,
+// so the handlers and the middleware in front of them fail the same way.
+package httpjson
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/topboyasante/trunc8/internal/types"
+)
+
+// ErrorCode turns a status into the "error" field of an error body, e.g. 404 into "not_found".
+func ErrorCode(status int) string {
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+}
+
+// WriteError sends a JSON error body like {"error": "not_found", "message": "link not found: abc"}.
+// The "error" field is derived from the status, so clients can switch on it.
+func WriteError(w http.ResponseWriter, status int, message string) {
+	body, err := json.Marshal(types.ErrorResponse{Error: ErrorCode(status), Message: message})
+	if err != nil {
+		http.Error(w, message, status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	w.Write(body)
+}
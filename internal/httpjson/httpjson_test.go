@@ -0,0 +1,30 @@
+package httpjson
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/topboyasante/trunc8/internal/types"
+)
+
+func TestWriteError(t *testing.T) {
+	w := httptest.NewRecorder()
+	WriteError(w, http.StatusTooManyRequests, "Slow down")
+
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429, got %d", w.Code)
+	}
+	if w.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("Expected a JSON body, got '%s'", w.Header().Get("Content-Type"))
+	}
+
+	var body types.ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if body.Error != "too_many_requests" || body.Message != "Slow down" {
+		t.Errorf("Unexpected body: %+v", body)
+	}
+}
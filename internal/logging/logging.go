@@ -0,0 +1,74 @@
+// Package logging builds the structured logger and carries the ID of the request being served,
+// so every message logged while serving a request can be traced back to it.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log/slog"
+
+	"github.com/topboyasante/trunc8/internal/config"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// New returns a logger writing to w in the format and from the level picked in cfg.
+// Messages logged with a context, like slog.InfoContext, get the request_id and trace_id of that context.
+func New(w io.Writer, cfg config.LogConfig) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: cfg.Level}
+
+	var handler slog.Handler
+	if cfg.Format == "text" {
+		handler = slog.NewTextHandler(w, opts)
+	} else {
+		handler = slog.NewJSONHandler(w, opts)
+	}
+
+	return slog.New(contextHandler{handler})
+}
+
+// contextHandler adds the request ID and the trace found in the context to every record,
+// so logs can be found from a trace and the other way around
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		r.AddAttrs(slog.String("trace_id", span.TraceID().String()), slog.String("span_id", span.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx that carries the ID of the request.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the ID of the request ctx belongs to, or "" outside of a request.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// NewRequestID returns a random ID like "4bf92f3577b34da6a3ce929d0e0e4736".
+func NewRequestID() string {
+	b := make([]byte, 16)
+	// crypto/rand never fails on the platforms we run on
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
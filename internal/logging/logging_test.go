@@ -0,0 +1,73 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/topboyasante/trunc8/internal/config"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestNew_AddsRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, config.LogConfig{Level: slog.LevelInfo, Format: "json"})
+
+	ctx := WithRequestID(context.Background(), "req-1")
+	logger.With("component", "test").InfoContext(ctx, "Hello", "code", "abc")
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("Expected a JSON line, got %q", buf.String())
+	}
+	if line["request_id"] != "req-1" || line["code"] != "abc" || line["component"] != "test" || line["msg"] != "Hello" {
+		t.Errorf("Unexpected line: %v", line)
+	}
+}
+
+func TestNew_AddsTrace(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, config.LogConfig{Level: slog.LevelInfo, Format: "json"})
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID}))
+	logger.InfoContext(ctx, "Hello")
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("Expected a JSON line, got %q", buf.String())
+	}
+	if line["trace_id"] != traceID.String() || line["span_id"] != spanID.String() {
+		t.Errorf("Expected the trace and span IDs, got %v", line)
+	}
+}
+
+func TestNew_Level(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, config.LogConfig{Level: slog.LevelWarn, Format: "text"})
+
+	logger.Info("Dropped")
+	logger.Warn("Kept")
+
+	if strings.Contains(buf.String(), "Dropped") || !strings.Contains(buf.String(), "msg=Kept") {
+		t.Errorf("Expected only the warning in text format, got %q", buf.String())
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	if id := RequestID(context.Background()); id != "" {
+		t.Errorf("Expected no request ID, got %q", id)
+	}
+
+	id := NewRequestID()
+	if len(id) != 32 || id == NewRequestID() {
+		t.Errorf("Expected a random 32 character ID, got %q", id)
+	}
+	if got := RequestID(WithRequestID(context.Background(), id)); got != id {
+		t.Errorf("Expected %q, got %q", id, got)
+	}
+}
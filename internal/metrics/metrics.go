@@ -0,0 +1,157 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/topboyasante/trunc8/internal/models"
+)
+
+const namespace = "trunc8"
+
+// Metrics holds everything exposed on /metrics. It has its own registry rather than the global one,
+// so tests can create as many as they like and only our metrics, the Go runtime and the process are exported.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	redirects       *prometheus.CounterVec
+	codeRetries     prometheus.Counter
+	storageDuration *prometheus.HistogramVec
+}
+
+// CacheStatsReporter is the redirect cache as seen by the metrics
+type CacheStatsReporter interface {
+	CacheStats() models.CacheStats
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests served, by route, method and status code.",
+		}, []string{"route", "method", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Time taken to serve HTTP requests, by route, method and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		redirects: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "redirects_total",
+			Help:      "Redirect lookups, by outcome: hit, miss, expired, disabled or error.",
+		}, []string{"outcome"}),
+		codeRetries: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "code_generation_retries_total",
+			Help:      "Generated codes that were already taken and had to be generated again.",
+		}),
+		storageDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "storage_operation_duration_seconds",
+			Help:      "Time taken by storage operations, by operation and outcome (ok or error).",
+			// Storage calls are much faster than whole requests, so the buckets start lower
+			Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"operation", "outcome"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestDuration,
+		m.redirects,
+		m.codeRetries,
+		m.storageDuration,
+	)
+
+	// Show the outcomes at 0 before the first redirect, so rate() and ratios work from the start
+	for _, outcome := range []string{models.RedirectHit, models.RedirectMiss, models.RedirectExpired, models.RedirectDisabled, models.RedirectError} {
+		m.redirects.WithLabelValues(outcome)
+	}
+
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// ObserveRequest records a request served on route, the pattern it matched, like "/api/links/{code}".
+// Both route and method have to come from a fixed set, since each value makes new series.
+func (m *Metrics) ObserveRequest(route, method string, status int, duration time.Duration) {
+	code := strconv.Itoa(status)
+	m.requests.WithLabelValues(route, method, code).Inc()
+	m.requestDuration.WithLabelValues(route, method, code).Observe(duration.Seconds())
+}
+
+// Redirect records the outcome of a redirect lookup.
+func (m *Metrics) Redirect(outcome string) {
+	m.redirects.WithLabelValues(outcome).Inc()
+}
+
+// CodeRetry records a generated code that collided with an existing one.
+func (m *Metrics) CodeRetry() {
+	m.codeRetries.Inc()
+}
+
+// ObserveStorage records a call to the storage. failed is only true when the storage itself failed:
+// a lookup that finds nothing is still a successful call.
+func (m *Metrics) ObserveStorage(operation string, duration time.Duration, failed bool) {
+	outcome := "ok"
+	if failed {
+		outcome = "error"
+	}
+	m.storageDuration.WithLabelValues(operation, outcome).Observe(duration.Seconds())
+}
+
+// RegisterCache exports the counters of the redirect cache, and the share of lookups it answered.
+// The values are read from cache whenever the metrics are scraped.
+func (m *Metrics) RegisterCache(cache CacheStatsReporter) {
+	m.registry.MustRegister(
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_hits_total",
+			Help:      "Redirect lookups answered by the cache with a link.",
+		}, func() float64 { return float64(cache.CacheStats().Hits) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_negative_hits_total",
+			Help:      "Redirect lookups answered by the cache with a cached \"not found\".",
+		}, func() float64 { return float64(cache.CacheStats().NegativeHits) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_misses_total",
+			Help:      "Redirect lookups that went to storage.",
+		}, func() float64 { return float64(cache.CacheStats().Misses) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "cache_entries",
+			Help:      "Links and \"not found\" codes held in the cache.",
+		}, func() float64 { return float64(cache.CacheStats().Entries) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "cache_hit_ratio",
+			Help:      "Share of redirect lookups answered by the cache since the server started.",
+		}, func() float64 { return hitRatio(cache.CacheStats()) }),
+	)
+}
+
+// hitRatio counts negative hits as hits: they spared the storage a lookup just the same.
+func hitRatio(stats models.CacheStats) float64 {
+	hits := stats.Hits + stats.NegativeHits
+	total := hits + stats.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(hits) / float64(total)
+}
@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/topboyasante/trunc8/internal/models"
+)
+
+type mockCache struct {
+	stats models.CacheStats
+}
+
+func (m *mockCache) CacheStats() models.CacheStats {
+	return m.stats
+}
+
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	return w.Body.String()
+}
+
+func TestMetrics(t *testing.T) {
+	m := New()
+	m.ObserveRequest("/{code}", http.MethodGet, http.StatusFound, 10*time.Millisecond)
+	m.Redirect(models.RedirectHit)
+	m.Redirect(models.RedirectExpired)
+	m.CodeRetry()
+	m.ObserveStorage("find_one", time.Millisecond, false)
+	m.RegisterCache(&mockCache{stats: models.CacheStats{Hits: 2, NegativeHits: 1, Misses: 1}})
+
+	body := scrape(t, m)
+
+	for _, line := range []string{
+		`trunc8_http_requests_total{method="GET",route="/{code}",status="302"} 1`,
+		`trunc8_http_request_duration_seconds_count{method="GET",route="/{code}",status="302"} 1`,
+		`trunc8_redirects_total{outcome="hit"} 1`,
+		`trunc8_redirects_total{outcome="expired"} 1`,
+		`trunc8_redirects_total{outcome="miss"} 0`,
+		`trunc8_code_generation_retries_total 1`,
+		`trunc8_storage_operation_duration_seconds_count{operation="find_one",outcome="ok"} 1`,
+		`trunc8_cache_hits_total 2`,
+		`trunc8_cache_hit_ratio 0.75`,
+	} {
+		if !strings.Contains(body, line) {
+			t.Errorf("Expected the metrics to contain %q", line)
+		}
+	}
+}
+
+func TestHitRatio_NoLookups(t *testing.T) {
+	if ratio := hitRatio(models.CacheStats{}); ratio != 0 {
+		t.Errorf("Expected 0 before any lookup, got %v", ratio)
+	}
+}
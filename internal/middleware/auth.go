@@ -0,0 +1,88 @@
+// Package middleware contains the http.Handler wrappers that run before our handlers.
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/topboyasante/trunc8/internal/auth"
+	"github.com/topboyasante/trunc8/internal/httpjson"
+	"github.com/topboyasante/trunc8/internal/models"
+)
+
+// Authenticator looks up the API key a request was made with
+type Authenticator interface {
+	Authenticate(ctx context.Context, key string) (*models.APIKey, error)
+}
+
+// Public is the scope of routes anyone can call without a key, like redirects
+const Public = ""
+
+// RequireAPIKey checks that requests carry an API key with the scope their route needs.
+// scopes maps a route to its scope, either for one method ("GET /api/links") or for all of them
+// ("/shorten"). The route is the pattern it was registered with on mux.
+// Routes missing from scopes need the admin scope, so forgetting one fails closed.
+func RequireAPIKey(mux *http.ServeMux, authenticator Authenticator, scopes map[string]string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			pattern := routePattern(mux, r)
+			if pattern == "" {
+				// Nothing matches, let the mux answer with its 404
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			scope, ok := lookupRoute(scopes, r, pattern)
+			if !ok {
+				scope = models.ScopeAdmin
+			}
+
+			if scope == Public {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key := requestKey(r)
+			if key == "" {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				httpjson.WriteError(w, http.StatusUnauthorized, "An API key is required")
+				return
+			}
+
+			apiKey, err := authenticator.Authenticate(r.Context(), key)
+			if errors.Is(err, models.ErrAPIKeyNotFound) {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				httpjson.WriteError(w, http.StatusUnauthorized, "Invalid API key")
+				return
+			}
+			if err != nil {
+				slog.ErrorContext(r.Context(), "Unable to authenticate request", "error", err)
+				httpjson.WriteError(w, http.StatusInternalServerError, "Unable to authenticate request")
+				return
+			}
+
+			if !apiKey.HasScope(scope) {
+				httpjson.WriteError(w, http.StatusForbidden, "This API key is missing the "+scope+" scope")
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(auth.WithKey(r.Context(), apiKey)))
+		})
+	}
+}
+
+// requestKey reads the key from "Authorization: Bearer <key>", or from X-API-Key
+// for clients that can't set the Authorization header.
+func requestKey(r *http.Request) string {
+	if header := r.Header.Get("Authorization"); header != "" {
+		scheme, key, found := strings.Cut(header, " ")
+		if found && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(key)
+		}
+		return ""
+	}
+	return strings.TrimSpace(r.Header.Get("X-API-Key"))
+}
@@ -0,0 +1,165 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/topboyasante/trunc8/internal/auth"
+	"github.com/topboyasante/trunc8/internal/models"
+	"github.com/topboyasante/trunc8/internal/types"
+)
+
+// Authenticator that knows a fixed set of keys
+type mockAuthenticator struct {
+	keys map[string]*models.APIKey
+	err  error
+}
+
+func (m *mockAuthenticator) Authenticate(ctx context.Context, key string) (*models.APIKey, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	if apiKey, ok := m.keys[key]; ok {
+		return apiKey, nil
+	}
+	return nil, models.ErrAPIKeyNotFound
+}
+
+func newTestHandler(authenticator Authenticator) http.Handler {
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		// Handlers behind the middleware can see who is calling
+		if key, found := auth.KeyFromContext(r.Context()); found {
+			w.Header().Set("X-Key-Name", key.Name)
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/shorten", ok)
+	mux.HandleFunc("/{code}", ok)
+	mux.HandleFunc("/api/links/{code}", ok)
+	mux.HandleFunc("/api/keys", ok)
+
+	return RequireAPIKey(mux, authenticator, map[string]string{
+		"/{code}":                 Public,
+		"/shorten":                models.ScopeLinksWrite,
+		"GET /api/links/{code}":   models.ScopeLinksRead,
+		"PATCH /api/links/{code}": models.ScopeLinksWrite,
+	})(mux)
+}
+
+func TestRequireAPIKey(t *testing.T) {
+	authenticator := &mockAuthenticator{keys: map[string]*models.APIKey{
+		"reader": {Name: "reader", Scopes: []string{models.ScopeLinksRead}},
+		"writer": {Name: "writer", Scopes: []string{models.ScopeLinksWrite}},
+		"admin":  {Name: "admin", Scopes: []string{models.ScopeAdmin}},
+	}}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		key    string
+		status int
+	}{
+		{"redirects are public", http.MethodGet, "/TEST", "", http.StatusOK},
+		{"missing key", http.MethodPost, "/shorten", "", http.StatusUnauthorized},
+		{"unknown key", http.MethodPost, "/shorten", "nope", http.StatusUnauthorized},
+		{"missing scope", http.MethodPost, "/shorten", "reader", http.StatusForbidden},
+		{"matching scope", http.MethodPost, "/shorten", "writer", http.StatusOK},
+		{"scope per method", http.MethodGet, "/api/links/TEST", "reader", http.StatusOK},
+		{"scope per method, other method", http.MethodPatch, "/api/links/TEST", "reader", http.StatusForbidden},
+		{"admin has every scope", http.MethodPatch, "/api/links/TEST", "admin", http.StatusOK},
+		{"routes without a rule need admin", http.MethodGet, "/api/keys", "writer", http.StatusForbidden},
+		{"routes without a rule, admin", http.MethodGet, "/api/keys", "admin", http.StatusOK},
+	}
+
+	handler := newTestHandler(authenticator)
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		if tt.key != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.key)
+		}
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, req)
+
+		if w.Code != tt.status {
+			t.Errorf("%s: expected status code %d, got %d", tt.name, tt.status, w.Code)
+		}
+
+		if tt.status == http.StatusOK && tt.key != "" && w.Header().Get("X-Key-Name") != tt.key {
+			t.Errorf("%s: expected the key to be stored in the request context", tt.name)
+		}
+	}
+}
+
+func TestRequireAPIKey_Unauthorized(t *testing.T) {
+	handler := newTestHandler(&mockAuthenticator{})
+
+	req := httptest.NewRequest(http.MethodPost, "/shorten", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if w.Header().Get("WWW-Authenticate") == "" {
+		t.Error("Expected a WWW-Authenticate header")
+	}
+
+	var response types.ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal error response: %v", err)
+	}
+
+	if response.Error != "unauthorized" {
+		t.Errorf("Expected error 'unauthorized', got '%s'", response.Error)
+	}
+}
+
+func TestRequireAPIKey_XAPIKeyHeader(t *testing.T) {
+	handler := newTestHandler(&mockAuthenticator{keys: map[string]*models.APIKey{
+		"writer": {Name: "writer", Scopes: []string{models.ScopeLinksWrite}},
+	}})
+
+	req := httptest.NewRequest(http.MethodPost, "/shorten", nil)
+	req.Header.Set("X-API-Key", "writer")
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestRequireAPIKey_AuthenticatorError(t *testing.T) {
+	handler := newTestHandler(&mockAuthenticator{err: errors.New("connection refused")})
+
+	req := httptest.NewRequest(http.MethodPost, "/shorten", nil)
+	req.Header.Set("Authorization", "Bearer writer")
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status code %d, got %d", http.StatusInternalServerError, w.Code)
+	}
+}
+
+func TestRequireAPIKey_UnknownRoute(t *testing.T) {
+	handler := newTestHandler(&mockAuthenticator{})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/unknown/route", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status code %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
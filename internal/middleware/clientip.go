@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"net/http"
+	"net/netip"
+
+	"github.com/topboyasante/trunc8/internal/utils"
+)
+
+// ClientIP finds the IP address of the client behind the proxies in trusted and puts it in
+// the request context, where utils.ClientIP reads it. Without trusted proxies it is the address
+// the request came from. It must run before RequestLog and RateLimit, which read it.
+func ClientIP(trusted []netip.Prefix) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := utils.WithClientIP(r.Context(), utils.ForwardedClientIP(r, trusted))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
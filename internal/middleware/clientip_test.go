@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/topboyasante/trunc8/internal/utils"
+)
+
+func TestClientIP(t *testing.T) {
+	var got string
+	handler := ClientIP([]netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = utils.ClientIP(r)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/abcd", nil)
+	req.RemoteAddr = "10.0.0.2:51234"
+	req.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.1")
+
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if got != "203.0.113.7" {
+		t.Errorf("Expected the client behind the proxies, got '%s'", got)
+	}
+}
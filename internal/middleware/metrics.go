@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+)
+
+// RequestObserver is told about every request once it has been served
+type RequestObserver interface {
+	ObserveRequest(route, method string, status int, duration time.Duration)
+}
+
+// unmatchedRoute stands for every request no route of the mux matches. Using the path instead
+// would let anyone create a new series per URL they try.
+const unmatchedRoute = "unmatched"
+
+// Metrics times every request and reports it under the route of mux it matched and its method.
+// It must run before RequireAPIKey and RateLimit, so the requests they reject are counted too.
+func Metrics(mux *http.ServeMux, observer RequestObserver) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			route := routeLabel(mux, r)
+			if route == "" {
+				route = unmatchedRoute
+			}
+
+			sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(sw, r)
+
+			observer.ObserveRequest(route, methodLabel(r), sw.status, time.Since(start))
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type observedRequest struct {
+	route, method string
+	status        int
+}
+
+type mockRequestObserver struct {
+	requests []observedRequest
+}
+
+func (m *mockRequestObserver) ObserveRequest(route, method string, status int, duration time.Duration) {
+	m.requests = append(m.requests, observedRequest{route, method, status})
+}
+
+func TestMetrics(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/{code}", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://example.com", http.StatusFound)
+	})
+	mux.HandleFunc("/api/links", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[]"))
+	})
+	mux.HandleFunc("POST /api/links/bulk", func(w http.ResponseWriter, r *http.Request) {})
+
+	observer := &mockRequestObserver{}
+	handler := Chain(mux, Metrics(mux, observer))
+
+	for _, path := range []string{"/abc", "/api/links", "/api/links/abc/nope"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("FOOBAR", "/api/links", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/links/bulk", nil))
+
+	expected := []observedRequest{
+		{"/{code}", http.MethodGet, http.StatusFound},
+		{"/api/links", http.MethodGet, http.StatusOK},
+		// Paths that match no route share one series
+		{"unmatched", http.MethodGet, http.StatusNotFound},
+		// So do methods we don't know
+		{"/api/links", "other", http.StatusOK},
+		// Routes for a single method are named like in traces, without the method
+		{"/api/links/bulk", http.MethodPost, http.StatusOK},
+	}
+	if len(observer.requests) != len(expected) {
+		t.Fatalf("Expected %d requests, got %d", len(expected), len(observer.requests))
+	}
+	for i, want := range expected {
+		if observer.requests[i] != want {
+			t.Errorf("Expected %+v, got %+v", want, observer.requests[i])
+		}
+	}
+}
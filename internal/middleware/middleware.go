@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Middleware wraps a handler with extra behaviour
+type Middleware func(http.Handler) http.Handler
+
+// Chain wraps h with the middlewares. The first one runs first:
+// Chain(h, a, b) is the same as a(b(h)).
+func Chain(h http.Handler, middlewares ...Middleware) http.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+	return h
+}
+
+// routePattern returns the pattern mux will serve the request with, e.g. "/api/links/{code}".
+// It's empty when no route matches.
+func routePattern(mux *http.ServeMux, r *http.Request) string {
+	_, pattern := mux.Handler(r)
+	return pattern
+}
+
+// routeLabel returns the route of the request as metrics and traces show it: the pattern without
+// the method that patterns registered for a single method, like "POST /api/links/bulk", start with.
+// The method is reported on its own. It's empty when no route matches.
+func routeLabel(mux *http.ServeMux, r *http.Request) string {
+	route := routePattern(mux, r)
+	if _, path, ok := strings.Cut(route, " "); ok {
+		return path
+	}
+	return route
+}
+
+// lookupRoute finds the entry for a route in a table keyed by "METHOD pattern"
+// or by the pattern alone, which applies to every method. The method specific entry wins.
+func lookupRoute[T any](table map[string]T, r *http.Request, pattern string) (T, bool) {
+	if v, ok := table[r.Method+" "+pattern]; ok {
+		return v, true
+	}
+	v, ok := table[pattern]
+	return v, ok
+}
+
+// knownMethods are the methods our routes answer to
+var knownMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodOptions: true,
+}
+
+// otherMethod stands for every method that isn't in knownMethods. Clients can send any token
+// as the method, so using it as is would let them create a new series or span name per request.
+const otherMethod = "other"
+
+// methodLabel returns the method of r to put in metric labels and span names.
+func methodLabel(r *http.Request) string {
+	if knownMethods[r.Method] {
+		return r.Method
+	}
+	return otherMethod
+}
+
+// statusWriter remembers the status code and size of the response it writes
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/topboyasante/trunc8/internal/auth"
+	"github.com/topboyasante/trunc8/internal/httpjson"
+	"github.com/topboyasante/trunc8/internal/ratelimit"
+	"github.com/topboyasante/trunc8/internal/utils"
+)
+
+// RateLimit rejects requests with 429 Too Many Requests once their client used up its limit.
+// limiters maps a route of mux to its limiter, like the scopes of RequireAPIKey;
+// routes without a limiter are not limited. The routes in perItem aren't charged here: their
+// handler calls ratelimit.Charge with the number of items once it has read the request.
+// Clients are told apart by their API key, or by their IP address on public routes.
+// It has to run after RequireAPIKey, which puts the key in the request context.
+func RateLimit(mux *http.ServeMux, limiters map[string]*ratelimit.Limiter, perItem map[string]bool) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			pattern := routePattern(mux, r)
+			limiter, ok := lookupRoute(limiters, r, pattern)
+			if !ok || limiter == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key := clientKey(r)
+			if charged, _ := lookupRoute(perItem, r, pattern); charged {
+				ctx := ratelimit.WithCharge(r.Context(), func(n int) ratelimit.Result {
+					result := limiter.AllowN(key, n)
+					setRateLimitHeaders(w, result)
+					return result
+				})
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
+			result := limiter.Allow(key)
+			setRateLimitHeaders(w, result)
+			if !result.Allowed {
+				httpjson.WriteError(w, http.StatusTooManyRequests, "Rate limit exceeded, retry in "+seconds(result.RetryAfter)+" seconds")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// setRateLimitHeaders tells the client where it stands, and when it was rejected how long to wait
+func setRateLimitHeaders(w http.ResponseWriter, result ratelimit.Result) {
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	w.Header().Set("RateLimit-Reset", seconds(result.Reset))
+	if !result.Allowed {
+		w.Header().Set("Retry-After", seconds(result.RetryAfter))
+	}
+}
+
+// clientKey is the bucket a request counts against.
+// Keys get their own bucket wherever they are used from, so clients behind the same
+// NAT don't share a limit; anonymous requests are counted per IP.
+func clientKey(r *http.Request) string {
+	if key, ok := auth.KeyFromContext(r.Context()); ok {
+		return "key:" + key.ID
+	}
+	return "ip:" + utils.ClientIP(r)
+}
+
+// seconds rounds up, so a client that waits as long as it's told is never rejected again
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
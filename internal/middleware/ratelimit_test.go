@@ -0,0 +1,166 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/topboyasante/trunc8/internal/auth"
+	"github.com/topboyasante/trunc8/internal/models"
+	"github.com/topboyasante/trunc8/internal/ratelimit"
+)
+
+func newRateLimitedHandler(limiters map[string]*ratelimit.Limiter) http.Handler {
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/shorten", ok)
+	mux.HandleFunc("/{code}", ok)
+	mux.HandleFunc("/api/links", ok)
+	mux.HandleFunc("POST /api/links/bulk", func(w http.ResponseWriter, r *http.Request) {
+		if result, ok := ratelimit.Charge(r.Context(), 3); ok && !result.Allowed {
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	})
+
+	return RateLimit(mux, limiters, map[string]bool{"POST /api/links/bulk": true})(mux)
+}
+
+func TestRateLimit(t *testing.T) {
+	handler := newRateLimitedHandler(map[string]*ratelimit.Limiter{
+		"/shorten": ratelimit.New(60, 2),
+		"/{code}":  ratelimit.New(60, 5),
+	})
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/shorten", nil)
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected request %d to be allowed, got %d", i+1, w.Code)
+		}
+
+		if w.Header().Get("RateLimit-Limit") != "2" {
+			t.Errorf("Expected RateLimit-Limit 2, got '%s'", w.Header().Get("RateLimit-Limit"))
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/shorten", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status code %d, got %d", http.StatusTooManyRequests, w.Code)
+	}
+
+	if w.Header().Get("Retry-After") != "1" {
+		t.Errorf("Expected Retry-After 1, got '%s'", w.Header().Get("Retry-After"))
+	}
+
+	if w.Header().Get("RateLimit-Remaining") != "0" {
+		t.Errorf("Expected RateLimit-Remaining 0, got '%s'", w.Header().Get("RateLimit-Remaining"))
+	}
+
+	// Redirects have a limit of their own
+	req = httptest.NewRequest(http.MethodGet, "/TEST", nil)
+	w = httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected the redirect limit to be separate, got %d", w.Code)
+	}
+
+	// Routes without a limiter are never limited
+	for i := 0; i < 10; i++ {
+		req = httptest.NewRequest(http.MethodGet, "/api/links", nil)
+		w = httptest.NewRecorder()
+
+		handler.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK || w.Header().Get("RateLimit-Limit") != "" {
+			t.Fatalf("Expected /api/links not to be limited, got %d", w.Code)
+		}
+	}
+}
+
+func TestRateLimit_PerAPIKey(t *testing.T) {
+	handler := newRateLimitedHandler(map[string]*ratelimit.Limiter{
+		"/shorten": ratelimit.New(60, 1),
+	})
+
+	send := func(keyID, remoteAddr string) int {
+		req := httptest.NewRequest(http.MethodPost, "/shorten", nil)
+		req.RemoteAddr = remoteAddr
+		if keyID != "" {
+			req = req.WithContext(auth.WithKey(req.Context(), &models.APIKey{ID: keyID}))
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if send("key-1", "10.0.0.1:1234") != http.StatusOK {
+		t.Fatal("Expected the first request of key-1 to be allowed")
+	}
+
+	// Same IP, different key
+	if send("key-2", "10.0.0.1:1234") != http.StatusOK {
+		t.Error("Expected key-2 to have its own limit")
+	}
+
+	// Same key, different IP
+	if send("key-1", "10.0.0.2:1234") != http.StatusTooManyRequests {
+		t.Error("Expected key-1 to be limited wherever it's used from")
+	}
+
+	if send("", "10.0.0.1:1234") != http.StatusOK {
+		t.Error("Expected anonymous requests to be limited per IP")
+	}
+
+	if send("", "10.0.0.1:5678") != http.StatusTooManyRequests {
+		t.Error("Expected the port not to matter for the IP limit")
+	}
+}
+
+func TestRateLimit_PerItem(t *testing.T) {
+	shorten := ratelimit.New(60, 5)
+	handler := newRateLimitedHandler(map[string]*ratelimit.Limiter{
+		"/shorten":             shorten,
+		"POST /api/links/bulk": shorten,
+	})
+
+	// The handler takes 3 tokens, the middleware none
+	req := httptest.NewRequest(http.MethodPost, "/api/links/bulk", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected the bulk request to be allowed, got %d", w.Code)
+	}
+	if w.Header().Get("RateLimit-Remaining") != "2" {
+		t.Errorf("Expected RateLimit-Remaining 2, got '%s'", w.Header().Get("RateLimit-Remaining"))
+	}
+
+	// The 2 tokens left don't cover another 3
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/links/bulk", nil))
+
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "1" {
+		t.Errorf("Expected the second bulk request to wait 1 second, got %d and Retry-After '%s'", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	// The links of bulk requests come out of the /shorten budget
+	for i := 0; i < 2; i++ {
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/shorten", nil))
+	}
+	if w.Code != http.StatusOK || w.Header().Get("RateLimit-Remaining") != "0" {
+		t.Errorf("Expected the last token to be taken, got %d", w.Code)
+	}
+}
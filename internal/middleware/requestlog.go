@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/topboyasante/trunc8/internal/logging"
+	"github.com/topboyasante/trunc8/internal/utils"
+)
+
+// RequestIDHeader carries the ID of a request, both ways
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDPattern is what we accept as an ID from the client or a proxy in front of us.
+// Anything else is replaced, so clients can't inject arbitrary text into our logs.
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestLog gives every request an ID and logs one line once it has been served.
+// The ID is taken from the X-Request-ID header when there is a valid one, so a request can be
+// followed through the proxies in front of us; otherwise a new one is made. It is sent back in
+// the response, and put in the request context so everything logged while serving it carries it.
+// It must run after ClientIP, whose address it logs, and before RequireAPIKey and RateLimit,
+// so the requests they reject are logged too.
+func RequestLog(mux *http.ServeMux) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			id := r.Header.Get(RequestIDHeader)
+			if !requestIDPattern.MatchString(id) {
+				id = logging.NewRequestID()
+			}
+			w.Header().Set(RequestIDHeader, id)
+			ctx := logging.WithRequestID(r.Context(), id)
+
+			sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(sw, r.WithContext(ctx))
+
+			level := slog.LevelInfo
+			if sw.status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+
+			slog.LogAttrs(ctx, level, "Request served",
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.String("route", routePattern(mux, r)),
+				slog.Int("status", sw.status),
+				slog.Int("bytes", sw.bytes),
+				slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+				slog.String("client_ip", utils.ClientIP(r)),
+				slog.String("user_agent", r.UserAgent()),
+			)
+		})
+	}
+}
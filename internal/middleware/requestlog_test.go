@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/topboyasante/trunc8/internal/config"
+	"github.com/topboyasante/trunc8/internal/logging"
+)
+
+// captureLogs sends what is logged through slog to the returned buffer until the test ends
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(logging.New(&buf, config.LogConfig{Level: slog.LevelInfo, Format: "json"}))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	return &buf
+}
+
+func newRequestLogHandler(seen *string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/{code}", func(w http.ResponseWriter, r *http.Request) {
+		*seen = logging.RequestID(r.Context())
+		slog.InfoContext(r.Context(), "Looking up link")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("nope"))
+	})
+	return Chain(mux, RequestLog(mux))
+}
+
+func TestRequestLog(t *testing.T) {
+	logs := captureLogs(t)
+	var seen string
+	handler := newRequestLogHandler(&seen)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/abc", nil))
+
+	id := w.Header().Get(RequestIDHeader)
+	if id == "" || id != seen {
+		t.Fatalf("Expected the ID sent back %q to be the one in the context %q", id, seen)
+	}
+
+	lines := bytes.Split(bytes.TrimSpace(logs.Bytes()), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %d: %s", len(lines), logs.String())
+	}
+
+	var handlerLine, accessLine map[string]any
+	json.Unmarshal(lines[0], &handlerLine)
+	json.Unmarshal(lines[1], &accessLine)
+
+	// Both lines can be found by the request ID
+	if handlerLine["request_id"] != id || accessLine["request_id"] != id {
+		t.Errorf("Expected request_id %q on every line, got %s", id, logs.String())
+	}
+	if accessLine["route"] != "/{code}" || accessLine["status"] != float64(http.StatusNotFound) || accessLine["bytes"] != float64(4) {
+		t.Errorf("Unexpected access log line: %v", accessLine)
+	}
+}
+
+func TestRequestLog_PropagatesRequestID(t *testing.T) {
+	captureLogs(t)
+	var seen string
+	handler := newRequestLogHandler(&seen)
+
+	tests := []struct {
+		header string
+		kept   bool
+	}{
+		{"7d0c5a4e-2f41-4c6b-9a53-0b8f7c1e2d3a", true},
+		{"edge-proxy:1234", true},
+		{"line one\nline two", false},
+		{string(bytes.Repeat([]byte("a"), 129)), false},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/abc", nil)
+		req.Header.Set(RequestIDHeader, tt.header)
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, req)
+
+		id := w.Header().Get(RequestIDHeader)
+		if kept := id == tt.header; kept != tt.kept {
+			t.Errorf("%q: expected kept to be %v, got ID %q", tt.header, tt.kept, id)
+		}
+		if seen != id {
+			t.Errorf("Expected the context to carry %q, got %q", id, seen)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/topboyasante/trunc8/internal/middleware"
+
+// Tracing starts a span for every request, named after the route of mux it matched, like "GET /{code}".
+// When the request carries a W3C traceparent header the span joins that trace, so a redirect
+// shows up under whatever sent the visitor to us. It must run before every other middleware,
+// so they all see the span in the request context and the access log carries its trace ID.
+func Tracing(mux *http.ServeMux) Middleware {
+	tracer := otel.Tracer(tracerName)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+			route := routeLabel(mux, r)
+			method := methodLabel(r)
+			name := method + " " + route
+			if route == "" {
+				// The path would make a span name per URL anyone tries
+				name = method
+			}
+
+			ctx, span := tracer.Start(ctx, name,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPRequestMethodKey.String(method),
+					semconv.URLPath(r.URL.Path),
+					semconv.HTTPRoute(route),
+					semconv.UserAgentOriginal(r.UserAgent()),
+				),
+			)
+			defer span.End()
+
+			sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(sw, r.WithContext(ctx))
+
+			span.SetAttributes(semconv.HTTPResponseStatusCode(sw.status))
+			// Client errors are the client's problem, only our own failures mark the span
+			if sw.status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(sw.status))
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+)
+
+// recordSpans installs a tracer provider that keeps the spans in memory until the test ends
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	recorder := tracetest.NewSpanRecorder()
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	return recorder
+}
+
+func TestTracing(t *testing.T) {
+	spans := recordSpans(t)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/{code}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	handler := Chain(mux, Tracing(mux))
+
+	req := httptest.NewRequest(http.MethodGet, "/abc", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	ended := spans.Ended()
+	if len(ended) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(ended))
+	}
+	span := ended[0]
+
+	if span.Name() != "GET /{code}" {
+		t.Errorf("Expected span 'GET /{code}', got '%s'", span.Name())
+	}
+	// The span joins the trace of the caller
+	if span.SpanContext().TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" || span.Parent().SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("Expected the span to continue the incoming trace, got trace %s and parent %s", span.SpanContext().TraceID(), span.Parent().SpanID())
+	}
+	if span.Status().Code != codes.Error {
+		t.Errorf("Expected a 500 to mark the span as failed, got %v", span.Status())
+	}
+
+	found := false
+	for _, attr := range span.Attributes() {
+		if attr == semconv.HTTPResponseStatusCode(http.StatusInternalServerError) {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected the status code in the attributes, got %v", span.Attributes())
+	}
+}
+
+func TestTracing_NewTrace(t *testing.T) {
+	spans := recordSpans(t)
+
+	mux := http.NewServeMux()
+	handler := Chain(mux, Tracing(mux))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	ended := spans.Ended()
+	if len(ended) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(ended))
+	}
+	if ended[0].Parent().IsValid() {
+		t.Error("Expected a new trace without a traceparent header")
+	}
+	// No route matched, the path stays out of the name
+	if ended[0].Name() != "GET" {
+		t.Errorf("Expected span 'GET', got '%s'", ended[0].Name())
+	}
+	if ended[0].Status().Code == codes.Error {
+		t.Error("Expected a 404 not to mark the span as failed")
+	}
+}
+
+func TestTracing_MethodPattern(t *testing.T) {
+	spans := recordSpans(t)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/links/bulk", func(w http.ResponseWriter, r *http.Request) {})
+	handler := Chain(mux, Tracing(mux))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/links/bulk", nil))
+
+	ended := spans.Ended()
+	if len(ended) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(ended))
+	}
+	if ended[0].Name() != "POST /api/links/bulk" {
+		t.Errorf("Expected span 'POST /api/links/bulk', got '%s'", ended[0].Name())
+	}
+}
+
+func TestTracing_UnknownMethod(t *testing.T) {
+	spans := recordSpans(t)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/{code}", func(w http.ResponseWriter, r *http.Request) {})
+	handler := Chain(mux, Tracing(mux))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("FOOBAR", "/abc", nil))
+
+	ended := spans.Ended()
+	if len(ended) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(ended))
+	}
+	if ended[0].Name() != "other /{code}" {
+		t.Errorf("Expected span 'other /{code}', got '%s'", ended[0].Name())
+	}
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// The scopes an API key can be given
+const (
+	ScopeLinksWrite = "links:write" // create, update, disable and delete links
+	ScopeLinksRead  = "links:read"  // look up and list links
+	ScopeStatsRead  = "stats:read"  // read click analytics
+	ScopeAdmin      = "admin"       // everything, including managing API keys
+)
+
+// Scopes are all the scopes we know about
+var Scopes = map[string]bool{
+	ScopeLinksWrite: true,
+	ScopeLinksRead:  true,
+	ScopeStatsRead:  true,
+	ScopeAdmin:      true,
+}
+
+// APIKey gives its bearer access to the API.
+// We only store a hash of the key: the key itself is shown once, when it's created.
+type APIKey struct {
+	ID        string    `bson:"_id,omitempty" json:"id"`
+	Name      string    `bson:"name" json:"name"`
+	Prefix    string    `bson:"prefix" json:"prefix"` // the start of the key, to recognize it in a list
+	KeyHash   string    `bson:"key_hash" json:"-"`
+	Scopes    []string  `bson:"scopes" json:"scopes"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+
+	// The user the key was issued to. Admin keys have neither and can see every workspace.
+	UserID      string `bson:"user_id,omitempty" json:"user_id,omitempty"`
+	WorkspaceID string `bson:"workspace_id,omitempty" json:"workspace_id,omitempty"`
+}
+
+// HasScope reports whether the key grants scope. Admin keys are granted every scope.
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
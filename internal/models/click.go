@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// Click is a single visit to a short link.
+// We store the hash of the client IP instead of the IP itself,
+// so we can count unique visitors without keeping personal data around.
+type Click struct {
+	ID        string    `bson:"_id,omitempty"`
+	Code      string    `bson:"code"`
+	Timestamp time.Time `bson:"timestamp"`
+	Referrer  string    `bson:"referrer,omitempty"`
+	UserAgent string    `bson:"user_agent,omitempty"`
+	Browser   string    `bson:"browser,omitempty"` // user agent family, e.g. "Chrome"
+	IPHash    string    `bson:"ip_hash,omitempty"`
+}
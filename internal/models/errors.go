@@ -0,0 +1,47 @@
+package models
+
+import "errors"
+
+// Errors shared between the repository, service and handler layers.
+// Callers should compare against them with errors.Is, since they are usually wrapped with more detail.
+var (
+	ErrNotFound          = errors.New("link not found")
+	ErrExpired           = errors.New("link has expired")
+	ErrDisabled          = errors.New("link is disabled")
+	ErrInvalidURL        = errors.New("invalid URL")
+	ErrDuplicateCode     = errors.New("short code already exists")
+	ErrInvalidAlias      = errors.New("invalid alias")
+	ErrAliasTaken        = errors.New("alias is already taken")
+	ErrInvalidExpiration = errors.New("invalid expiration")
+	ErrInvalidRedirect   = errors.New("invalid redirect type")
+	ErrInvalidStatsQuery = errors.New("invalid stats query")
+	ErrInvalidTags       = errors.New("invalid tags")
+	ErrInvalidListQuery  = errors.New("invalid list query")
+	ErrInvalidAPIKey     = errors.New("invalid API key")
+	ErrAPIKeyNotFound    = errors.New("API key not found")
+	ErrInvalidWorkspace  = errors.New("invalid workspace")
+	ErrWorkspaceNotFound = errors.New("workspace not found")
+	ErrInvalidUser       = errors.New("invalid user")
+	ErrUserNotFound      = errors.New("user not found")
+	ErrEmailTaken        = errors.New("email is already taken")
+	ErrInvalidBulk       = errors.New("invalid bulk request")
+)
+
+// domainErrors are the errors above. Add new ones here too.
+var domainErrors = []error{
+	ErrNotFound, ErrExpired, ErrDisabled, ErrInvalidURL, ErrDuplicateCode, ErrInvalidAlias, ErrAliasTaken,
+	ErrInvalidExpiration, ErrInvalidRedirect, ErrInvalidStatsQuery, ErrInvalidTags, ErrInvalidListQuery,
+	ErrInvalidAPIKey, ErrAPIKeyNotFound, ErrInvalidWorkspace, ErrWorkspaceNotFound, ErrInvalidUser,
+	ErrUserNotFound, ErrEmailTaken, ErrInvalidBulk,
+}
+
+// IsDomainError reports whether err is one of the errors above: an answer, like a link that
+// doesn't exist, rather than a failure, like a database that can't be reached.
+func IsDomainError(err error) bool {
+	for _, e := range domainErrors {
+		if errors.Is(err, e) {
+			return true
+		}
+	}
+	return false
+}
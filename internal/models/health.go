@@ -0,0 +1,16 @@
+package models
+
+// CacheStats counts the lookups made through the redirect cache since the server started.
+type CacheStats struct {
+	Hits         uint64 `json:"hits"`
+	NegativeHits uint64 `json:"negative_hits"` // lookups answered with a cached "not found"
+	Misses       uint64 `json:"misses"`        // lookups that went to storage
+	Entries      int    `json:"entries"`
+}
+
+// MigrationState lists the schema migrations of the storage.
+// Backends without a schema have neither applied nor pending migrations.
+type MigrationState struct {
+	Applied []string `json:"applied"`
+	Pending []string `json:"pending"`
+}
@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// The states a link can be listed by
+const (
+	StatusActive   = "active"
+	StatusDisabled = "disabled"
+	StatusExpired  = "expired" // past expires_at or out of clicks, but not disabled
+)
+
+// The fields links can be sorted by
+const (
+	SortCreatedAt = "created_at"
+	SortClicks    = "clicks"
+)
+
+// ListQuery describes which links to list and in what order.
+// Empty filters match every link.
+type ListQuery struct {
+	WorkspaceID string // set by the service, empty means every workspace
+
+	OwnerID string
+	Tag     string
+	Domain  string
+	Status  string // active, disabled or expired
+	Search  string // substring of the destination URL
+
+	Sort       string // created_at or clicks
+	Descending bool
+	Limit      int
+
+	Cursor string      // opaque cursor from the previous page, as sent by the client
+	After  *ListCursor // the decoded cursor, nil for the first page
+
+	Now time.Time // the time used to tell active links from expired ones
+}
+
+// ListCursor points at the last link of a page.
+// The next page starts right after it in the sort order; the ID breaks ties
+// between links with the same sort value.
+type ListCursor struct {
+	Sort      string    `json:"sort"`
+	CreatedAt time.Time `json:"created_at,omitempty"`
+	Clicks    int       `json:"clicks,omitempty"`
+	ID        string    `json:"id"`
+}
+
+// LinkPage is one page of links.
+// NextCursor is empty on the last page.
+type LinkPage struct {
+	Links      []*URL `json:"links"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
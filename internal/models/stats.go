@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// StatsQuery describes which clicks of a link we want to aggregate.
+type StatsQuery struct {
+	WorkspaceID string // set by the service, empty means every workspace
+	Code        string
+	From        time.Time
+	To          time.Time
+	Interval    string // "hour" or "day"
+	Limit       int    // how many entries to return in the top lists
+}
+
+// LinkStats is the result of aggregating the clicks of a link.
+type LinkStats struct {
+	Code           string        `json:"code"`
+	From           time.Time     `json:"from"`
+	To             time.Time     `json:"to"`
+	Interval       string        `json:"interval"`
+	TotalClicks    int64         `json:"total_clicks"`
+	UniqueVisitors int64         `json:"unique_visitors"`
+	Series         []StatsBucket `json:"series"`
+	TopReferrers   []StatsCount  `json:"top_referrers"`
+	TopUserAgents  []StatsCount  `json:"top_user_agents"`
+}
+
+// StatsBucket is the number of clicks in one hour or day of the series.
+type StatsBucket struct {
+	Time   time.Time `bson:"_id" json:"time"`
+	Clicks int64     `bson:"clicks" json:"clicks"`
+}
+
+// StatsCount is the number of clicks for one referrer or user agent family.
+type StatsCount struct {
+	Value  string `bson:"_id" json:"value"`
+	Clicks int64  `bson:"clicks" json:"clicks"`
+}
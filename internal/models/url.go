@@ -1,11 +1,81 @@
 package models
 
+import (
+	"net/http"
+	"time"
+)
+
 // struct field names should be exported (start with uppercase)
 // so they can be accessed outside the package
 
+// URL is a short link. Links are known to API clients by their code, so the ID stays internal.
 type URL struct {
-	ID          string `bson:"_id,omitempty"`
-	OriginalURL string `bson:"original_url"`
-	Code        string `bson:"code"`
-	ClickCount  int    `bson:"click_count"`
+	ID          string     `bson:"_id,omitempty" json:"-"`
+	OriginalURL string     `bson:"original_url" json:"original_url"`
+	Code        string     `bson:"code" json:"code"`
+	ClickCount  int        `bson:"click_count" json:"click_count"`
+	ExpiresAt   *time.Time `bson:"expires_at,omitempty" json:"expires_at,omitempty"` // nil means the link never expires
+	MaxClicks   int        `bson:"max_clicks,omitempty" json:"max_clicks,omitempty"` // 0 means there is no limit
+	Disabled    bool       `bson:"disabled,omitempty" json:"disabled"`
+
+	// RedirectType is the HTTP status used to redirect: 301, 302, 307 or 308.
+	// 0 means the server wide default from the config.
+	RedirectType int `bson:"redirect_type,omitempty" json:"redirect_type,omitempty"`
+
+	// Who created the link. Links made with a key that has no workspace have neither.
+	// The workspace is shown like on API keys, so admins listing every workspace can tell links apart.
+	OwnerID     string `bson:"owner_id,omitempty" json:"-"` // ID of the user
+	WorkspaceID string `bson:"workspace_id,omitempty" json:"workspace_id,omitempty"`
+
+	// Used to list and filter links
+	Tags      []string  `bson:"tags,omitempty" json:"tags,omitempty"`
+	Domain    string    `bson:"domain" json:"domain"` // host of OriginalURL
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+}
+
+// RedirectTypes are the statuses a link can redirect with
+var RedirectTypes = map[int]bool{
+	http.StatusMovedPermanently:  true, // 301
+	http.StatusFound:             true, // 302
+	http.StatusTemporaryRedirect: true, // 307
+	http.StatusPermanentRedirect: true, // 308
+}
+
+// The outcomes of a redirect lookup, as reported in the metrics
+const (
+	RedirectHit      = "hit"      // the visitor was redirected
+	RedirectMiss     = "miss"     // no link has the code
+	RedirectExpired  = "expired"  // the link is past its expiration date or out of clicks
+	RedirectDisabled = "disabled" // the link was disabled by its owner
+	RedirectError    = "error"    // the storage failed
+)
+
+// AcceptsMethod reports whether the link can be followed with the given HTTP method.
+// 307 and 308 tell the client to repeat the request with the same method and body,
+// so only those links are useful for anything but GET and HEAD.
+func (u *URL) AcceptsMethod(method string) bool {
+	switch method {
+	case "", http.MethodGet, http.MethodHead:
+		return true
+	}
+	return u.RedirectType == http.StatusTemporaryRedirect || u.RedirectType == http.StatusPermanentRedirect
+}
+
+// Expired reports whether the link is past its expiration time.
+// Running out of clicks is checked by the repository when it counts the click.
+func (u *URL) Expired(now time.Time) bool {
+	return u.ExpiresAt != nil && !now.Before(*u.ExpiresAt)
+}
+
+// LinkUpdate lists the changes to make to an existing link.
+// nil fields are left as they are.
+type LinkUpdate struct {
+	OriginalURL  *string
+	Domain       *string // set along with OriginalURL
+	ExpiresAt    *time.Time
+	RemoveExpiry bool // drops expires_at, so the link never expires
+	MaxClicks    *int // 0 removes the limit
+	RedirectType *int // 0 goes back to the server wide default
+	Disabled     *bool
+	Tags         []string // an empty, non-nil slice removes all tags
 }
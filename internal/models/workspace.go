@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Workspace is a team sharing the deployment. Links, users and API keys belong to one,
+// and nothing in a workspace can be seen or changed from another.
+type Workspace struct {
+	ID        string    `bson:"_id,omitempty" json:"id"`
+	Name      string    `bson:"name" json:"name"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+}
+
+// User is a member of a workspace. API keys are issued to users,
+// so every link can be traced back to the person who created it.
+type User struct {
+	ID          string    `bson:"_id,omitempty" json:"id"`
+	WorkspaceID string    `bson:"workspace_id" json:"workspace_id"`
+	Name        string    `bson:"name" json:"name"`
+	Email       string    `bson:"email" json:"email"`
+	CreatedAt   time.Time `bson:"created_at" json:"created_at"`
+}
@@ -0,0 +1,136 @@
+// Package ratelimit implements the token bucket rate limiter used by the middleware.
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often idle buckets are dropped, so the map doesn't grow with every client we ever saw
+const sweepInterval = time.Minute
+
+// Limiter keeps one token bucket per key, e.g. per client IP.
+// Each bucket holds up to burst tokens and refills at a steady rate.
+// A request takes a token; when the bucket is empty the request is rejected.
+type Limiter struct {
+	rate  float64 // tokens added per second
+	burst int
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+
+	now func() time.Time // replaced in tests
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time // when tokens was last brought up to date
+}
+
+// Result tells the caller whether a request may go through,
+// along with what the client needs to know to pace itself.
+type Result struct {
+	Allowed    bool
+	Limit      int           // the size of the bucket
+	Remaining  int           // whole tokens left after this request
+	RetryAfter time.Duration // how long until the next token, when rejected
+	Reset      time.Duration // how long until the bucket is full again
+}
+
+// New returns a limiter that allows perMinute requests per minute per key,
+// with bursts of up to burst requests.
+func New(perMinute, burst int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{
+		rate:    float64(perMinute) / 60,
+		burst:   burst,
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Allow takes a token from the bucket of key.
+func (l *Limiter) Allow(key string) Result {
+	return l.AllowN(key, 1)
+}
+
+// AllowN takes n tokens from the bucket of key, for a request that counts as n, like a bulk request
+// counts each of its links. A request bigger than the bucket is let through when the bucket is full,
+// and leaves it in debt: the client is rejected until it has refilled past zero.
+func (l *Limiter) AllowN(key string, n int) Result {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.burst), last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens += now.Sub(b.last).Seconds() * l.rate
+	if b.tokens > float64(l.burst) {
+		b.tokens = float64(l.burst)
+	}
+	b.last = now
+
+	needed := float64(min(n, l.burst))
+	result := Result{Limit: l.burst}
+	if b.tokens >= needed {
+		b.tokens -= float64(n)
+		result.Allowed = true
+	} else {
+		result.RetryAfter = l.timeFor(needed - b.tokens)
+	}
+
+	result.Remaining = max(int(b.tokens), 0)
+	result.Reset = l.timeFor(float64(l.burst) - b.tokens)
+
+	return result
+}
+
+// timeFor returns how long it takes to refill the given number of tokens.
+func (l *Limiter) timeFor(tokens float64) time.Duration {
+	if l.rate <= 0 {
+		return 0
+	}
+	return time.Duration(tokens / l.rate * float64(time.Second))
+}
+
+// sweep drops the buckets that have refilled completely: a new bucket
+// would be full too, so forgetting them changes nothing.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= float64(l.burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+type chargeKey struct{}
+
+// WithCharge returns a copy of ctx that lets the handler charge its request once it knows what it costs, see Charge.
+func WithCharge(ctx context.Context, charge func(n int) Result) context.Context {
+	return context.WithValue(ctx, chargeKey{}, charge)
+}
+
+// Charge takes n tokens for the request of ctx, whose route is rate limited per item rather than per request.
+// ok is false when the request isn't rate limited, in which case nothing is taken.
+func Charge(ctx context.Context, n int) (result Result, ok bool) {
+	charge, ok := ctx.Value(chargeKey{}).(func(n int) Result)
+	if !ok {
+		return Result{}, false
+	}
+	return charge(n), true
+}
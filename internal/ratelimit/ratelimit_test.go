@@ -0,0 +1,162 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// clock is a fake time source the tests move forward by hand
+type clock struct {
+	t time.Time
+}
+
+func (c *clock) now() time.Time {
+	return c.t
+}
+
+func newTestLimiter(perMinute, burst int) (*Limiter, *clock) {
+	c := &clock{t: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)}
+	l := New(perMinute, burst)
+	l.now = c.now
+	return l, c
+}
+
+func TestAllow_Burst(t *testing.T) {
+	l, _ := newTestLimiter(60, 3)
+
+	for i := 0; i < 3; i++ {
+		result := l.Allow("1.2.3.4")
+		if !result.Allowed {
+			t.Fatalf("Expected request %d to be allowed", i+1)
+		}
+		if result.Remaining != 2-i {
+			t.Errorf("Expected %d remaining, got %d", 2-i, result.Remaining)
+		}
+	}
+
+	result := l.Allow("1.2.3.4")
+	if result.Allowed {
+		t.Fatal("Expected the request after the burst to be rejected")
+	}
+
+	// One token per second
+	if result.RetryAfter != time.Second {
+		t.Errorf("Expected to retry after 1s, got %v", result.RetryAfter)
+	}
+
+	if result.Reset != 3*time.Second {
+		t.Errorf("Expected the bucket to be full after 3s, got %v", result.Reset)
+	}
+
+	if result.Limit != 3 {
+		t.Errorf("Expected limit 3, got %d", result.Limit)
+	}
+}
+
+func TestAllow_Refill(t *testing.T) {
+	l, c := newTestLimiter(60, 2)
+
+	l.Allow("1.2.3.4")
+	l.Allow("1.2.3.4")
+
+	if l.Allow("1.2.3.4").Allowed {
+		t.Fatal("Expected the bucket to be empty")
+	}
+
+	c.t = c.t.Add(1500 * time.Millisecond)
+
+	if !l.Allow("1.2.3.4").Allowed {
+		t.Fatal("Expected a token after 1.5s")
+	}
+
+	if l.Allow("1.2.3.4").Allowed {
+		t.Fatal("Expected only one token after 1.5s")
+	}
+
+	// A long pause doesn't give more than the burst
+	c.t = c.t.Add(time.Hour)
+
+	if result := l.Allow("1.2.3.4"); result.Remaining != 1 {
+		t.Errorf("Expected the bucket to be capped at 2, got %d remaining", result.Remaining)
+	}
+}
+
+func TestAllowN(t *testing.T) {
+	l, _ := newTestLimiter(60, 5)
+
+	if result := l.AllowN("1.2.3.4", 3); !result.Allowed || result.Remaining != 2 {
+		t.Fatalf("Expected 3 tokens to be taken, got %+v", result)
+	}
+
+	result := l.AllowN("1.2.3.4", 3)
+	if result.Allowed {
+		t.Fatal("Expected 3 tokens not to fit in the 2 left")
+	}
+	if result.Remaining != 2 || result.RetryAfter != time.Second {
+		t.Errorf("Expected nothing to be taken and a retry after 1s, got %+v", result)
+	}
+}
+
+func TestAllowN_BiggerThanBurst(t *testing.T) {
+	l, c := newTestLimiter(60, 5)
+
+	// A full bucket lets it through, and goes 5 tokens into debt
+	if result := l.AllowN("1.2.3.4", 10); !result.Allowed || result.Remaining != 0 {
+		t.Fatalf("Expected a full bucket to let 10 tokens through, got %+v", result)
+	}
+
+	result := l.Allow("1.2.3.4")
+	if result.Allowed || result.RetryAfter != 6*time.Second {
+		t.Fatalf("Expected to wait for the debt to be paid back, got %+v", result)
+	}
+
+	c.t = c.t.Add(6 * time.Second)
+	if !l.Allow("1.2.3.4").Allowed {
+		t.Error("Expected a token once the debt is paid back")
+	}
+}
+
+func TestCharge(t *testing.T) {
+	if _, ok := Charge(context.Background(), 3); ok {
+		t.Error("Expected a request without a charge not to be rate limited")
+	}
+
+	l, _ := newTestLimiter(60, 5)
+	ctx := WithCharge(context.Background(), func(n int) Result {
+		return l.AllowN("1.2.3.4", n)
+	})
+
+	result, ok := Charge(ctx, 3)
+	if !ok || !result.Allowed || result.Remaining != 2 {
+		t.Errorf("Expected 3 tokens to be taken, got %+v", result)
+	}
+}
+
+func TestAllow_KeysAreIndependent(t *testing.T) {
+	l, _ := newTestLimiter(60, 1)
+
+	if !l.Allow("1.2.3.4").Allowed {
+		t.Fatal("Expected the first request to be allowed")
+	}
+
+	if !l.Allow("5.6.7.8").Allowed {
+		t.Error("Expected another key to have its own bucket")
+	}
+}
+
+func TestAllow_SweepsIdleBuckets(t *testing.T) {
+	l, c := newTestLimiter(60, 5)
+
+	for i := 0; i < 100; i++ {
+		l.Allow(fmt.Sprintf("10.0.0.%d", i))
+	}
+
+	c.t = c.t.Add(2 * time.Minute)
+	l.Allow("1.2.3.4")
+
+	if len(l.buckets) != 1 {
+		t.Errorf("Expected idle buckets to be dropped, %d left", len(l.buckets))
+	}
+}
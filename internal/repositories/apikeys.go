@@ -0,0 +1,81 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/topboyasante/trunc8/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type APIKeyRepository struct {
+	collection *mongo.Collection
+}
+
+func NewAPIKeyRepository(db *mongo.Database) *APIKeyRepository {
+	return &APIKeyRepository{
+		collection: db.Collection("api_keys"),
+	}
+}
+
+// EnsureIndexes creates the unique index keys are looked up by.
+func (r *APIKeyRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "key_hash", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("creating api_keys indexes: %w", err)
+	}
+	return nil
+}
+
+func (r *APIKeyRepository) CreateAPIKey(ctx context.Context, key models.APIKey) (string, error) {
+	return insertOne(ctx, r.collection, key)
+}
+
+// FindAPIKeyByHash returns the key with the given hash, which is how requests are authenticated.
+func (r *APIKeyRepository) FindAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	var key models.APIKey
+
+	err := r.collection.FindOne(ctx, bson.M{"key_hash": hash}).Decode(&key)
+	if err == mongo.ErrNoDocuments {
+		return nil, models.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+// ListAPIKeys returns every key, oldest first.
+func (r *APIKeyRepository) ListAPIKeys(ctx context.Context) ([]*models.APIKey, error) {
+	var keys []*models.APIKey
+	if err := findAll(ctx, r.collection, bson.M{}, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// DeleteAPIKey revokes a key. Requests made with it are rejected from then on.
+func (r *APIKeyRepository) DeleteAPIKey(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("%w: %s", models.ErrAPIKeyNotFound, id)
+	}
+
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": objectID})
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("%w: %s", models.ErrAPIKeyNotFound, id)
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/topboyasante/trunc8/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestAPIKeyRepository_CreateAPIKey(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("success", func(mt *mtest.T) {
+		repo := &APIKeyRepository{collection: mt.Coll}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+
+		id, err := repo.CreateAPIKey(context.Background(), models.APIKey{Name: "ci", KeyHash: "abc"})
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if id == "" {
+			t.Error("Expected an ID")
+		}
+	})
+}
+
+func TestAPIKeyRepository_FindAPIKeyByHash(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("success", func(mt *mtest.T) {
+		repo := &APIKeyRepository{collection: mt.Coll}
+
+		first := mtest.CreateCursorResponse(1, "trunc8-db.api_keys", mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: "665f1f77bcf86cd799439011"},
+			{Key: "name", Value: "ci"},
+			{Key: "key_hash", Value: "abc"},
+			{Key: "scopes", Value: bson.A{"links:write"}},
+		})
+		killCursor := mtest.CreateCursorResponse(0, "trunc8-db.api_keys", mtest.NextBatch)
+		mt.AddMockResponses(first, killCursor)
+
+		key, err := repo.FindAPIKeyByHash(context.Background(), "abc")
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if key.Name != "ci" || !key.HasScope(models.ScopeLinksWrite) {
+			t.Errorf("Unexpected key: %+v", key)
+		}
+	})
+
+	mt.Run("not found", func(mt *mtest.T) {
+		repo := &APIKeyRepository{collection: mt.Coll}
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "trunc8-db.api_keys", mtest.FirstBatch))
+
+		_, err := repo.FindAPIKeyByHash(context.Background(), "abc")
+
+		if !errors.Is(err, models.ErrAPIKeyNotFound) {
+			t.Fatalf("Expected ErrAPIKeyNotFound, got %v", err)
+		}
+	})
+}
+
+func TestAPIKeyRepository_DeleteAPIKey(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("success", func(mt *mtest.T) {
+		repo := &APIKeyRepository{collection: mt.Coll}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}))
+
+		if err := repo.DeleteAPIKey(context.Background(), "665f1f77bcf86cd799439011"); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	})
+
+	mt.Run("not found", func(mt *mtest.T) {
+		repo := &APIKeyRepository{collection: mt.Coll}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}))
+
+		err := repo.DeleteAPIKey(context.Background(), "665f1f77bcf86cd799439011")
+
+		if !errors.Is(err, models.ErrAPIKeyNotFound) {
+			t.Fatalf("Expected ErrAPIKeyNotFound, got %v", err)
+		}
+	})
+
+	mt.Run("malformed id", func(mt *mtest.T) {
+		repo := &APIKeyRepository{collection: mt.Coll}
+
+		err := repo.DeleteAPIKey(context.Background(), "nope")
+
+		if !errors.Is(err, models.ErrAPIKeyNotFound) {
+			t.Fatalf("Expected ErrAPIKeyNotFound, got %v", err)
+		}
+	})
+}
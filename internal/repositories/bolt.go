@@ -0,0 +1,626 @@
+package repositories
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/topboyasante/trunc8/internal/models"
+	bolt "go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// The buckets of the data file. Documents are stored as BSON, the same documents
+// the Mongo backend stores, so the bson tags of the models apply here too.
+var (
+	linksBucket      = []byte("links")      // code -> link
+	clicksBucket     = []byte("clicks")     // code -> bucket of clicks, keyed by time then sequence
+	countersBucket   = []byte("counters")   // name -> uint64
+	apiKeysBucket    = []byte("api_keys")   // ID -> key
+	keyHashesBucket  = []byte("key_hashes") // key hash -> ID, keeps hashes unique
+	workspacesBucket = []byte("workspaces") // ID -> workspace
+	usersBucket      = []byte("users")      // ID -> user
+	emailsBucket     = []byte("emails")     // email -> user ID, keeps emails unique
+)
+
+// BoltRepository stores everything in a single bbolt file, so trunc8 can run as one binary without a database server.
+// Writes are serialized by bbolt, which is what makes counting clicks safe.
+// Links are kept by code only: listing them and finding one by URL read every link,
+// which is fine for the small deployments this backend is meant for.
+type BoltRepository struct {
+	db *bolt.DB
+}
+
+// NewBoltRepository opens the data file at path, creating it and its buckets if needed.
+func NewBoltRepository(path string) (*BoltRepository, error) {
+	// Without a timeout a second process opening the same file would wait forever for the lock
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{linksBucket, clicksBucket, countersBucket, apiKeysBucket, keyHashesBucket, workspacesBucket, usersBucket, emailsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return fmt.Errorf("creating bucket %s: %w", name, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &BoltRepository{db: db}, nil
+}
+
+// Ping fails once the data file has been closed.
+func (r *BoltRepository) Ping(ctx context.Context) error {
+	return r.db.View(func(tx *bolt.Tx) error { return nil })
+}
+
+// Migrations reports none: the buckets are created when the file is opened.
+func (r *BoltRepository) Migrations(ctx context.Context) (*models.MigrationState, error) {
+	return noMigrations(), nil
+}
+
+// Close closes the data file.
+func (r *BoltRepository) Close(ctx context.Context) error {
+	return r.db.Close()
+}
+
+// getDocument decodes the document stored under key into v. It returns false when there is none.
+func getDocument(bucket *bolt.Bucket, key string, v interface{}) (bool, error) {
+	data := bucket.Get([]byte(key))
+	if data == nil {
+		return false, nil
+	}
+	return true, bson.Unmarshal(data, v)
+}
+
+func putDocument(bucket *bolt.Bucket, key string, v interface{}) error {
+	data, err := bson.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return bucket.Put([]byte(key), data)
+}
+
+// newBoltID returns the next ID of bucket, shaped like the IDs of the memory backend.
+func newBoltID(bucket *bolt.Bucket) (string, error) {
+	n, err := bucket.NextSequence()
+	if err != nil {
+		return "", err
+	}
+	return sequenceID(n), nil
+}
+
+func (r *BoltRepository) Create(ctx context.Context, url models.URL) (string, error) {
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		links := tx.Bucket(linksBucket)
+		if links.Get([]byte(url.Code)) != nil {
+			return fmt.Errorf("%w: %s", models.ErrDuplicateCode, url.Code)
+		}
+
+		id, err := newBoltID(links)
+		if err != nil {
+			return err
+		}
+		url.ID = id
+
+		return putDocument(links, url.Code, url)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return url.ID, nil
+}
+
+// CreateMany stores the links in a single transaction, so the file is synced once for the whole batch.
+func (r *BoltRepository) CreateMany(ctx context.Context, urls []models.URL) ([]string, []error) {
+	ids := make([]string, len(urls))
+	errs := duplicateCodes(urls)
+
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		links := tx.Bucket(linksBucket)
+		for i, url := range urls {
+			if errs[i] != nil {
+				continue
+			}
+			if links.Get([]byte(url.Code)) != nil {
+				errs[i] = fmt.Errorf("%w: %s", models.ErrDuplicateCode, url.Code)
+				continue
+			}
+
+			id, err := newBoltID(links)
+			if err != nil {
+				return err
+			}
+			url.ID = id
+			if err := putDocument(links, url.Code, url); err != nil {
+				return err
+			}
+			ids[i] = id
+		}
+		return nil
+	})
+	if err != nil {
+		// Nothing was stored
+		clear(ids)
+		failBatch(errs, err)
+	}
+
+	return ids, errs
+}
+
+func (r *BoltRepository) FindOne(ctx context.Context, code string) (*models.URL, error) {
+	return r.FindInWorkspace(ctx, "", code)
+}
+
+// FindInWorkspace works like FindOne, but only finds links of the given workspace.
+// An empty workspaceID finds links of every workspace.
+func (r *BoltRepository) FindInWorkspace(ctx context.Context, workspaceID, code string) (*models.URL, error) {
+	var url *models.URL
+	err := r.db.View(func(tx *bolt.Tx) error {
+		var err error
+		url, err = findBoltLink(tx, workspaceID, code)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return url, nil
+}
+
+func findBoltLink(tx *bolt.Tx, workspaceID, code string) (*models.URL, error) {
+	var url models.URL
+	found, err := getDocument(tx.Bucket(linksBucket), code, &url)
+	if err != nil {
+		return nil, err
+	}
+	if !found || (workspaceID != "" && url.WorkspaceID != workspaceID) {
+		return nil, fmt.Errorf("%w: %s", models.ErrNotFound, code)
+	}
+	return &url, nil
+}
+
+// allLinks decodes every link of the file.
+func allLinks(tx *bolt.Tx) ([]*models.URL, error) {
+	var links []*models.URL
+	err := tx.Bucket(linksBucket).ForEach(func(k, v []byte) error {
+		var url models.URL
+		if err := bson.Unmarshal(v, &url); err != nil {
+			return fmt.Errorf("decoding link %s: %w", k, err)
+		}
+		links = append(links, &url)
+		return nil
+	})
+	return links, err
+}
+
+// FindByOriginalURL returns the oldest plain link to originalURL in the given workspace.
+// An empty workspaceID only matches links without a workspace, see ShortenerRepository.FindByOriginalURL.
+func (r *BoltRepository) FindByOriginalURL(ctx context.Context, workspaceID, originalURL string) (*models.URL, error) {
+	var found *models.URL
+	err := r.db.View(func(tx *bolt.Tx) error {
+		links, err := allLinks(tx)
+		if err != nil {
+			return err
+		}
+
+		for _, url := range links {
+			if url.WorkspaceID != workspaceID || url.OriginalURL != originalURL || !isPlainLink(url) {
+				continue
+			}
+			if found == nil || url.ID < found.ID {
+				found = url
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if found == nil {
+		return nil, fmt.Errorf("%w: %s", models.ErrNotFound, originalURL)
+	}
+	return found, nil
+}
+
+func (r *BoltRepository) Update(ctx context.Context, workspaceID, code string, update models.LinkUpdate) (*models.URL, error) {
+	var url *models.URL
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		url, err = findBoltLink(tx, workspaceID, code)
+		if err != nil {
+			return err
+		}
+
+		applyUpdate(url, update)
+		return putDocument(tx.Bucket(linksBucket), code, url)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return url, nil
+}
+
+// Delete removes the link with the given code along with its click events, in one transaction.
+func (r *BoltRepository) Delete(ctx context.Context, workspaceID, code string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		if _, err := findBoltLink(tx, workspaceID, code); err != nil {
+			return err
+		}
+
+		if err := tx.Bucket(linksBucket).Delete([]byte(code)); err != nil {
+			return err
+		}
+
+		err := tx.Bucket(clicksBucket).DeleteBucket([]byte(code))
+		if err != nil && !errors.Is(err, bolt.ErrBucketNotFound) {
+			return fmt.Errorf("deleting clicks of %s: %w", code, err)
+		}
+		return nil
+	})
+}
+
+func (r *BoltRepository) List(ctx context.Context, query models.ListQuery) ([]*models.URL, error) {
+	var links []*models.URL
+	err := r.db.View(func(tx *bolt.Tx) error {
+		all, err := allLinks(tx)
+		if err != nil {
+			return err
+		}
+		links = listLinks(all, query)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
+
+// IncrementClickCount bumps the click counter of a link by one, unless it has no clicks left.
+// Like the Mongo backend, it returns ErrExpired when the click can't be counted.
+func (r *BoltRepository) IncrementClickCount(ctx context.Context, code string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		links := tx.Bucket(linksBucket)
+
+		var url models.URL
+		found, err := getDocument(links, code, &url)
+		if err != nil {
+			return err
+		}
+		if !found || !hasClicksLeft(&url) {
+			return fmt.Errorf("%w: %s has no clicks left", models.ErrExpired, code)
+		}
+
+		url.ClickCount++
+		return putDocument(links, code, url)
+	})
+}
+
+// CreateClick stores a click in the bucket of its link. The key starts with the time of the click,
+// so ClickStats can seek straight to the start of the period.
+func (r *BoltRepository) CreateClick(ctx context.Context, click models.Click) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		clicks, err := tx.Bucket(clicksBucket).CreateBucketIfNotExists([]byte(click.Code))
+		if err != nil {
+			return err
+		}
+
+		seq, err := clicks.NextSequence()
+		if err != nil {
+			return err
+		}
+		click.ID = sequenceID(seq)
+
+		data, err := bson.Marshal(click)
+		if err != nil {
+			return err
+		}
+		return clicks.Put(clickKey(click.Timestamp, seq), data)
+	})
+}
+
+// clickKey sorts clicks by time, then by the order they were stored in.
+func clickKey(t time.Time, seq uint64) []byte {
+	key := make([]byte, 16)
+	binary.BigEndian.PutUint64(key, uint64(t.UnixNano()))
+	binary.BigEndian.PutUint64(key[8:], seq)
+	return key
+}
+
+func (r *BoltRepository) ClickStats(ctx context.Context, query models.StatsQuery) (*models.LinkStats, error) {
+	var clicks []models.Click
+	err := r.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(clicksBucket).Bucket([]byte(query.Code))
+		if bucket == nil {
+			return nil
+		}
+
+		end := clickKey(query.To, 0)
+		c := bucket.Cursor()
+		for k, v := c.Seek(clickKey(query.From, 0)); k != nil && bytes.Compare(k, end) < 0; k, v = c.Next() {
+			var click models.Click
+			if err := bson.Unmarshal(v, &click); err != nil {
+				return err
+			}
+			clicks = append(clicks, click)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return clickStats(clicks, query), nil
+}
+
+// NextSequence increments the counter called name and returns its new value.
+func (r *BoltRepository) NextSequence(ctx context.Context, name string) (int64, error) {
+	var seq uint64
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		counters := tx.Bucket(countersBucket)
+		if data := counters.Get([]byte(name)); data != nil {
+			seq = binary.BigEndian.Uint64(data)
+		}
+		seq++
+
+		value := make([]byte, 8)
+		binary.BigEndian.PutUint64(value, seq)
+		return counters.Put([]byte(name), value)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(seq), nil
+}
+
+// RaiseSequence raises the counter called name to value, unless it's already higher.
+func (r *BoltRepository) RaiseSequence(ctx context.Context, name string, value int64) (int64, error) {
+	seq := uint64(value)
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		counters := tx.Bucket(countersBucket)
+		if data := counters.Get([]byte(name)); data != nil {
+			seq = max(seq, binary.BigEndian.Uint64(data))
+		}
+
+		value := make([]byte, 8)
+		binary.BigEndian.PutUint64(value, seq)
+		return counters.Put([]byte(name), value)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(seq), nil
+}
+
+func (r *BoltRepository) CreateAPIKey(ctx context.Context, key models.APIKey) (string, error) {
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		hashes := tx.Bucket(keyHashesBucket)
+		if hashes.Get([]byte(key.KeyHash)) != nil {
+			return fmt.Errorf("an API key with hash %s already exists", key.KeyHash)
+		}
+
+		keys := tx.Bucket(apiKeysBucket)
+		id, err := newBoltID(keys)
+		if err != nil {
+			return err
+		}
+		key.ID = id
+
+		if err := hashes.Put([]byte(key.KeyHash), []byte(key.ID)); err != nil {
+			return err
+		}
+		return putDocument(keys, key.ID, key)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return key.ID, nil
+}
+
+// FindAPIKeyByHash returns the key with the given hash, which is how requests are authenticated.
+func (r *BoltRepository) FindAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	var key models.APIKey
+	err := r.db.View(func(tx *bolt.Tx) error {
+		id := tx.Bucket(keyHashesBucket).Get([]byte(hash))
+		if id == nil {
+			return models.ErrAPIKeyNotFound
+		}
+
+		found, err := getDocument(tx.Bucket(apiKeysBucket), string(id), &key)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return models.ErrAPIKeyNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+// ListAPIKeys returns every key, oldest first.
+func (r *BoltRepository) ListAPIKeys(ctx context.Context) ([]*models.APIKey, error) {
+	var keys []*models.APIKey
+	err := r.db.View(func(tx *bolt.Tx) error {
+		// Keys are sequential IDs, so the bucket is already in creation order
+		return tx.Bucket(apiKeysBucket).ForEach(func(k, v []byte) error {
+			var key models.APIKey
+			if err := bson.Unmarshal(v, &key); err != nil {
+				return err
+			}
+			keys = append(keys, &key)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// DeleteAPIKey revokes a key. Requests made with it are rejected from then on.
+func (r *BoltRepository) DeleteAPIKey(ctx context.Context, id string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		keys := tx.Bucket(apiKeysBucket)
+
+		var key models.APIKey
+		found, err := getDocument(keys, id, &key)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf("%w: %s", models.ErrAPIKeyNotFound, id)
+		}
+
+		if err := tx.Bucket(keyHashesBucket).Delete([]byte(key.KeyHash)); err != nil {
+			return err
+		}
+		return keys.Delete([]byte(id))
+	})
+}
+
+func (r *BoltRepository) CreateWorkspace(ctx context.Context, workspace models.Workspace) (string, error) {
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		workspaces := tx.Bucket(workspacesBucket)
+
+		id, err := newBoltID(workspaces)
+		if err != nil {
+			return err
+		}
+		workspace.ID = id
+
+		return putDocument(workspaces, workspace.ID, workspace)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return workspace.ID, nil
+}
+
+func (r *BoltRepository) FindWorkspace(ctx context.Context, id string) (*models.Workspace, error) {
+	var workspace models.Workspace
+	err := r.db.View(func(tx *bolt.Tx) error {
+		found, err := getDocument(tx.Bucket(workspacesBucket), id, &workspace)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf("%w: %s", models.ErrWorkspaceNotFound, id)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &workspace, nil
+}
+
+// ListWorkspaces returns every workspace, oldest first.
+func (r *BoltRepository) ListWorkspaces(ctx context.Context) ([]*models.Workspace, error) {
+	var workspaces []*models.Workspace
+	err := r.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(workspacesBucket).ForEach(func(k, v []byte) error {
+			var workspace models.Workspace
+			if err := bson.Unmarshal(v, &workspace); err != nil {
+				return err
+			}
+			workspaces = append(workspaces, &workspace)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return workspaces, nil
+}
+
+func (r *BoltRepository) CreateUser(ctx context.Context, user models.User) (string, error) {
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		emails := tx.Bucket(emailsBucket)
+		if emails.Get([]byte(user.Email)) != nil {
+			return fmt.Errorf("%w: %s", models.ErrEmailTaken, user.Email)
+		}
+
+		users := tx.Bucket(usersBucket)
+		id, err := newBoltID(users)
+		if err != nil {
+			return err
+		}
+		user.ID = id
+
+		if err := emails.Put([]byte(user.Email), []byte(user.ID)); err != nil {
+			return err
+		}
+		return putDocument(users, user.ID, user)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return user.ID, nil
+}
+
+func (r *BoltRepository) FindUser(ctx context.Context, id string) (*models.User, error) {
+	var user models.User
+	err := r.db.View(func(tx *bolt.Tx) error {
+		found, err := getDocument(tx.Bucket(usersBucket), id, &user)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf("%w: %s", models.ErrUserNotFound, id)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// ListUsers returns the members of a workspace, oldest first.
+func (r *BoltRepository) ListUsers(ctx context.Context, workspaceID string) ([]*models.User, error) {
+	var users []*models.User
+	err := r.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(usersBucket).ForEach(func(k, v []byte) error {
+			var user models.User
+			if err := bson.Unmarshal(v, &user); err != nil {
+				return err
+			}
+			if user.WorkspaceID == workspaceID {
+				users = append(users, &user)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
@@ -0,0 +1,223 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/topboyasante/trunc8/internal/models"
+)
+
+func newTestBoltRepository(t *testing.T) (*BoltRepository, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "trunc8.db")
+	repo, err := NewBoltRepository(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	t.Cleanup(func() { repo.Close(context.Background()) })
+
+	return repo, path
+}
+
+func TestBoltRepository_Links(t *testing.T) {
+	repo, path := newTestBoltRepository(t)
+	ctx := context.Background()
+
+	expiresAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	id, err := repo.Create(ctx, models.URL{Code: "abc", OriginalURL: "https://example.com", ExpiresAt: &expiresAt, MaxClicks: 2, Tags: []string{"promo"}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if _, err := repo.Create(ctx, models.URL{Code: "abc"}); !errors.Is(err, models.ErrDuplicateCode) {
+		t.Errorf("Expected ErrDuplicateCode, got %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := repo.IncrementClickCount(ctx, "abc"); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	if err := repo.IncrementClickCount(ctx, "abc"); !errors.Is(err, models.ErrExpired) {
+		t.Errorf("Expected ErrExpired, got %v", err)
+	}
+
+	// Everything has to survive closing and opening the file again
+	repo.Close(ctx)
+	repo, err = NewBoltRepository(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer repo.Close(ctx)
+
+	url, err := repo.FindInWorkspace(ctx, "", "abc")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if url.ID != id || url.ClickCount != 2 || url.ExpiresAt == nil || !url.ExpiresAt.Equal(expiresAt) || len(url.Tags) != 1 {
+		t.Errorf("Unexpected link: %+v", url)
+	}
+
+	noLimit := 0
+	url, err = repo.Update(ctx, "", "abc", models.LinkUpdate{MaxClicks: &noLimit, RemoveExpiry: true, Tags: []string{}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if url.MaxClicks != 0 || url.ExpiresAt != nil || url.Tags != nil {
+		t.Errorf("Unexpected link after update: %+v", url)
+	}
+
+	found, err := repo.FindByOriginalURL(ctx, "", "https://example.com")
+	if err != nil || found.Code != "abc" {
+		t.Errorf("Expected the link to be plain now, got %+v, %v", found, err)
+	}
+
+	links, err := repo.List(ctx, models.ListQuery{Status: models.StatusActive, Now: time.Now()})
+	if err != nil || len(links) != 1 {
+		t.Errorf("Expected 1 active link, got %d, %v", len(links), err)
+	}
+
+	if _, err := repo.FindInWorkspace(ctx, "ws-1", "abc"); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for another workspace, got %v", err)
+	}
+}
+
+func TestBoltRepository_Clicks(t *testing.T) {
+	repo, _ := newTestBoltRepository(t)
+	ctx := context.Background()
+
+	repo.Create(ctx, models.URL{Code: "abc"})
+
+	day := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	for _, at := range []time.Duration{-time.Hour, time.Hour, 2 * time.Hour, 25 * time.Hour} {
+		if err := repo.CreateClick(ctx, models.Click{Code: "abc", Timestamp: day.Add(at), IPHash: "1"}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	query := models.StatsQuery{Code: "abc", From: day, To: day.Add(24 * time.Hour), Interval: "hour", Limit: 5}
+	stats, err := repo.ClickStats(ctx, query)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if stats.TotalClicks != 2 || stats.UniqueVisitors != 1 || len(stats.Series) != 2 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	if err := repo.Delete(ctx, "", "abc"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	stats, _ = repo.ClickStats(ctx, query)
+	if stats.TotalClicks != 0 {
+		t.Errorf("Expected the clicks to be deleted with the link, got %d", stats.TotalClicks)
+	}
+}
+
+func TestBoltRepository_NextSequence(t *testing.T) {
+	repo, _ := newTestBoltRepository(t)
+	ctx := context.Background()
+
+	for want := int64(1); want <= 3; want++ {
+		got, err := repo.NextSequence(ctx, "links")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if got != want {
+			t.Errorf("Expected %d, got %d", want, got)
+		}
+	}
+}
+
+func TestBoltRepository_RaiseSequence(t *testing.T) {
+	repo, _ := newTestBoltRepository(t)
+	ctx := context.Background()
+
+	for _, step := range []struct{ value, want int64 }{{0, 0}, {5, 5}, {3, 5}, {0, 5}, {6, 6}} {
+		got, err := repo.RaiseSequence(ctx, "code_length", step.value)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if got != step.want {
+			t.Errorf("Raising to %d: expected %d, got %d", step.value, step.want, got)
+		}
+	}
+}
+
+func TestBoltRepository_KeysAndUsers(t *testing.T) {
+	repo, _ := newTestBoltRepository(t)
+	ctx := context.Background()
+
+	id, err := repo.CreateAPIKey(ctx, models.APIKey{Name: "ci", KeyHash: "hash", Scopes: []string{models.ScopeLinksRead}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	key, err := repo.FindAPIKeyByHash(ctx, "hash")
+	if err != nil || key.ID != id || key.KeyHash != "hash" {
+		t.Errorf("Unexpected key: %+v, %v", key, err)
+	}
+
+	if err := repo.DeleteAPIKey(ctx, id); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := repo.FindAPIKeyByHash(ctx, "hash"); !errors.Is(err, models.ErrAPIKeyNotFound) {
+		t.Errorf("Expected ErrAPIKeyNotFound, got %v", err)
+	}
+
+	workspaceID, _ := repo.CreateWorkspace(ctx, models.Workspace{Name: "Acme"})
+	if _, err := repo.CreateUser(ctx, models.User{WorkspaceID: workspaceID, Email: "ama@example.com"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := repo.CreateUser(ctx, models.User{WorkspaceID: workspaceID, Email: "ama@example.com"}); !errors.Is(err, models.ErrEmailTaken) {
+		t.Errorf("Expected ErrEmailTaken, got %v", err)
+	}
+
+	users, err := repo.ListUsers(ctx, workspaceID)
+	if err != nil || len(users) != 1 {
+		t.Errorf("Expected 1 user, got %d, %v", len(users), err)
+	}
+}
+
+func TestBoltRepository_Ping(t *testing.T) {
+	repo, _ := newTestBoltRepository(t)
+	ctx := context.Background()
+
+	if err := repo.Ping(ctx); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	repo.Close(ctx)
+	if err := repo.Ping(ctx); err == nil {
+		t.Error("Expected an error once the file is closed")
+	}
+}
+
+func TestBoltRepository_CreateMany(t *testing.T) {
+	repo, _ := newTestBoltRepository(t)
+	ctx := context.Background()
+	repo.Create(ctx, models.URL{Code: "taken", OriginalURL: "https://example.com"})
+
+	ids, errs := repo.CreateMany(ctx, []models.URL{
+		{Code: "one", OriginalURL: "https://example.com/1"},
+		{Code: "taken", OriginalURL: "https://example.com/2"},
+		{Code: "one", OriginalURL: "https://example.com/3"},
+	})
+
+	if errs[0] != nil || ids[0] == "" {
+		t.Fatalf("Expected the first link to be stored, got %v", errs[0])
+	}
+	for _, i := range []int{1, 2} {
+		if !errors.Is(errs[i], models.ErrDuplicateCode) {
+			t.Errorf("Expected ErrDuplicateCode for link %d, got %v", i, errs[i])
+		}
+	}
+
+	url, err := repo.FindOne(ctx, "one")
+	if err != nil || url.ID != ids[0] {
+		t.Errorf("Expected to find the stored link with ID %s, got %+v, %v", ids[0], url, err)
+	}
+}
@@ -0,0 +1,136 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/topboyasante/trunc8/internal/cache"
+	"github.com/topboyasante/trunc8/internal/models"
+)
+
+// CachedRepository puts an LRU cache in front of FindOne, the lookup every redirect makes.
+// Codes that don't exist are cached too, for a shorter time, so scans for valid codes don't reach storage.
+// Every other method goes straight to the wrapped repository.
+//
+// Changes made through this repository invalidate the cache right away. Changes made by other
+// instances of the server are only seen once the entry expires, so keep the TTL short when running several.
+type CachedRepository struct {
+	Repository
+
+	links       *cache.LRU[*models.URL] // nil marks a code that doesn't exist
+	ttl         time.Duration
+	negativeTTL time.Duration
+
+	// epoch counts the invalidations. A lookup only caches what it found when no invalidation
+	// happened while it ran: the link it read may be the one from before the change.
+	mu    sync.Mutex
+	epoch uint64
+
+	hits         atomic.Uint64
+	negativeHits atomic.Uint64
+	misses       atomic.Uint64
+}
+
+// NewCachedRepository caches up to size links found in repo for ttl, and codes that weren't found for negativeTTL.
+func NewCachedRepository(repo Repository, size int, ttl, negativeTTL time.Duration) *CachedRepository {
+	return &CachedRepository{
+		Repository:  repo,
+		links:       cache.New[*models.URL](size),
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+	}
+}
+
+func (r *CachedRepository) FindOne(ctx context.Context, code string) (*models.URL, error) {
+	if url, ok := r.links.Get(code); ok {
+		if url == nil {
+			r.negativeHits.Add(1)
+			return nil, fmt.Errorf("%w: %s", models.ErrNotFound, code)
+		}
+		r.hits.Add(1)
+		// Callers may change the link they get back, the cached one has to stay as it was
+		return copyURL(url), nil
+	}
+
+	r.misses.Add(1)
+
+	r.mu.Lock()
+	epoch := r.epoch
+	r.mu.Unlock()
+
+	url, err := r.Repository.FindOne(ctx, code)
+	if errors.Is(err, models.ErrNotFound) && r.negativeTTL > 0 {
+		r.setIfCurrent(epoch, code, nil, r.negativeTTL)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r.setIfCurrent(epoch, code, copyURL(url), r.ttl)
+	return url, nil
+}
+
+// setIfCurrent caches url under code, unless the cache was invalidated since epoch.
+func (r *CachedRepository) setIfCurrent(epoch uint64, code string, url *models.URL, ttl time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.epoch == epoch {
+		r.links.Set(code, url, ttl)
+	}
+}
+
+// invalidate drops the cached entries of codes, and keeps the lookups running
+// at the same time from caching what they read before the change.
+func (r *CachedRepository) invalidate(codes ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.epoch++
+	for _, code := range codes {
+		r.links.Delete(code)
+	}
+}
+
+// Create drops a cached "not found" for the code, so the new link works right away.
+func (r *CachedRepository) Create(ctx context.Context, url models.URL) (string, error) {
+	id, err := r.Repository.Create(ctx, url)
+	r.invalidate(url.Code)
+	return id, err
+}
+
+func (r *CachedRepository) CreateMany(ctx context.Context, urls []models.URL) ([]string, []error) {
+	ids, errs := r.Repository.CreateMany(ctx, urls)
+	codes := make([]string, len(urls))
+	for i, url := range urls {
+		codes[i] = url.Code
+	}
+	r.invalidate(codes...)
+	return ids, errs
+}
+
+func (r *CachedRepository) Update(ctx context.Context, workspaceID, code string, update models.LinkUpdate) (*models.URL, error) {
+	url, err := r.Repository.Update(ctx, workspaceID, code, update)
+	r.invalidate(code)
+	return url, err
+}
+
+func (r *CachedRepository) Delete(ctx context.Context, workspaceID, code string) error {
+	err := r.Repository.Delete(ctx, workspaceID, code)
+	r.invalidate(code)
+	return err
+}
+
+// CacheStats returns the hit and miss counters.
+func (r *CachedRepository) CacheStats() models.CacheStats {
+	return models.CacheStats{
+		Hits:         r.hits.Load(),
+		NegativeHits: r.negativeHits.Load(),
+		Misses:       r.misses.Load(),
+		Entries:      r.links.Len(),
+	}
+}
@@ -0,0 +1,125 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/topboyasante/trunc8/internal/models"
+)
+
+func TestCachedRepository_FindOne(t *testing.T) {
+	ctx := context.Background()
+	repo := NewCachedRepository(NewMemoryRepository(), 10, time.Minute, time.Minute)
+
+	repo.Create(ctx, models.URL{Code: "abc", OriginalURL: "https://example.com"})
+
+	for i := 0; i < 3; i++ {
+		url, err := repo.FindOne(ctx, "abc")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		// Changing the returned link must not change the cached one
+		url.OriginalURL = "https://changed.example.com"
+	}
+
+	url, _ := repo.FindOne(ctx, "abc")
+	if url.OriginalURL != "https://example.com" {
+		t.Errorf("Expected the cached link to be unchanged, got %q", url.OriginalURL)
+	}
+
+	stats := repo.CacheStats()
+	if stats.Misses != 1 || stats.Hits != 3 || stats.Entries != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestCachedRepository_NegativeCaching(t *testing.T) {
+	ctx := context.Background()
+	repo := NewCachedRepository(NewMemoryRepository(), 10, time.Minute, time.Minute)
+
+	for i := 0; i < 2; i++ {
+		if _, err := repo.FindOne(ctx, "abc"); !errors.Is(err, models.ErrNotFound) {
+			t.Fatalf("Expected ErrNotFound, got %v", err)
+		}
+	}
+
+	stats := repo.CacheStats()
+	if stats.Misses != 1 || stats.NegativeHits != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	// Creating the link has to forget that it didn't exist
+	repo.Create(ctx, models.URL{Code: "abc"})
+	if _, err := repo.FindOne(ctx, "abc"); err != nil {
+		t.Errorf("Expected the new link to be found, got %v", err)
+	}
+}
+
+func TestCachedRepository_Invalidation(t *testing.T) {
+	ctx := context.Background()
+	repo := NewCachedRepository(NewMemoryRepository(), 10, time.Minute, time.Minute)
+
+	repo.Create(ctx, models.URL{Code: "abc"})
+	repo.FindOne(ctx, "abc")
+
+	disabled := true
+	repo.Update(ctx, "", "abc", models.LinkUpdate{Disabled: &disabled})
+
+	url, _ := repo.FindOne(ctx, "abc")
+	if !url.Disabled {
+		t.Error("Expected the update to be visible right away")
+	}
+
+	repo.Delete(ctx, "", "abc")
+
+	if _, err := repo.FindOne(ctx, "abc"); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound after Delete, got %v", err)
+	}
+}
+
+// racingRepository runs write in the middle of FindOne, after the link has been read
+type racingRepository struct {
+	Repository
+	write func()
+}
+
+func (r *racingRepository) FindOne(ctx context.Context, code string) (*models.URL, error) {
+	url, err := r.Repository.FindOne(ctx, code)
+	if r.write != nil {
+		write := r.write
+		r.write = nil
+		write()
+	}
+	return url, err
+}
+
+func TestCachedRepository_InvalidationDuringLookup(t *testing.T) {
+	ctx := context.Background()
+	racing := &racingRepository{Repository: NewMemoryRepository()}
+	repo := NewCachedRepository(racing, 10, time.Minute, time.Minute)
+
+	repo.Create(ctx, models.URL{Code: "abc"})
+
+	// The link is disabled after the lookup read it, but before it was cached
+	disabled := true
+	racing.write = func() {
+		repo.Update(ctx, "", "abc", models.LinkUpdate{Disabled: &disabled})
+	}
+	repo.FindOne(ctx, "abc")
+
+	if url, _ := repo.FindOne(ctx, "abc"); !url.Disabled {
+		t.Error("Expected the lookup not to cache the link from before the update")
+	}
+
+	// Same for a code that is created while a lookup finds nothing
+	racing.write = func() {
+		repo.Create(ctx, models.URL{Code: "new"})
+	}
+	repo.FindOne(ctx, "new")
+
+	if _, err := repo.FindOne(ctx, "new"); err != nil {
+		t.Errorf("Expected the new link not to be hidden by a cached not found, got %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package repositories
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/topboyasante/trunc8/internal/logging"
+	"github.com/topboyasante/trunc8/internal/models"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// AsyncClickRepository stores click events in the background, so redirects don't wait for the write.
+// Clicks are queued in a buffer drained by a single goroutine. When the buffer is full the click is
+// written right away instead, so a burst of traffic slows redirects down rather than losing clicks.
+// Every other method goes straight to the wrapped repository.
+type AsyncClickRepository struct {
+	Repository
+
+	mu     sync.RWMutex // guards closed, so no click is sent on the closed channel
+	closed bool
+	clicks chan queuedClick
+	done   chan struct{} // closed once the queue has been drained
+}
+
+// queuedClick keeps what identifies the redirect a click comes from, so the write
+// shows up in its trace and its logs even though it happens after the redirect is done.
+type queuedClick struct {
+	click     models.Click
+	span      trace.SpanContext
+	requestID string
+}
+
+// NewAsyncClickRepository queues up to buffer clicks in front of repo.
+func NewAsyncClickRepository(repo Repository, buffer int) *AsyncClickRepository {
+	r := &AsyncClickRepository{
+		Repository: repo,
+		clicks:     make(chan queuedClick, buffer),
+		done:       make(chan struct{}),
+	}
+	go r.run()
+	return r
+}
+
+func (r *AsyncClickRepository) run() {
+	defer close(r.done)
+
+	for queued := range r.clicks {
+		ctx := trace.ContextWithSpanContext(context.Background(), queued.span)
+		r.write(logging.WithRequestID(ctx, queued.requestID), queued.click)
+	}
+}
+
+// write stores a click, logging failures: analytics should never stop a visitor from being redirected.
+func (r *AsyncClickRepository) write(ctx context.Context, click models.Click) {
+	if err := r.Repository.CreateClick(ctx, click); err != nil {
+		slog.ErrorContext(ctx, "Failed to record click", "code", click.Code, "error", err)
+	}
+}
+
+// CreateClick queues the click. It only fails when it has to write the click itself and the write fails.
+func (r *AsyncClickRepository) CreateClick(ctx context.Context, click models.Click) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if !r.closed {
+		select {
+		case r.clicks <- queuedClick{click, trace.SpanContextFromContext(ctx), logging.RequestID(ctx)}:
+			return nil
+		default:
+		}
+	}
+
+	return r.Repository.CreateClick(ctx, click)
+}
+
+// Close writes the clicks still in the buffer, then closes the wrapped repository.
+// If ctx ends first, the clicks that are left are lost.
+func (r *AsyncClickRepository) Close(ctx context.Context) error {
+	r.mu.Lock()
+	if !r.closed {
+		r.closed = true
+		close(r.clicks)
+	}
+	r.mu.Unlock()
+
+	select {
+	case <-r.done:
+	case <-ctx.Done():
+		slog.Error("Gave up on writing the queued clicks", "clicks", len(r.clicks), "error", ctx.Err())
+	}
+
+	return r.Repository.Close(ctx)
+}
@@ -0,0 +1,64 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/topboyasante/trunc8/internal/models"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestAsyncClickRepository_CloseFlushesClicks(t *testing.T) {
+	ctx := context.Background()
+	memory := NewMemoryRepository()
+	repo := NewAsyncClickRepository(memory, 10)
+
+	now := time.Now()
+	// More clicks than the buffer holds, the ones that don't fit are written right away
+	for i := 0; i < 25; i++ {
+		if err := repo.CreateClick(ctx, models.Click{Code: "abc", Timestamp: now}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	if err := repo.Close(ctx); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	stats, _ := memory.ClickStats(ctx, models.StatsQuery{Code: "abc", From: now, To: now.Add(time.Second), Interval: "hour"})
+	if stats.TotalClicks != 25 {
+		t.Errorf("Expected 25 clicks after Close, got %d", stats.TotalClicks)
+	}
+
+	// Clicks arriving after Close are written right away instead of panicking on the closed queue
+	if err := repo.CreateClick(ctx, models.Click{Code: "abc", Timestamp: now}); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
+
+func TestAsyncClickRepository_KeepsTrace(t *testing.T) {
+	spans, tracer := recordSpans(t)
+	repo := NewAsyncClickRepository(NewInstrumentedRepository(NewMemoryRepository(), &mockStorageObserver{}), 10)
+
+	ctx, redirect := tracer.Start(context.Background(), "GET /{code}")
+	if err := repo.CreateClick(ctx, models.Click{Code: "abc", Timestamp: time.Now()}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	redirect.End()
+	repo.Close(context.Background())
+
+	// The write happens in the background, after the redirect, and still belongs to its trace
+	var write sdktrace.ReadOnlySpan
+	for _, span := range spans.Ended() {
+		if span.Name() == "storage.create_click" {
+			write = span
+		}
+	}
+	if write == nil {
+		t.Fatal("Expected a span for the click write")
+	}
+	if write.Parent().SpanID() != redirect.SpanContext().SpanID() {
+		t.Errorf("Expected the write under the redirect span, got parent %s", write.Parent().SpanID())
+	}
+}
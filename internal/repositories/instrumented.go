@@ -0,0 +1,265 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/topboyasante/trunc8/internal/models"
+	"github.com/topboyasante/trunc8/internal/tracing"
+	"go.opentelemetry.io/otel"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// StorageObserver is told about every call made to the storage
+type StorageObserver interface {
+	ObserveStorage(operation string, duration time.Duration, failed bool)
+}
+
+const tracerName = "github.com/topboyasante/trunc8/internal/repositories"
+
+// InstrumentedRepository times and traces every call to the wrapped repository.
+// It sits right on top of the backend, under the cache and the click queue, so what it measures
+// is the storage itself.
+type InstrumentedRepository struct {
+	repo     Repository
+	observer StorageObserver
+	backend  string
+}
+
+func NewInstrumentedRepository(repo Repository, observer StorageObserver) *InstrumentedRepository {
+	return &InstrumentedRepository{
+		repo:     repo,
+		observer: observer,
+		backend:  backendName(repo),
+	}
+}
+
+// backendName is the name of the database behind repo, as OpenTelemetry spells it.
+func backendName(repo Repository) string {
+	switch repo.(type) {
+	case *MongoRepository:
+		return semconv.DBSystemNameMongoDB.Value.AsString()
+	case *PostgresRepository:
+		return semconv.DBSystemNamePostgreSQL.Value.AsString()
+	case *BoltRepository:
+		return "bolt"
+	case *MemoryRepository:
+		return "memory"
+	default:
+		return "other"
+	}
+}
+
+// start starts a span for operation under the one in ctx. The returned function ends it,
+// and reports how long the call took and whether it failed. Errors like ErrNotFound
+// are answers from the storage, not failures of it.
+func (r *InstrumentedRepository) start(ctx context.Context, operation string) (context.Context, func(error)) {
+	begin := time.Now()
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "storage."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameKey.String(r.backend),
+			semconv.DBOperationName(operation),
+		),
+	)
+
+	return ctx, func(err error) {
+		r.observer.ObserveStorage(operation, time.Since(begin), err != nil && !models.IsDomainError(err))
+		tracing.End(span, err)
+	}
+}
+
+func (r *InstrumentedRepository) Create(ctx context.Context, url models.URL) (string, error) {
+	ctx, done := r.start(ctx, "create")
+	v, err := r.repo.Create(ctx, url)
+	done(err)
+	return v, err
+}
+
+// CreateMany is reported as one call, failed only when the whole batch failed
+func (r *InstrumentedRepository) CreateMany(ctx context.Context, urls []models.URL) ([]string, []error) {
+	ctx, done := r.start(ctx, "create_many")
+	ids, errs := r.repo.CreateMany(ctx, urls)
+	done(batchError(errs))
+	return ids, errs
+}
+
+// batchError is the error every link of a batch failed with, or nil when at least one of them was stored.
+func batchError(errs []error) error {
+	for _, err := range errs {
+		if err == nil {
+			return nil
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs[0]
+}
+
+func (r *InstrumentedRepository) FindOne(ctx context.Context, code string) (*models.URL, error) {
+	ctx, done := r.start(ctx, "find_one")
+	v, err := r.repo.FindOne(ctx, code)
+	done(err)
+	return v, err
+}
+
+func (r *InstrumentedRepository) FindInWorkspace(ctx context.Context, workspaceID, code string) (*models.URL, error) {
+	ctx, done := r.start(ctx, "find_in_workspace")
+	v, err := r.repo.FindInWorkspace(ctx, workspaceID, code)
+	done(err)
+	return v, err
+}
+
+func (r *InstrumentedRepository) FindByOriginalURL(ctx context.Context, workspaceID, originalURL string) (*models.URL, error) {
+	ctx, done := r.start(ctx, "find_by_original_url")
+	v, err := r.repo.FindByOriginalURL(ctx, workspaceID, originalURL)
+	done(err)
+	return v, err
+}
+
+func (r *InstrumentedRepository) Update(ctx context.Context, workspaceID, code string, update models.LinkUpdate) (*models.URL, error) {
+	ctx, done := r.start(ctx, "update")
+	v, err := r.repo.Update(ctx, workspaceID, code, update)
+	done(err)
+	return v, err
+}
+
+func (r *InstrumentedRepository) Delete(ctx context.Context, workspaceID, code string) error {
+	ctx, done := r.start(ctx, "delete")
+	err := r.repo.Delete(ctx, workspaceID, code)
+	done(err)
+	return err
+}
+
+func (r *InstrumentedRepository) List(ctx context.Context, query models.ListQuery) ([]*models.URL, error) {
+	ctx, done := r.start(ctx, "list")
+	v, err := r.repo.List(ctx, query)
+	done(err)
+	return v, err
+}
+
+func (r *InstrumentedRepository) IncrementClickCount(ctx context.Context, code string) error {
+	ctx, done := r.start(ctx, "increment_click_count")
+	err := r.repo.IncrementClickCount(ctx, code)
+	done(err)
+	return err
+}
+
+func (r *InstrumentedRepository) NextSequence(ctx context.Context, name string) (int64, error) {
+	ctx, done := r.start(ctx, "next_sequence")
+	v, err := r.repo.NextSequence(ctx, name)
+	done(err)
+	return v, err
+}
+
+func (r *InstrumentedRepository) RaiseSequence(ctx context.Context, name string, value int64) (int64, error) {
+	ctx, done := r.start(ctx, "raise_sequence")
+	v, err := r.repo.RaiseSequence(ctx, name, value)
+	done(err)
+	return v, err
+}
+
+func (r *InstrumentedRepository) CreateClick(ctx context.Context, click models.Click) error {
+	ctx, done := r.start(ctx, "create_click")
+	err := r.repo.CreateClick(ctx, click)
+	done(err)
+	return err
+}
+
+func (r *InstrumentedRepository) ClickStats(ctx context.Context, query models.StatsQuery) (*models.LinkStats, error) {
+	ctx, done := r.start(ctx, "click_stats")
+	v, err := r.repo.ClickStats(ctx, query)
+	done(err)
+	return v, err
+}
+
+func (r *InstrumentedRepository) CreateAPIKey(ctx context.Context, key models.APIKey) (string, error) {
+	ctx, done := r.start(ctx, "create_api_key")
+	v, err := r.repo.CreateAPIKey(ctx, key)
+	done(err)
+	return v, err
+}
+
+func (r *InstrumentedRepository) FindAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	ctx, done := r.start(ctx, "find_api_key_by_hash")
+	v, err := r.repo.FindAPIKeyByHash(ctx, hash)
+	done(err)
+	return v, err
+}
+
+func (r *InstrumentedRepository) ListAPIKeys(ctx context.Context) ([]*models.APIKey, error) {
+	ctx, done := r.start(ctx, "list_api_keys")
+	v, err := r.repo.ListAPIKeys(ctx)
+	done(err)
+	return v, err
+}
+
+func (r *InstrumentedRepository) DeleteAPIKey(ctx context.Context, id string) error {
+	ctx, done := r.start(ctx, "delete_api_key")
+	err := r.repo.DeleteAPIKey(ctx, id)
+	done(err)
+	return err
+}
+
+func (r *InstrumentedRepository) CreateWorkspace(ctx context.Context, workspace models.Workspace) (string, error) {
+	ctx, done := r.start(ctx, "create_workspace")
+	v, err := r.repo.CreateWorkspace(ctx, workspace)
+	done(err)
+	return v, err
+}
+
+func (r *InstrumentedRepository) FindWorkspace(ctx context.Context, id string) (*models.Workspace, error) {
+	ctx, done := r.start(ctx, "find_workspace")
+	v, err := r.repo.FindWorkspace(ctx, id)
+	done(err)
+	return v, err
+}
+
+func (r *InstrumentedRepository) ListWorkspaces(ctx context.Context) ([]*models.Workspace, error) {
+	ctx, done := r.start(ctx, "list_workspaces")
+	v, err := r.repo.ListWorkspaces(ctx)
+	done(err)
+	return v, err
+}
+
+func (r *InstrumentedRepository) CreateUser(ctx context.Context, user models.User) (string, error) {
+	ctx, done := r.start(ctx, "create_user")
+	v, err := r.repo.CreateUser(ctx, user)
+	done(err)
+	return v, err
+}
+
+func (r *InstrumentedRepository) FindUser(ctx context.Context, id string) (*models.User, error) {
+	ctx, done := r.start(ctx, "find_user")
+	v, err := r.repo.FindUser(ctx, id)
+	done(err)
+	return v, err
+}
+
+func (r *InstrumentedRepository) ListUsers(ctx context.Context, workspaceID string) ([]*models.User, error) {
+	ctx, done := r.start(ctx, "list_users")
+	v, err := r.repo.ListUsers(ctx, workspaceID)
+	done(err)
+	return v, err
+}
+
+func (r *InstrumentedRepository) Ping(ctx context.Context) error {
+	ctx, done := r.start(ctx, "ping")
+	err := r.repo.Ping(ctx)
+	done(err)
+	return err
+}
+
+func (r *InstrumentedRepository) Migrations(ctx context.Context) (*models.MigrationState, error) {
+	ctx, done := r.start(ctx, "migrations")
+	v, err := r.repo.Migrations(ctx)
+	done(err)
+	return v, err
+}
+
+// Close isn't timed: it only happens once, on shutdown.
+func (r *InstrumentedRepository) Close(ctx context.Context) error {
+	return r.repo.Close(ctx)
+}
@@ -0,0 +1,102 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/topboyasante/trunc8/internal/models"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type observedOperation struct {
+	operation string
+	failed    bool
+}
+
+type mockStorageObserver struct {
+	operations []observedOperation
+}
+
+func (m *mockStorageObserver) ObserveStorage(operation string, duration time.Duration, failed bool) {
+	m.operations = append(m.operations, observedOperation{operation, failed})
+}
+
+// unreachableRepository is a backend whose server went away
+type unreachableRepository struct {
+	*MemoryRepository
+}
+
+func (unreachableRepository) Ping(ctx context.Context) error {
+	return errors.New("connection refused")
+}
+
+func TestInstrumentedRepository(t *testing.T) {
+	ctx := context.Background()
+	observer := &mockStorageObserver{}
+	repo := NewInstrumentedRepository(unreachableRepository{NewMemoryRepository()}, observer)
+
+	if _, err := repo.Create(ctx, models.URL{Code: "abc", OriginalURL: "https://example.com"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := repo.FindOne(ctx, "nope"); !errors.Is(err, models.ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound, got %v", err)
+	}
+	if err := repo.Ping(ctx); err == nil {
+		t.Fatal("Expected an error")
+	}
+
+	expected := []observedOperation{
+		{"create", false},
+		// Finding nothing is an answer, the storage didn't fail
+		{"find_one", false},
+		{"ping", true},
+	}
+	if len(observer.operations) != len(expected) {
+		t.Fatalf("Expected %d operations, got %d", len(expected), len(observer.operations))
+	}
+	for i, want := range expected {
+		if observer.operations[i] != want {
+			t.Errorf("Expected %+v, got %+v", want, observer.operations[i])
+		}
+	}
+}
+
+// recordSpans installs a tracer provider that keeps the spans in memory until the test ends
+func recordSpans(t *testing.T) (*tracetest.SpanRecorder, trace.Tracer) {
+	t.Helper()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	return recorder, provider.Tracer("test")
+}
+
+func TestInstrumentedRepository_Spans(t *testing.T) {
+	spans, tracer := recordSpans(t)
+	repo := NewInstrumentedRepository(NewMemoryRepository(), &mockStorageObserver{})
+
+	ctx, parent := tracer.Start(context.Background(), "ShortnerService.RedirectURL")
+	repo.FindOne(ctx, "nope")
+	parent.End()
+
+	span := spans.Ended()[0]
+	if span.Name() != "storage.find_one" || span.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("Expected storage.find_one under the service span, got %s under %s", span.Name(), span.Parent().SpanID())
+	}
+	expected := []attribute.KeyValue{semconv.DBSystemNameKey.String("memory"), semconv.DBOperationName("find_one")}
+	for i, attr := range expected {
+		if span.Attributes()[i] != attr {
+			t.Errorf("Expected attribute %v, got %v", attr, span.Attributes()[i])
+		}
+	}
+}
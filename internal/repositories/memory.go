@@ -0,0 +1,347 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/topboyasante/trunc8/internal/models"
+)
+
+// MemoryRepository keeps everything in memory, so the server can run without a database.
+// Nothing survives a restart, which makes it a good fit for development and tests.
+// It behaves like MongoRepository, down to the errors it returns.
+type MemoryRepository struct {
+	mu sync.RWMutex
+
+	links      map[string]*models.URL // by code
+	clicks     []models.Click
+	counters   map[string]int64
+	keys       map[string]*models.APIKey // by ID
+	workspaces map[string]*models.Workspace
+	users      map[string]*models.User
+
+	lastID int64
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		links:      map[string]*models.URL{},
+		counters:   map[string]int64{},
+		keys:       map[string]*models.APIKey{},
+		workspaces: map[string]*models.Workspace{},
+		users:      map[string]*models.User{},
+	}
+}
+
+// newID returns the next sequential ID. The caller must hold the write lock.
+func (r *MemoryRepository) newID() string {
+	r.lastID++
+	return sequenceID(uint64(r.lastID))
+}
+
+func (r *MemoryRepository) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (r *MemoryRepository) Migrations(ctx context.Context) (*models.MigrationState, error) {
+	return noMigrations(), nil
+}
+
+func (r *MemoryRepository) Close(ctx context.Context) error {
+	return nil
+}
+
+func (r *MemoryRepository) Create(ctx context.Context, url models.URL) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.links[url.Code]; ok {
+		return "", fmt.Errorf("%w: %s", models.ErrDuplicateCode, url.Code)
+	}
+
+	url.ID = r.newID()
+	r.links[url.Code] = copyURL(&url)
+	return url.ID, nil
+}
+
+func (r *MemoryRepository) CreateMany(ctx context.Context, urls []models.URL) ([]string, []error) {
+	ids := make([]string, len(urls))
+	errs := make([]error, len(urls))
+	for i, url := range urls {
+		ids[i], errs[i] = r.Create(ctx, url)
+	}
+	return ids, errs
+}
+
+func (r *MemoryRepository) FindOne(ctx context.Context, code string) (*models.URL, error) {
+	return r.FindInWorkspace(ctx, "", code)
+}
+
+// FindInWorkspace works like FindOne, but only finds links of the given workspace.
+// An empty workspaceID finds links of every workspace.
+func (r *MemoryRepository) FindInWorkspace(ctx context.Context, workspaceID, code string) (*models.URL, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	url, err := r.findLink(workspaceID, code)
+	if err != nil {
+		return nil, err
+	}
+	return copyURL(url), nil
+}
+
+// findLink returns the stored link, not a copy. The caller must hold the lock.
+func (r *MemoryRepository) findLink(workspaceID, code string) (*models.URL, error) {
+	url, ok := r.links[code]
+	if !ok || (workspaceID != "" && url.WorkspaceID != workspaceID) {
+		return nil, fmt.Errorf("%w: %s", models.ErrNotFound, code)
+	}
+	return url, nil
+}
+
+// FindByOriginalURL returns the oldest plain link to originalURL in the given workspace.
+// An empty workspaceID only matches links without a workspace, see ShortenerRepository.FindByOriginalURL.
+func (r *MemoryRepository) FindByOriginalURL(ctx context.Context, workspaceID, originalURL string) (*models.URL, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var found *models.URL
+	for _, url := range r.links {
+		if url.WorkspaceID != workspaceID || url.OriginalURL != originalURL || !isPlainLink(url) {
+			continue
+		}
+		if found == nil || url.ID < found.ID {
+			found = url
+		}
+	}
+
+	if found == nil {
+		return nil, fmt.Errorf("%w: %s", models.ErrNotFound, originalURL)
+	}
+	return copyURL(found), nil
+}
+
+func (r *MemoryRepository) Update(ctx context.Context, workspaceID, code string, update models.LinkUpdate) (*models.URL, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	url, err := r.findLink(workspaceID, code)
+	if err != nil {
+		return nil, err
+	}
+
+	applyUpdate(url, update)
+	return copyURL(url), nil
+}
+
+// Delete removes the link with the given code along with its click events.
+func (r *MemoryRepository) Delete(ctx context.Context, workspaceID, code string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, err := r.findLink(workspaceID, code); err != nil {
+		return err
+	}
+	delete(r.links, code)
+
+	clicks := r.clicks[:0]
+	for _, click := range r.clicks {
+		if click.Code != code {
+			clicks = append(clicks, click)
+		}
+	}
+	r.clicks = clicks
+
+	return nil
+}
+
+func (r *MemoryRepository) List(ctx context.Context, query models.ListQuery) ([]*models.URL, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	links := make([]*models.URL, 0, len(r.links))
+	for _, url := range r.links {
+		links = append(links, url)
+	}
+	return listLinks(links, query), nil
+}
+
+// IncrementClickCount bumps the click counter of a link by one, unless it has no clicks left.
+// Like the Mongo backend, it returns ErrExpired when the click can't be counted.
+func (r *MemoryRepository) IncrementClickCount(ctx context.Context, code string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	url, ok := r.links[code]
+	if !ok || !hasClicksLeft(url) {
+		return fmt.Errorf("%w: %s has no clicks left", models.ErrExpired, code)
+	}
+
+	url.ClickCount++
+	return nil
+}
+
+func (r *MemoryRepository) CreateClick(ctx context.Context, click models.Click) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	click.ID = r.newID()
+	r.clicks = append(r.clicks, click)
+	return nil
+}
+
+func (r *MemoryRepository) ClickStats(ctx context.Context, query models.StatsQuery) (*models.LinkStats, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return clickStats(r.clicks, query), nil
+}
+
+func (r *MemoryRepository) NextSequence(ctx context.Context, name string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.counters[name]++
+	return r.counters[name], nil
+}
+
+func (r *MemoryRepository) RaiseSequence(ctx context.Context, name string, value int64) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.counters[name] = max(r.counters[name], value)
+	return r.counters[name], nil
+}
+
+func (r *MemoryRepository) CreateAPIKey(ctx context.Context, key models.APIKey) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.keys {
+		if existing.KeyHash == key.KeyHash {
+			return "", fmt.Errorf("an API key with hash %s already exists", key.KeyHash)
+		}
+	}
+
+	key.ID = r.newID()
+	r.keys[key.ID] = &key
+	return key.ID, nil
+}
+
+func (r *MemoryRepository) FindAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, key := range r.keys {
+		if key.KeyHash == hash {
+			found := *key
+			return &found, nil
+		}
+	}
+	return nil, models.ErrAPIKeyNotFound
+}
+
+// ListAPIKeys returns every key, oldest first.
+func (r *MemoryRepository) ListAPIKeys(ctx context.Context) ([]*models.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var keys []*models.APIKey
+	for _, key := range r.keys {
+		found := *key
+		keys = append(keys, &found)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+	return keys, nil
+}
+
+func (r *MemoryRepository) DeleteAPIKey(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.keys[id]; !ok {
+		return fmt.Errorf("%w: %s", models.ErrAPIKeyNotFound, id)
+	}
+	delete(r.keys, id)
+	return nil
+}
+
+func (r *MemoryRepository) CreateWorkspace(ctx context.Context, workspace models.Workspace) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	workspace.ID = r.newID()
+	r.workspaces[workspace.ID] = &workspace
+	return workspace.ID, nil
+}
+
+func (r *MemoryRepository) FindWorkspace(ctx context.Context, id string) (*models.Workspace, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	workspace, ok := r.workspaces[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", models.ErrWorkspaceNotFound, id)
+	}
+	found := *workspace
+	return &found, nil
+}
+
+// ListWorkspaces returns every workspace, oldest first.
+func (r *MemoryRepository) ListWorkspaces(ctx context.Context) ([]*models.Workspace, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var workspaces []*models.Workspace
+	for _, workspace := range r.workspaces {
+		found := *workspace
+		workspaces = append(workspaces, &found)
+	}
+	sort.Slice(workspaces, func(i, j int) bool { return workspaces[i].ID < workspaces[j].ID })
+	return workspaces, nil
+}
+
+func (r *MemoryRepository) CreateUser(ctx context.Context, user models.User) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.users {
+		if existing.Email == user.Email {
+			return "", fmt.Errorf("%w: %s", models.ErrEmailTaken, user.Email)
+		}
+	}
+
+	user.ID = r.newID()
+	r.users[user.ID] = &user
+	return user.ID, nil
+}
+
+func (r *MemoryRepository) FindUser(ctx context.Context, id string) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", models.ErrUserNotFound, id)
+	}
+	found := *user
+	return &found, nil
+}
+
+// ListUsers returns the members of a workspace, oldest first.
+func (r *MemoryRepository) ListUsers(ctx context.Context, workspaceID string) ([]*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var users []*models.User
+	for _, user := range r.users {
+		if user.WorkspaceID == workspaceID {
+			found := *user
+			users = append(users, &found)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users, nil
+}
@@ -0,0 +1,320 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/topboyasante/trunc8/internal/config"
+	"github.com/topboyasante/trunc8/internal/models"
+)
+
+func TestNew(t *testing.T) {
+	repo, err := New(context.Background(), &config.Config{Database: config.DatabaseConfig{Url: "memory://"}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, ok := repo.(*MemoryRepository); !ok {
+		t.Errorf("Expected a MemoryRepository, got %T", repo)
+	}
+
+	_, err = New(context.Background(), &config.Config{Database: config.DatabaseConfig{Url: "redis://localhost:6379"}})
+	if err == nil {
+		t.Error("Expected an error for an unsupported scheme")
+	}
+}
+
+func TestMemoryRepository_Create(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+
+	id, err := repo.Create(ctx, models.URL{Code: "abc", OriginalURL: "https://example.com"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(id) != 24 {
+		t.Errorf("Expected a 24 character ID, got %q", id)
+	}
+
+	_, err = repo.Create(ctx, models.URL{Code: "abc", OriginalURL: "https://example.org"})
+	if !errors.Is(err, models.ErrDuplicateCode) {
+		t.Fatalf("Expected ErrDuplicateCode, got %v", err)
+	}
+
+	url, err := repo.FindOne(ctx, "abc")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if url.ID != id || url.OriginalURL != "https://example.com" {
+		t.Errorf("Unexpected link: %+v", url)
+	}
+}
+
+func TestMemoryRepository_FindInWorkspace(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+
+	repo.Create(ctx, models.URL{Code: "abc", WorkspaceID: "ws-1"})
+
+	if _, err := repo.FindInWorkspace(ctx, "ws-1", "abc"); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if _, err := repo.FindInWorkspace(ctx, "", "abc"); err != nil {
+		t.Errorf("Expected no error for every workspace, got %v", err)
+	}
+	if _, err := repo.FindInWorkspace(ctx, "ws-2", "abc"); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestMemoryRepository_FindByOriginalURL(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+
+	repo.Create(ctx, models.URL{Code: "tagged", OriginalURL: "https://example.com", Tags: []string{"promo"}})
+	repo.Create(ctx, models.URL{Code: "plain", OriginalURL: "https://example.com"})
+	repo.Create(ctx, models.URL{Code: "other", OriginalURL: "https://example.com", WorkspaceID: "ws-1"})
+
+	url, err := repo.FindByOriginalURL(ctx, "", "https://example.com")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if url.Code != "plain" {
+		t.Errorf("Expected the plain link, got %q", url.Code)
+	}
+
+	url, err = repo.FindByOriginalURL(ctx, "ws-1", "https://example.com")
+	if err != nil || url.Code != "other" {
+		t.Errorf("Expected the link of the workspace, got %+v, %v", url, err)
+	}
+
+	if _, err := repo.FindByOriginalURL(ctx, "ws-2", "https://example.com"); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestMemoryRepository_Update(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+
+	expiresAt := time.Now().Add(time.Hour)
+	repo.Create(ctx, models.URL{Code: "abc", MaxClicks: 5, ExpiresAt: &expiresAt, Tags: []string{"a"}})
+
+	noLimit := 0
+	disabled := true
+	url, err := repo.Update(ctx, "", "abc", models.LinkUpdate{
+		MaxClicks:    &noLimit,
+		RemoveExpiry: true,
+		Disabled:     &disabled,
+		Tags:         []string{},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if url.MaxClicks != 0 || url.ExpiresAt != nil || !url.Disabled || url.Tags != nil {
+		t.Errorf("Unexpected link after update: %+v", url)
+	}
+
+	if _, err := repo.Update(ctx, "ws-1", "abc", models.LinkUpdate{}); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for another workspace, got %v", err)
+	}
+}
+
+func TestMemoryRepository_Delete(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+
+	now := time.Now()
+	repo.Create(ctx, models.URL{Code: "abc"})
+	repo.CreateClick(ctx, models.Click{Code: "abc", Timestamp: now})
+	repo.CreateClick(ctx, models.Click{Code: "xyz", Timestamp: now})
+
+	if err := repo.Delete(ctx, "", "abc"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := repo.Delete(ctx, "", "abc"); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	// A new link with the same code starts without clicks
+	repo.Create(ctx, models.URL{Code: "abc"})
+	stats, _ := repo.ClickStats(ctx, models.StatsQuery{Code: "abc", From: now.Add(-time.Hour), To: now.Add(time.Hour), Interval: "day"})
+	if stats.TotalClicks != 0 {
+		t.Errorf("Expected no clicks, got %d", stats.TotalClicks)
+	}
+	if len(repo.clicks) != 1 {
+		t.Errorf("Expected the clicks of other links to be kept, got %d clicks", len(repo.clicks))
+	}
+}
+
+func TestMemoryRepository_IncrementClickCount(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+
+	repo.Create(ctx, models.URL{Code: "abc", MaxClicks: 1})
+
+	if err := repo.IncrementClickCount(ctx, "abc"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := repo.IncrementClickCount(ctx, "abc"); !errors.Is(err, models.ErrExpired) {
+		t.Errorf("Expected ErrExpired, got %v", err)
+	}
+
+	url, _ := repo.FindOne(ctx, "abc")
+	if url.ClickCount != 1 {
+		t.Errorf("Expected 1 click, got %d", url.ClickCount)
+	}
+}
+
+func TestMemoryRepository_List(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+	links := []models.URL{
+		{Code: "a", OriginalURL: "https://Example.com/a", CreatedAt: now.Add(1 * time.Minute), Tags: []string{"promo"}},
+		{Code: "b", OriginalURL: "https://example.com/b", CreatedAt: now.Add(2 * time.Minute), Disabled: true},
+		{Code: "c", OriginalURL: "https://example.com/c", CreatedAt: now.Add(3 * time.Minute), ExpiresAt: &past},
+		{Code: "d", OriginalURL: "https://other.com/d", CreatedAt: now.Add(4 * time.Minute), MaxClicks: 2, ClickCount: 2},
+		{Code: "e", OriginalURL: "https://example.com/e", CreatedAt: now.Add(5 * time.Minute), WorkspaceID: "ws-1"},
+	}
+	for _, link := range links {
+		repo.Create(ctx, link)
+	}
+
+	tests := []struct {
+		name  string
+		query models.ListQuery
+		want  []string
+	}{
+		{"newest first", models.ListQuery{Descending: true}, []string{"e", "d", "c", "b", "a"}},
+		{"workspace", models.ListQuery{WorkspaceID: "ws-1"}, []string{"e"}},
+		{"tag", models.ListQuery{Tag: "promo"}, []string{"a"}},
+		{"search", models.ListQuery{Search: "EXAMPLE.COM"}, []string{"a", "b", "c", "e"}},
+		{"active", models.ListQuery{Status: models.StatusActive}, []string{"a", "e"}},
+		{"expired", models.ListQuery{Status: models.StatusExpired}, []string{"c", "d"}},
+		{"disabled", models.ListQuery{Status: models.StatusDisabled}, []string{"b"}},
+		{"most clicked", models.ListQuery{Sort: models.SortClicks, Descending: true, Limit: 1}, []string{"d"}},
+		{"after cursor", models.ListQuery{Limit: 2, After: &models.ListCursor{CreatedAt: now.Add(2 * time.Minute), ID: "000000000000000000000002"}}, []string{"c", "d"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.query.Now = now
+
+			got, err := repo.List(ctx, tt.query)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			var codes []string
+			for _, link := range got {
+				codes = append(codes, link.Code)
+			}
+			if len(codes) != len(tt.want) {
+				t.Fatalf("Expected %v, got %v", tt.want, codes)
+			}
+			for i := range codes {
+				if codes[i] != tt.want[i] {
+					t.Fatalf("Expected %v, got %v", tt.want, codes)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryRepository_ClickStats(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+
+	day := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	clicks := []models.Click{
+		{Code: "abc", Timestamp: day.Add(time.Hour), Referrer: "https://news.ycombinator.com", Browser: "Chrome", IPHash: "1"},
+		{Code: "abc", Timestamp: day.Add(2 * time.Hour), Browser: "Chrome", IPHash: "1"},
+		{Code: "abc", Timestamp: day.Add(26 * time.Hour), IPHash: "2"},
+		{Code: "abc", Timestamp: day.Add(72 * time.Hour)}, // outside the period
+		{Code: "xyz", Timestamp: day.Add(time.Hour)},
+	}
+	for _, click := range clicks {
+		repo.CreateClick(ctx, click)
+	}
+
+	stats, err := repo.ClickStats(ctx, models.StatsQuery{
+		Code:     "abc",
+		From:     day,
+		To:       day.Add(48 * time.Hour),
+		Interval: "day",
+		Limit:    10,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if stats.TotalClicks != 3 {
+		t.Errorf("Expected 3 clicks, got %d", stats.TotalClicks)
+	}
+	if stats.UniqueVisitors != 2 {
+		t.Errorf("Expected 2 unique visitors, got %d", stats.UniqueVisitors)
+	}
+	if len(stats.Series) != 2 || !stats.Series[0].Time.Equal(day) || stats.Series[0].Clicks != 2 {
+		t.Errorf("Unexpected series: %+v", stats.Series)
+	}
+	if len(stats.TopReferrers) != 2 || stats.TopReferrers[0] != (models.StatsCount{Value: "(direct)", Clicks: 2}) {
+		t.Errorf("Unexpected referrers: %+v", stats.TopReferrers)
+	}
+	if len(stats.TopUserAgents) != 2 || stats.TopUserAgents[1] != (models.StatsCount{Value: "Unknown", Clicks: 1}) {
+		t.Errorf("Unexpected user agents: %+v", stats.TopUserAgents)
+	}
+}
+
+func TestMemoryRepository_Users(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+
+	workspaceID, _ := repo.CreateWorkspace(ctx, models.Workspace{Name: "Acme"})
+
+	if _, err := repo.CreateUser(ctx, models.User{WorkspaceID: workspaceID, Email: "ama@example.com"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := repo.CreateUser(ctx, models.User{WorkspaceID: workspaceID, Email: "ama@example.com"}); !errors.Is(err, models.ErrEmailTaken) {
+		t.Errorf("Expected ErrEmailTaken, got %v", err)
+	}
+
+	users, _ := repo.ListUsers(ctx, workspaceID)
+	if len(users) != 1 {
+		t.Errorf("Expected 1 user, got %d", len(users))
+	}
+
+	if _, err := repo.FindWorkspace(ctx, "missing"); !errors.Is(err, models.ErrWorkspaceNotFound) {
+		t.Errorf("Expected ErrWorkspaceNotFound, got %v", err)
+	}
+}
+
+func TestMemoryRepository_CreateMany(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository()
+	repo.Create(ctx, models.URL{Code: "taken", OriginalURL: "https://example.com"})
+
+	ids, errs := repo.CreateMany(ctx, []models.URL{
+		{Code: "one", OriginalURL: "https://example.com/1"},
+		{Code: "taken", OriginalURL: "https://example.com/2"},
+		{Code: "two", OriginalURL: "https://example.com/3"},
+		{Code: "one", OriginalURL: "https://example.com/4"},
+	})
+
+	if errs[0] != nil || errs[2] != nil || ids[0] == "" || ids[2] == "" {
+		t.Fatalf("Expected the free codes to be stored, got ids %v and errors %v", ids, errs)
+	}
+	for _, i := range []int{1, 3} {
+		if !errors.Is(errs[i], models.ErrDuplicateCode) || ids[i] != "" {
+			t.Errorf("Expected ErrDuplicateCode for link %d, got %v", i, errs[i])
+		}
+	}
+
+	url, _ := repo.FindOne(ctx, "one")
+	if url.OriginalURL != "https://example.com/1" {
+		t.Errorf("Expected the first link with the code to win, got %q", url.OriginalURL)
+	}
+}
@@ -0,0 +1,74 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/topboyasante/trunc8/internal/database"
+	"github.com/topboyasante/trunc8/internal/models"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/connstring"
+)
+
+// MongoRepository is the MongoDB backend. Each group of collections keeps its own repository,
+// embedded here so together they implement Repository.
+type MongoRepository struct {
+	*ShortenerRepository
+	*APIKeyRepository
+	*WorkspaceRepository
+
+	client *mongo.Client
+}
+
+// NewMongoRepository connects to MongoDB and creates the indexes of every collection.
+// The database named in the URI is used, or name when the URI doesn't have one.
+func NewMongoRepository(ctx context.Context, uri, name string) (*MongoRepository, error) {
+	cs, err := connstring.ParseAndValidate(uri)
+	if err != nil {
+		return nil, fmt.Errorf("parsing DATABASE_URL: %w", err)
+	}
+	if cs.Database != "" {
+		name = cs.Database
+	}
+
+	client, err := database.ConnectToMongo(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+
+	db := client.Database(name)
+	repo := &MongoRepository{
+		ShortenerRepository: NewShortnerRepository(db),
+		APIKeyRepository:    NewAPIKeyRepository(db),
+		WorkspaceRepository: NewWorkspaceRepository(db),
+		client:              client,
+	}
+
+	// The unique indexes have to exist before we accept any links, keys or users
+	for _, ensure := range []func(context.Context) error{
+		repo.ShortenerRepository.EnsureIndexes,
+		repo.APIKeyRepository.EnsureIndexes,
+		repo.WorkspaceRepository.EnsureIndexes,
+	} {
+		if err := ensure(ctx); err != nil {
+			client.Disconnect(ctx)
+			return nil, err
+		}
+	}
+
+	return repo, nil
+}
+
+func (r *MongoRepository) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx, nil)
+}
+
+// Migrations reports none: collections have no schema, and their indexes are created on startup.
+func (r *MongoRepository) Migrations(ctx context.Context) (*models.MigrationState, error) {
+	return noMigrations(), nil
+}
+
+// Close disconnects from MongoDB.
+func (r *MongoRepository) Close(ctx context.Context) error {
+	return r.client.Disconnect(ctx)
+}
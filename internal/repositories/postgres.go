@@ -0,0 +1,785 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/topboyasante/trunc8/internal/database"
+	"github.com/topboyasante/trunc8/internal/models"
+)
+
+// PostgresRepository is the Postgres backend. The schema lives in the migrations of the database package.
+// IDs are identity columns, handed out to the rest of the app as decimal strings.
+type PostgresRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresRepository connects to Postgres and brings the schema up to date.
+func NewPostgresRepository(ctx context.Context, url string) (*PostgresRepository, error) {
+	db, err := database.ConnectToPostgres(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := database.Migrate(ctx, db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrating Postgres: %w", err)
+	}
+
+	return &PostgresRepository{db: db}, nil
+}
+
+func (r *PostgresRepository) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+func (r *PostgresRepository) Migrations(ctx context.Context) (*models.MigrationState, error) {
+	applied, pending, err := database.MigrationStatus(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	return &models.MigrationState{Applied: applied, Pending: pending}, nil
+}
+
+// Close closes every connection of the pool.
+func (r *PostgresRepository) Close(ctx context.Context) error {
+	return r.db.Close()
+}
+
+const linkColumns = `id, code, original_url, click_count, expires_at, max_clicks, disabled,
+	redirect_type, owner_id, workspace_id, tags, domain, created_at`
+
+// scanner is what *sql.Row and *sql.Rows have in common.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanLink(row scanner) (*models.URL, error) {
+	var url models.URL
+	var id int64
+	var expiresAt sql.NullTime
+	var tags []string
+
+	err := row.Scan(&id, &url.Code, &url.OriginalURL, &url.ClickCount, &expiresAt, &url.MaxClicks, &url.Disabled,
+		&url.RedirectType, &url.OwnerID, &url.WorkspaceID, pq.Array(&tags), &url.Domain, &url.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	url.ID = formatID(id)
+	url.CreatedAt = url.CreatedAt.UTC()
+	if expiresAt.Valid {
+		t := expiresAt.Time.UTC()
+		url.ExpiresAt = &t
+	}
+	// An empty array is how the table stores no tags, which is nil everywhere else
+	if len(tags) > 0 {
+		url.Tags = tags
+	}
+
+	return &url, nil
+}
+
+func (r *PostgresRepository) Create(ctx context.Context, url models.URL) (string, error) {
+	var id int64
+	err := r.db.QueryRowContext(ctx, `INSERT INTO links (code, original_url, click_count, expires_at, max_clicks, disabled,
+			redirect_type, owner_id, workspace_id, tags, domain, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id`,
+		url.Code, url.OriginalURL, url.ClickCount, nullTime(url.ExpiresAt), url.MaxClicks, url.Disabled,
+		url.RedirectType, url.OwnerID, url.WorkspaceID, textArray(url.Tags), url.Domain, url.CreatedAt,
+	).Scan(&id)
+	if isUniqueViolation(err, "links_code_key") {
+		return "", fmt.Errorf("%w: %s", models.ErrDuplicateCode, url.Code)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return formatID(id), nil
+}
+
+// CreateMany stores the links with a single INSERT. Codes that are already taken are skipped
+// by ON CONFLICT instead of failing the whole statement, and are the ones missing from RETURNING.
+func (r *PostgresRepository) CreateMany(ctx context.Context, urls []models.URL) ([]string, []error) {
+	ids := make([]string, len(urls))
+	errs := duplicateCodes(urls)
+
+	var args sqlArgs
+	var values []string
+	for i, url := range urls {
+		if errs[i] != nil {
+			continue
+		}
+		values = append(values, "("+strings.Join([]string{
+			args.add(url.Code), args.add(url.OriginalURL), args.add(url.ClickCount), args.add(nullTime(url.ExpiresAt)),
+			args.add(url.MaxClicks), args.add(url.Disabled), args.add(url.RedirectType), args.add(url.OwnerID),
+			args.add(url.WorkspaceID), args.add(textArray(url.Tags)), args.add(url.Domain), args.add(url.CreatedAt),
+		}, ", ")+")")
+	}
+	if len(values) == 0 {
+		return ids, errs
+	}
+
+	rows, err := r.db.QueryContext(ctx, `INSERT INTO links (code, original_url, click_count, expires_at, max_clicks, disabled,
+			redirect_type, owner_id, workspace_id, tags, domain, created_at)
+		VALUES `+strings.Join(values, ", ")+`
+		ON CONFLICT (code) DO NOTHING
+		RETURNING code, id`, args...)
+	if err != nil {
+		failBatch(errs, err)
+		return ids, errs
+	}
+	defer rows.Close()
+
+	inserted := make(map[string]int64, len(values))
+	for rows.Next() {
+		var code string
+		var id int64
+		if err := rows.Scan(&code, &id); err != nil {
+			failBatch(errs, err)
+			return ids, errs
+		}
+		inserted[code] = id
+	}
+	if err := rows.Err(); err != nil {
+		failBatch(errs, err)
+		return ids, errs
+	}
+
+	for i, url := range urls {
+		if errs[i] != nil {
+			continue
+		}
+		if id, ok := inserted[url.Code]; ok {
+			ids[i] = formatID(id)
+		} else {
+			errs[i] = fmt.Errorf("%w: %s", models.ErrDuplicateCode, url.Code)
+		}
+	}
+
+	return ids, errs
+}
+
+func (r *PostgresRepository) FindOne(ctx context.Context, code string) (*models.URL, error) {
+	return r.FindInWorkspace(ctx, "", code)
+}
+
+// FindInWorkspace works like FindOne, but only finds links of the given workspace.
+// An empty workspaceID finds links of every workspace.
+func (r *PostgresRepository) FindInWorkspace(ctx context.Context, workspaceID, code string) (*models.URL, error) {
+	url, err := scanLink(r.db.QueryRowContext(ctx,
+		`SELECT `+linkColumns+` FROM links WHERE code = $1 AND ($2::text = '' OR workspace_id = $2)`,
+		code, workspaceID,
+	))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", models.ErrNotFound, code)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return url, nil
+}
+
+// FindByOriginalURL returns the oldest plain link to originalURL in the given workspace.
+// An empty workspaceID only matches links without a workspace, see ShortenerRepository.FindByOriginalURL.
+func (r *PostgresRepository) FindByOriginalURL(ctx context.Context, workspaceID, originalURL string) (*models.URL, error) {
+	url, err := scanLink(r.db.QueryRowContext(ctx,
+		`SELECT `+linkColumns+` FROM links
+		WHERE original_url = $1 AND workspace_id = $2
+			AND expires_at IS NULL AND max_clicks = 0 AND redirect_type = 0
+			AND cardinality(tags) = 0 AND NOT disabled
+		ORDER BY id
+		LIMIT 1`,
+		originalURL, workspaceID,
+	))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", models.ErrNotFound, originalURL)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return url, nil
+}
+
+// Update applies the changes in update to the link with the given code and returns the updated link.
+// The row stays locked between reading and writing it, so clicks counted in the meantime aren't lost.
+func (r *PostgresRepository) Update(ctx context.Context, workspaceID, code string, update models.LinkUpdate) (*models.URL, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	url, err := scanLink(tx.QueryRowContext(ctx,
+		`SELECT `+linkColumns+` FROM links WHERE code = $1 AND ($2::text = '' OR workspace_id = $2) FOR UPDATE`,
+		code, workspaceID,
+	))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", models.ErrNotFound, code)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	applyUpdate(url, update)
+
+	_, err = tx.ExecContext(ctx, `UPDATE links
+		SET original_url = $2, domain = $3, tags = $4, expires_at = $5, max_clicks = $6, redirect_type = $7, disabled = $8
+		WHERE id = $1`,
+		url.ID, url.OriginalURL, url.Domain, textArray(url.Tags), nullTime(url.ExpiresAt), url.MaxClicks, url.RedirectType, url.Disabled,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return url, nil
+}
+
+// Delete removes the link with the given code along with its click events, in one transaction.
+func (r *PostgresRepository) Delete(ctx context.Context, workspaceID, code string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM links WHERE code = $1 AND ($2::text = '' OR workspace_id = $2)`, code, workspaceID)
+	if err != nil {
+		return err
+	}
+	if deleted, err := result.RowsAffected(); err != nil {
+		return err
+	} else if deleted == 0 {
+		return fmt.Errorf("%w: %s", models.ErrNotFound, code)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM clicks WHERE code = $1`, code); err != nil {
+		return fmt.Errorf("deleting clicks of %s: %w", code, err)
+	}
+
+	return tx.Commit()
+}
+
+// List returns up to query.Limit links matching the query, in the requested order.
+func (r *PostgresRepository) List(ctx context.Context, query models.ListQuery) ([]*models.URL, error) {
+	where, args, err := listWhere(query)
+	if err != nil {
+		return nil, err
+	}
+
+	field := "created_at"
+	if query.Sort == models.SortClicks {
+		field = "click_count"
+	}
+	direction := "ASC"
+	if query.Descending {
+		direction = "DESC"
+	}
+
+	statement := fmt.Sprintf(`SELECT %s FROM links WHERE %s ORDER BY %s %s, id %s LIMIT %s`,
+		linkColumns, where, field, direction, direction, args.add(query.Limit))
+
+	rows, err := r.db.QueryContext(ctx, statement, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []*models.URL
+	for rows.Next() {
+		url, err := scanLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, url)
+	}
+
+	return links, rows.Err()
+}
+
+// sqlArgs collects the arguments of a statement as it's built.
+type sqlArgs []interface{}
+
+// add appends an argument and returns its placeholder.
+func (a *sqlArgs) add(v interface{}) string {
+	*a = append(*a, v)
+	return "$" + strconv.Itoa(len(*a))
+}
+
+// listWhere turns the filters of the query into a WHERE clause and its arguments.
+func listWhere(query models.ListQuery) (string, sqlArgs, error) {
+	var args sqlArgs
+	conditions := []string{"TRUE"}
+
+	if query.WorkspaceID != "" {
+		conditions = append(conditions, "workspace_id = "+args.add(query.WorkspaceID))
+	}
+	if query.OwnerID != "" {
+		conditions = append(conditions, "owner_id = "+args.add(query.OwnerID))
+	}
+	if query.Tag != "" {
+		// @> rather than ANY, so the GIN index on tags is used
+		conditions = append(conditions, "tags @> ARRAY["+args.add(query.Tag)+"]::text[]")
+	}
+	if query.Domain != "" {
+		conditions = append(conditions, "domain = "+args.add(query.Domain))
+	}
+	if query.Search != "" {
+		conditions = append(conditions, "original_url ILIKE '%' || "+args.add(escapeLike(query.Search))+" || '%'")
+	}
+
+	switch query.Status {
+	case models.StatusDisabled:
+		conditions = append(conditions, "disabled")
+	case models.StatusExpired:
+		now := args.add(query.Now)
+		conditions = append(conditions,
+			"NOT disabled",
+			"((expires_at IS NOT NULL AND expires_at <= "+now+") OR (max_clicks > 0 AND click_count >= max_clicks))",
+		)
+	case models.StatusActive:
+		now := args.add(query.Now)
+		conditions = append(conditions,
+			"NOT disabled",
+			"(expires_at IS NULL OR expires_at > "+now+")",
+			"(max_clicks = 0 OR click_count < max_clicks)",
+		)
+	}
+
+	if query.After != nil {
+		id, err := strconv.ParseInt(query.After.ID, 10, 64)
+		if err != nil {
+			return "", nil, fmt.Errorf("%w: malformed cursor", models.ErrInvalidListQuery)
+		}
+
+		op := ">"
+		if query.Descending {
+			op = "<"
+		}
+
+		// A row comparison: a later sort value, or the same value and a later id
+		if query.Sort == models.SortClicks {
+			conditions = append(conditions, "(click_count, id) "+op+" ("+args.add(query.After.Clicks)+", "+args.add(id)+")")
+		} else {
+			conditions = append(conditions, "(created_at, id) "+op+" ("+args.add(query.After.CreatedAt)+", "+args.add(id)+")")
+		}
+	}
+
+	return strings.Join(conditions, " AND "), args, nil
+}
+
+// escapeLike escapes the characters LIKE treats specially, so s only matches itself.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// IncrementClickCount bumps the click counter of a link by one.
+// The WHERE clause only matches links that still have clicks left, so two visitors can never
+// both get the last click of a link with max_clicks. When nothing matches we return ErrExpired.
+func (r *PostgresRepository) IncrementClickCount(ctx context.Context, code string) error {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE links SET click_count = click_count + 1 WHERE code = $1 AND (max_clicks = 0 OR click_count < max_clicks)`,
+		code,
+	)
+	if err != nil {
+		return err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return fmt.Errorf("%w: %s has no clicks left", models.ErrExpired, code)
+	}
+
+	return nil
+}
+
+func (r *PostgresRepository) CreateClick(ctx context.Context, click models.Click) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO clicks (code, clicked_at, referrer, user_agent, browser, ip_hash) VALUES ($1, $2, $3, $4, $5, $6)`,
+		click.Code, click.Timestamp, click.Referrer, click.UserAgent, click.Browser, click.IPHash,
+	)
+	return err
+}
+
+// NextSequence increments the counter called name and returns its new value.
+// The counter row is created on first use.
+func (r *PostgresRepository) NextSequence(ctx context.Context, name string) (int64, error) {
+	var seq int64
+	err := r.db.QueryRowContext(ctx,
+		`INSERT INTO counters (name, seq) VALUES ($1, 1)
+		ON CONFLICT (name) DO UPDATE SET seq = counters.seq + 1
+		RETURNING seq`,
+		name,
+	).Scan(&seq)
+	return seq, err
+}
+
+// RaiseSequence raises the counter called name to value, unless it's already higher.
+// GREATEST makes it safe for instances racing to raise the same counter.
+func (r *PostgresRepository) RaiseSequence(ctx context.Context, name string, value int64) (int64, error) {
+	var seq int64
+	err := r.db.QueryRowContext(ctx,
+		`INSERT INTO counters (name, seq) VALUES ($1, $2)
+		ON CONFLICT (name) DO UPDATE SET seq = GREATEST(counters.seq, EXCLUDED.seq)
+		RETURNING seq`,
+		name, value,
+	).Scan(&seq)
+	return seq, err
+}
+
+// ClickStats aggregates the clicks of a link. The queries run in one repeatable read transaction,
+// so the totals, the time series and the top lists all see the same data.
+func (r *PostgresRepository) ClickStats(ctx context.Context, query models.StatsQuery) (*models.LinkStats, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	stats := &models.LinkStats{
+		Code:     query.Code,
+		From:     query.From,
+		To:       query.To,
+		Interval: query.Interval,
+	}
+
+	const period = `code = $1 AND clicked_at >= $2 AND clicked_at < $3`
+
+	err = tx.QueryRowContext(ctx,
+		`SELECT count(*), count(DISTINCT NULLIF(ip_hash, '')) FROM clicks WHERE `+period,
+		query.Code, query.From, query.To,
+	).Scan(&stats.TotalClicks, &stats.UniqueVisitors)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx,
+		`SELECT date_trunc($4, clicked_at, 'UTC') AS bucket, count(*) FROM clicks WHERE `+period+`
+		GROUP BY bucket ORDER BY bucket`,
+		query.Code, query.From, query.To, query.Interval,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bucket models.StatsBucket
+		if err := rows.Scan(&bucket.Time, &bucket.Clicks); err != nil {
+			return nil, err
+		}
+		bucket.Time = bucket.Time.UTC()
+		stats.Series = append(stats.Series, bucket)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if stats.TopReferrers, err = topColumnValues(ctx, tx, "referrer", "(direct)", query); err != nil {
+		return nil, err
+	}
+	if stats.TopUserAgents, err = topColumnValues(ctx, tx, "browser", "Unknown", query); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// topColumnValues counts clicks per value of column and returns the most common ones.
+// Clicks without a value are counted under fallback. column is never user input.
+func topColumnValues(ctx context.Context, tx *sql.Tx, column, fallback string, query models.StatsQuery) ([]models.StatsCount, error) {
+	// COLLATE "C" breaks ties byte by byte, like Mongo does
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(`SELECT COALESCE(NULLIF(%s, ''), $4) AS value, count(*) AS clicks
+		FROM clicks WHERE code = $1 AND clicked_at >= $2 AND clicked_at < $3
+		GROUP BY value ORDER BY clicks DESC, value COLLATE "C" LIMIT $5`, column),
+		query.Code, query.From, query.To, fallback, query.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var top []models.StatsCount
+	for rows.Next() {
+		var count models.StatsCount
+		if err := rows.Scan(&count.Value, &count.Clicks); err != nil {
+			return nil, err
+		}
+		top = append(top, count)
+	}
+
+	return top, rows.Err()
+}
+
+const apiKeyColumns = `id, name, prefix, key_hash, scopes, user_id, workspace_id, created_at`
+
+func scanAPIKey(row scanner) (*models.APIKey, error) {
+	var key models.APIKey
+	var id int64
+
+	err := row.Scan(&id, &key.Name, &key.Prefix, &key.KeyHash, pq.Array(&key.Scopes), &key.UserID, &key.WorkspaceID, &key.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	key.ID = formatID(id)
+	key.CreatedAt = key.CreatedAt.UTC()
+	return &key, nil
+}
+
+func (r *PostgresRepository) CreateAPIKey(ctx context.Context, key models.APIKey) (string, error) {
+	var id int64
+	err := r.db.QueryRowContext(ctx, `INSERT INTO api_keys (name, prefix, key_hash, scopes, user_id, workspace_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id`,
+		key.Name, key.Prefix, key.KeyHash, textArray(key.Scopes), key.UserID, key.WorkspaceID, key.CreatedAt,
+	).Scan(&id)
+	if err != nil {
+		return "", err
+	}
+
+	return formatID(id), nil
+}
+
+// FindAPIKeyByHash returns the key with the given hash, which is how requests are authenticated.
+func (r *PostgresRepository) FindAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	key, err := scanAPIKey(r.db.QueryRowContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash = $1`, hash))
+	if err == sql.ErrNoRows {
+		return nil, models.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// ListAPIKeys returns every key, oldest first.
+func (r *PostgresRepository) ListAPIKeys(ctx context.Context) ([]*models.APIKey, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys ORDER BY created_at, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []*models.APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// DeleteAPIKey revokes a key. Requests made with it are rejected from then on.
+func (r *PostgresRepository) DeleteAPIKey(ctx context.Context, id string) error {
+	if err := r.deleteByID(ctx, "api_keys", id); err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%w: %s", models.ErrAPIKeyNotFound, id)
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *PostgresRepository) CreateWorkspace(ctx context.Context, workspace models.Workspace) (string, error) {
+	var id int64
+	err := r.db.QueryRowContext(ctx,
+		`INSERT INTO workspaces (name, created_at) VALUES ($1, $2) RETURNING id`,
+		workspace.Name, workspace.CreatedAt,
+	).Scan(&id)
+	if err != nil {
+		return "", err
+	}
+
+	return formatID(id), nil
+}
+
+func scanWorkspace(row scanner) (*models.Workspace, error) {
+	var workspace models.Workspace
+	var id int64
+
+	if err := row.Scan(&id, &workspace.Name, &workspace.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	workspace.ID = formatID(id)
+	workspace.CreatedAt = workspace.CreatedAt.UTC()
+	return &workspace, nil
+}
+
+func (r *PostgresRepository) FindWorkspace(ctx context.Context, id string) (*models.Workspace, error) {
+	workspace, err := findRowByID(ctx, r.db, `SELECT id, name, created_at FROM workspaces WHERE id = $1`, id, scanWorkspace)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", models.ErrWorkspaceNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return workspace, nil
+}
+
+// ListWorkspaces returns every workspace, oldest first.
+func (r *PostgresRepository) ListWorkspaces(ctx context.Context) ([]*models.Workspace, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, name, created_at FROM workspaces ORDER BY created_at, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var workspaces []*models.Workspace
+	for rows.Next() {
+		workspace, err := scanWorkspace(rows)
+		if err != nil {
+			return nil, err
+		}
+		workspaces = append(workspaces, workspace)
+	}
+
+	return workspaces, rows.Err()
+}
+
+func (r *PostgresRepository) CreateUser(ctx context.Context, user models.User) (string, error) {
+	var id int64
+	err := r.db.QueryRowContext(ctx,
+		`INSERT INTO users (workspace_id, name, email, created_at) VALUES ($1, $2, $3, $4) RETURNING id`,
+		user.WorkspaceID, user.Name, user.Email, user.CreatedAt,
+	).Scan(&id)
+	if isUniqueViolation(err, "users_email_key") {
+		return "", fmt.Errorf("%w: %s", models.ErrEmailTaken, user.Email)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return formatID(id), nil
+}
+
+func scanUser(row scanner) (*models.User, error) {
+	var user models.User
+	var id int64
+
+	if err := row.Scan(&id, &user.WorkspaceID, &user.Name, &user.Email, &user.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	user.ID = formatID(id)
+	user.CreatedAt = user.CreatedAt.UTC()
+	return &user, nil
+}
+
+func (r *PostgresRepository) FindUser(ctx context.Context, id string) (*models.User, error) {
+	user, err := findRowByID(ctx, r.db, `SELECT id, workspace_id, name, email, created_at FROM users WHERE id = $1`, id, scanUser)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", models.ErrUserNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// ListUsers returns the members of a workspace, oldest first.
+func (r *PostgresRepository) ListUsers(ctx context.Context, workspaceID string) ([]*models.User, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, workspace_id, name, email, created_at FROM users WHERE workspace_id = $1 ORDER BY created_at, id`,
+		workspaceID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*models.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+// findRowByID runs query with the numeric value of id and scans the row it returns.
+// An ID that isn't a number can't match anything, so it's reported as sql.ErrNoRows.
+func findRowByID[T any](ctx context.Context, db *sql.DB, query, id string, scan func(scanner) (T, error)) (T, error) {
+	numericID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		var zero T
+		return zero, sql.ErrNoRows
+	}
+	return scan(db.QueryRowContext(ctx, query, numericID))
+}
+
+// deleteByID deletes the row of table with the given id. Like findRowByID, it returns sql.ErrNoRows
+// when there is no such row. table is never user input.
+func (r *PostgresRepository) deleteByID(ctx context.Context, table, id string) error {
+	numericID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return sql.ErrNoRows
+	}
+
+	result, err := r.db.ExecContext(ctx, `DELETE FROM `+table+` WHERE id = $1`, numericID)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func formatID(id int64) string {
+	return strconv.FormatInt(id, 10)
+}
+
+// nullTime stores a nil time as NULL.
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *t, Valid: true}
+}
+
+// textArray stores a nil slice as an empty array, since the array columns are NOT NULL.
+func textArray(values []string) interface{} {
+	if values == nil {
+		values = []string{}
+	}
+	return pq.Array(values)
+}
+
+// isUniqueViolation reports whether err is Postgres rejecting a row because of the given unique constraint.
+func isUniqueViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == constraint
+}
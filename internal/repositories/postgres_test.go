@@ -0,0 +1,114 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/topboyasante/trunc8/internal/models"
+)
+
+func TestListWhere(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	where, args, err := listWhere(models.ListQuery{
+		WorkspaceID: "7",
+		Tag:         "promo",
+		Search:      "50%_off",
+		Status:      models.StatusActive,
+		Sort:        models.SortClicks,
+		Descending:  true,
+		After:       &models.ListCursor{Clicks: 10, ID: "42"},
+		Now:         now,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := "TRUE AND workspace_id = $1 AND tags @> ARRAY[$2]::text[] AND original_url ILIKE '%' || $3 || '%'" +
+		" AND NOT disabled AND (expires_at IS NULL OR expires_at > $4) AND (max_clicks = 0 OR click_count < max_clicks)" +
+		" AND (click_count, id) < ($5, $6)"
+	if where != expected {
+		t.Errorf("Unexpected WHERE clause:\n%s", where)
+	}
+
+	if len(args) != 6 || args[2] != `50\%\_off` || args[3] != now || args[5] != int64(42) {
+		t.Errorf("Unexpected arguments: %v", args)
+	}
+}
+
+func TestListWhere_MalformedCursor(t *testing.T) {
+	_, _, err := listWhere(models.ListQuery{After: &models.ListCursor{ID: "665f1f77bcf86cd799439011"}})
+
+	if !errors.Is(err, models.ErrInvalidListQuery) {
+		t.Fatalf("Expected ErrInvalidListQuery, got %v", err)
+	}
+}
+
+// TestPostgresRepository runs against a real server, set TEST_POSTGRES_URL to run it.
+// The schema is migrated in the database it points to, so use a throwaway one.
+func TestPostgresRepository(t *testing.T) {
+	url := os.Getenv("TEST_POSTGRES_URL")
+	if url == "" {
+		t.Skip("Skipping Postgres test - TEST_POSTGRES_URL is not set")
+	}
+
+	ctx := context.Background()
+	repo, err := NewPostgresRepository(ctx, url)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer repo.Close(ctx)
+
+	if err := repo.Ping(ctx); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	state, err := repo.Migrations(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(state.Applied) == 0 || len(state.Pending) != 0 {
+		t.Errorf("Expected every migration to be applied, got %+v", state)
+	}
+
+	code := "pg" + time.Now().Format("150405.000")
+	if _, err := repo.Create(ctx, models.URL{Code: code, OriginalURL: "https://example.com", MaxClicks: 1, CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer repo.Delete(ctx, "", code)
+
+	if _, err := repo.Create(ctx, models.URL{Code: code, OriginalURL: "https://example.org", CreatedAt: time.Now()}); !errors.Is(err, models.ErrDuplicateCode) {
+		t.Errorf("Expected ErrDuplicateCode, got %v", err)
+	}
+
+	if err := repo.IncrementClickCount(ctx, code); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := repo.IncrementClickCount(ctx, code); !errors.Is(err, models.ErrExpired) {
+		t.Errorf("Expected ErrExpired, got %v", err)
+	}
+
+	ids, errs := repo.CreateMany(ctx, []models.URL{
+		{Code: code + "a", OriginalURL: "https://example.com/a", Tags: []string{"bulk"}, CreatedAt: time.Now()},
+		{Code: code, OriginalURL: "https://example.com/b", CreatedAt: time.Now()},
+		{Code: code + "a", OriginalURL: "https://example.com/c", CreatedAt: time.Now()},
+	})
+	defer repo.Delete(ctx, "", code+"a")
+	if errs[0] != nil || ids[0] == "" {
+		t.Fatalf("Expected the first link to be stored, got %v", errs[0])
+	}
+	if !errors.Is(errs[1], models.ErrDuplicateCode) || !errors.Is(errs[2], models.ErrDuplicateCode) {
+		t.Errorf("Expected ErrDuplicateCode for the taken codes, got %v", errs[1:])
+	}
+
+	disabled := true
+	updated, err := repo.Update(ctx, "", code, models.LinkUpdate{Disabled: &disabled, Tags: []string{"a"}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !updated.Disabled || updated.ClickCount != 1 || len(updated.Tags) != 1 {
+		t.Errorf("Unexpected link after update: %+v", updated)
+	}
+}
@@ -0,0 +1,260 @@
+package repositories
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/topboyasante/trunc8/internal/models"
+)
+
+// The helpers in this file do in Go what Mongo does with filters and pipelines,
+// for the backends that keep whole documents and have no query language of their own.
+
+// sequenceID formats the nth ID handed out by a backend. IDs are shaped like a Mongo ObjectID,
+// 24 hex characters, and sorting them gives the order things were created in.
+func sequenceID(n uint64) string {
+	return fmt.Sprintf("%024x", n)
+}
+
+// copyURL returns a copy of u that shares nothing with it, so stored links can't be changed by callers.
+func copyURL(u *models.URL) *models.URL {
+	c := *u
+	if u.ExpiresAt != nil {
+		expiresAt := *u.ExpiresAt
+		c.ExpiresAt = &expiresAt
+	}
+	if u.Tags != nil {
+		c.Tags = append([]string(nil), u.Tags...)
+	}
+	return &c
+}
+
+// isPlainLink reports whether u is a link FindByOriginalURL can hand out. See ShortenerRepository.FindByOriginalURL.
+func isPlainLink(u *models.URL) bool {
+	return u.ExpiresAt == nil && u.MaxClicks == 0 && u.RedirectType == 0 && len(u.Tags) == 0 && !u.Disabled
+}
+
+// hasClicksLeft reports whether a click can still be counted on u.
+func hasClicksLeft(u *models.URL) bool {
+	return u.MaxClicks == 0 || u.ClickCount < u.MaxClicks
+}
+
+// applyUpdate makes the changes in update to u, the same way ShortenerRepository.Update does with $set and $unset.
+func applyUpdate(u *models.URL, update models.LinkUpdate) {
+	if update.OriginalURL != nil {
+		u.OriginalURL = *update.OriginalURL
+	}
+	if update.Domain != nil {
+		u.Domain = *update.Domain
+	}
+	if update.Tags != nil {
+		if len(update.Tags) == 0 {
+			u.Tags = nil
+		} else {
+			u.Tags = append([]string(nil), update.Tags...)
+		}
+	}
+	if update.ExpiresAt != nil {
+		expiresAt := *update.ExpiresAt
+		u.ExpiresAt = &expiresAt
+	}
+	if update.RemoveExpiry {
+		u.ExpiresAt = nil
+	}
+	if update.MaxClicks != nil {
+		u.MaxClicks = *update.MaxClicks
+	}
+	if update.RedirectType != nil {
+		u.RedirectType = *update.RedirectType
+	}
+	if update.Disabled != nil {
+		u.Disabled = *update.Disabled
+	}
+}
+
+// matchesList reports whether u passes the filters of the query. The cursor is handled by listLinks.
+func matchesList(u *models.URL, query models.ListQuery) bool {
+	if query.WorkspaceID != "" && u.WorkspaceID != query.WorkspaceID {
+		return false
+	}
+	if query.OwnerID != "" && u.OwnerID != query.OwnerID {
+		return false
+	}
+	if query.Tag != "" && !containsString(u.Tags, query.Tag) {
+		return false
+	}
+	if query.Domain != "" && u.Domain != query.Domain {
+		return false
+	}
+	if query.Search != "" && !strings.Contains(strings.ToLower(u.OriginalURL), strings.ToLower(query.Search)) {
+		return false
+	}
+
+	expired := u.Expired(query.Now) || !hasClicksLeft(u)
+	switch query.Status {
+	case models.StatusDisabled:
+		return u.Disabled
+	case models.StatusExpired:
+		return !u.Disabled && expired
+	case models.StatusActive:
+		return !u.Disabled && !expired
+	}
+	return true
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// listLinks filters, sorts and pages links the same way ShortenerRepository.List does.
+func listLinks(links []*models.URL, query models.ListQuery) []*models.URL {
+	var after *models.URL
+	if query.After != nil {
+		after = &models.URL{ID: query.After.ID, CreatedAt: query.After.CreatedAt, ClickCount: query.After.Clicks}
+	}
+
+	var matched []*models.URL
+	for _, u := range links {
+		if !matchesList(u, query) {
+			continue
+		}
+		if after != nil && compareLinks(u, after, query) <= 0 {
+			continue
+		}
+		matched = append(matched, copyURL(u))
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		return compareLinks(matched[i], matched[j], query) < 0
+	})
+
+	if query.Limit > 0 && len(matched) > query.Limit {
+		matched = matched[:query.Limit]
+	}
+	return matched
+}
+
+// compareLinks orders two links by the sort field of the query, then by ID.
+// It returns a negative number when a comes first.
+func compareLinks(a, b *models.URL, query models.ListQuery) int {
+	c := 0
+	if query.Sort == models.SortClicks {
+		c = a.ClickCount - b.ClickCount
+	} else {
+		c = a.CreatedAt.Compare(b.CreatedAt)
+	}
+	if c == 0 {
+		c = strings.Compare(a.ID, b.ID)
+	}
+	if query.Descending {
+		return -c
+	}
+	return c
+}
+
+// clickStats aggregates clicks like the $facet pipeline of ShortenerRepository.ClickStats.
+// Only the clicks of the link and period in query are counted.
+func clickStats(clicks []models.Click, query models.StatsQuery) *models.LinkStats {
+	stats := &models.LinkStats{
+		Code:     query.Code,
+		From:     query.From,
+		To:       query.To,
+		Interval: query.Interval,
+	}
+
+	visitors := map[string]bool{}
+	buckets := map[time.Time]int64{}
+	referrers := map[string]int64{}
+	browsers := map[string]int64{}
+
+	for _, click := range clicks {
+		if click.Code != query.Code || click.Timestamp.Before(query.From) || !click.Timestamp.Before(query.To) {
+			continue
+		}
+
+		stats.TotalClicks++
+		if click.IPHash != "" {
+			visitors[click.IPHash] = true
+		}
+		buckets[truncateTime(click.Timestamp, query.Interval)]++
+		referrers[valueOr(click.Referrer, "(direct)")]++
+		browsers[valueOr(click.Browser, "Unknown")]++
+	}
+	stats.UniqueVisitors = int64(len(visitors))
+
+	for t, n := range buckets {
+		stats.Series = append(stats.Series, models.StatsBucket{Time: t, Clicks: n})
+	}
+	sort.Slice(stats.Series, func(i, j int) bool {
+		return stats.Series[i].Time.Before(stats.Series[j].Time)
+	})
+
+	stats.TopReferrers = topCounts(referrers, query.Limit)
+	stats.TopUserAgents = topCounts(browsers, query.Limit)
+
+	return stats
+}
+
+// truncateTime returns the start of the hour or day t falls in, in UTC like $dateTrunc.
+func truncateTime(t time.Time, interval string) time.Time {
+	t = t.UTC()
+	if interval == "hour" {
+		return t.Truncate(time.Hour)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// topCounts returns the limit most clicked values, ties broken alphabetically.
+func topCounts(counts map[string]int64, limit int) []models.StatsCount {
+	var top []models.StatsCount
+	for value, clicks := range counts {
+		top = append(top, models.StatsCount{Value: value, Clicks: clicks})
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Clicks != top[j].Clicks {
+			return top[i].Clicks > top[j].Clicks
+		}
+		return top[i].Value < top[j].Value
+	})
+	if limit > 0 && len(top) > limit {
+		top = top[:limit]
+	}
+	return top
+}
+
+// duplicateCodes returns the errors of a CreateMany before anything is stored: a link that reuses
+// the code of an earlier link of the batch gets ErrDuplicateCode, the others nil.
+func duplicateCodes(urls []models.URL) []error {
+	errs := make([]error, len(urls))
+	seen := make(map[string]bool, len(urls))
+	for i, url := range urls {
+		if seen[url.Code] {
+			errs[i] = fmt.Errorf("%w: %s", models.ErrDuplicateCode, url.Code)
+		}
+		seen[url.Code] = true
+	}
+	return errs
+}
+
+// failBatch sets err for every link of a CreateMany that hasn't failed already.
+func failBatch(errs []error, err error) {
+	for i := range errs {
+		if errs[i] == nil {
+			errs[i] = err
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/topboyasante/trunc8/internal/config"
+	"github.com/topboyasante/trunc8/internal/models"
+)
+
+// Repository is everything trunc8 keeps in storage. Each backend implements all of it,
+// so the backend is picked by configuration alone.
+// The services only see the parts they need, through their own interfaces.
+type Repository interface {
+	// Links
+	Create(ctx context.Context, url models.URL) (string, error)
+	// CreateMany stores the links in as few round trips as the backend allows. ids[i] and errs[i]
+	// are those of urls[i]: a link whose code is taken gets ErrDuplicateCode without stopping the others.
+	CreateMany(ctx context.Context, urls []models.URL) (ids []string, errs []error)
+	FindOne(ctx context.Context, code string) (*models.URL, error)
+	FindInWorkspace(ctx context.Context, workspaceID, code string) (*models.URL, error)
+	FindByOriginalURL(ctx context.Context, workspaceID, originalURL string) (*models.URL, error)
+	Update(ctx context.Context, workspaceID, code string, update models.LinkUpdate) (*models.URL, error)
+	Delete(ctx context.Context, workspaceID, code string) error
+	List(ctx context.Context, query models.ListQuery) ([]*models.URL, error)
+	IncrementClickCount(ctx context.Context, code string) error
+	NextSequence(ctx context.Context, name string) (int64, error)
+	// RaiseSequence raises the counter called name to value, unless it's already higher, and returns
+	// where it stands. A value of 0 reads the counter, which is 0 until it is first used.
+	RaiseSequence(ctx context.Context, name string, value int64) (int64, error)
+
+	// Clicks
+	CreateClick(ctx context.Context, click models.Click) error
+	ClickStats(ctx context.Context, query models.StatsQuery) (*models.LinkStats, error)
+
+	// API keys
+	CreateAPIKey(ctx context.Context, key models.APIKey) (string, error)
+	FindAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error)
+	ListAPIKeys(ctx context.Context) ([]*models.APIKey, error)
+	DeleteAPIKey(ctx context.Context, id string) error
+
+	// Workspaces and users
+	CreateWorkspace(ctx context.Context, workspace models.Workspace) (string, error)
+	FindWorkspace(ctx context.Context, id string) (*models.Workspace, error)
+	ListWorkspaces(ctx context.Context) ([]*models.Workspace, error)
+	CreateUser(ctx context.Context, user models.User) (string, error)
+	FindUser(ctx context.Context, id string) (*models.User, error)
+	ListUsers(ctx context.Context, workspaceID string) ([]*models.User, error)
+
+	// Ping checks the storage can be reached.
+	Ping(ctx context.Context) error
+
+	// Migrations reports which schema migrations have been applied.
+	Migrations(ctx context.Context) (*models.MigrationState, error)
+
+	// Close releases the connections or files held by the backend.
+	Close(ctx context.Context) error
+}
+
+// New opens the backend named by the scheme of DATABASE_URL and prepares it for use.
+func New(ctx context.Context, cfg *config.Config) (Repository, error) {
+	u, err := url.Parse(cfg.Database.Url)
+	if err != nil {
+		return nil, fmt.Errorf("parsing DATABASE_URL: %w", err)
+	}
+
+	switch u.Scheme {
+	case "mongodb", "mongodb+srv":
+		return NewMongoRepository(ctx, cfg.Database.Url, cfg.Database.Name)
+	case "postgres", "postgresql":
+		return NewPostgresRepository(ctx, cfg.Database.Url)
+	case "bolt":
+		// bolt:///var/lib/trunc8/trunc8.db for an absolute path, bolt://trunc8.db for a relative one
+		return NewBoltRepository(strings.TrimPrefix(cfg.Database.Url, "bolt://"))
+	case "memory":
+		return NewMemoryRepository(), nil
+	default:
+		return nil, fmt.Errorf("unsupported DATABASE_URL scheme %q, expected postgres, mongodb, bolt or memory", u.Scheme)
+	}
+}
+
+// noMigrations is the migration state of the backends without a schema.
+func noMigrations() *models.MigrationState {
+	return &models.MigrationState{Applied: []string{}, Pending: []string{}}
+}
+
+var (
+	_ Repository = (*MongoRepository)(nil)
+	_ Repository = (*PostgresRepository)(nil)
+	_ Repository = (*BoltRepository)(nil)
+	_ Repository = (*MemoryRepository)(nil)
+	_ Repository = (*InstrumentedRepository)(nil)
+)
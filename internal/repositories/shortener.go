@@ -2,28 +2,92 @@ package repositories
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"regexp"
 
-	"github.com/topboyasante/trunc8/internal/database"
 	"github.com/topboyasante/trunc8/internal/models"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type ShortenerRepository struct {
 	collection *mongo.Collection
+	clicks     *mongo.Collection
+	counters   *mongo.Collection
 }
 
-func NewShortnerRepository() *ShortenerRepository {
-	collection := database.DBClient.Database("trunc8-db").Collection("links")
+func NewShortnerRepository(db *mongo.Database) *ShortenerRepository {
 	return &ShortenerRepository{
-		collection: collection,
+		collection: db.Collection("links"),
+		clicks:     db.Collection("clicks"),
+		counters:   db.Collection("counters"),
 	}
 }
 
+// EnsureIndexes creates the indexes the repository relies on.
+// Creating an index that already exists is a no-op, so it's safe to call on every startup.
+func (r *ShortenerRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			// The unique index is what guarantees two links never share a code
+			Keys:    bson.D{{Key: "code", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			// Used to find an existing link for a URL before creating a new one
+			Keys: bson.D{{Key: "original_url", Value: 1}, {Key: "workspace_id", Value: 1}},
+		},
+		{
+			// Listing links, newest or most clicked first. _id breaks ties for the cursor.
+			Keys: bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "click_count", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		{
+			// Every list made with a workspace key is limited to its workspace
+			Keys: bson.D{{Key: "workspace_id", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+		{
+			// The filters of the list that narrow it down the most
+			Keys: bson.D{{Key: "owner_id", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "tags", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "domain", Value: 1}},
+		},
+		{
+			// A TTL index: Mongo deletes a link once the time in expires_at has passed.
+			// Links without expires_at are never touched. Their clicks stay behind, so the
+			// stats of a link only count the clicks made since it was created.
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("creating links indexes: %w", err)
+	}
+
+	_, err = r.clicks.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "code", Value: 1}, {Key: "timestamp", Value: 1}},
+	})
+	if err != nil {
+		return fmt.Errorf("creating clicks indexes: %w", err)
+	}
+
+	return nil
+}
+
 func (r *ShortenerRepository) Create(ctx context.Context, user models.URL) (string, error) {
 	result, err := r.collection.InsertOne(ctx, user)
+	if mongo.IsDuplicateKeyError(err) {
+		return "", fmt.Errorf("%w: %s", models.ErrDuplicateCode, user.Code)
+	}
 	if err != nil {
 		return "", err
 	}
@@ -35,12 +99,67 @@ func (r *ShortenerRepository) Create(ctx context.Context, user models.URL) (stri
 	return id.Hex(), nil
 }
 
+// CreateMany stores the links with one unordered InsertMany: a taken code only fails its own link.
+func (r *ShortenerRepository) CreateMany(ctx context.Context, urls []models.URL) ([]string, []error) {
+	ids := make([]string, len(urls))
+	errs := duplicateCodes(urls)
+
+	// Which link of the batch each document is
+	var docs []interface{}
+	var positions []int
+	for i, url := range urls {
+		if errs[i] == nil {
+			docs = append(docs, url)
+			positions = append(positions, i)
+		}
+	}
+	if len(docs) == 0 {
+		return ids, errs
+	}
+
+	result, err := r.collection.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+
+	var bulkErr mongo.BulkWriteException
+	if err != nil && !errors.As(err, &bulkErr) {
+		failBatch(errs, err)
+		return ids, errs
+	}
+	for _, writeErr := range bulkErr.WriteErrors {
+		i := positions[writeErr.Index]
+		if mongo.IsDuplicateKeyError(writeErr) {
+			errs[i] = fmt.Errorf("%w: %s", models.ErrDuplicateCode, urls[i].Code)
+		} else {
+			errs[i] = writeErr
+		}
+	}
+	// A write concern error leaves it unknown whether the documents were written
+	if bulkErr.WriteConcernError != nil {
+		failBatch(errs, bulkErr)
+	}
+
+	// The driver generates the IDs before sending, so every document has one, written or not
+	for n, insertedID := range result.InsertedIDs {
+		i := positions[n]
+		if errs[i] != nil {
+			continue
+		}
+		id, ok := insertedID.(primitive.ObjectID)
+		if !ok {
+			errs[i] = fmt.Errorf("expected ObjectID for InsertedID, got %T", insertedID)
+			continue
+		}
+		ids[i] = id.Hex()
+	}
+
+	return ids, errs
+}
+
 func (r *ShortenerRepository) FindOne(ctx context.Context, code string) (*models.URL, error) {
 	var user models.URL
 
 	err := r.collection.FindOne(ctx, bson.M{"code": code}).Decode(&user)
 	if err == mongo.ErrNoDocuments {
-		return nil, nil
+		return nil, fmt.Errorf("%w: %s", models.ErrNotFound, code)
 	}
 
 	if err != nil {
@@ -49,3 +168,432 @@ func (r *ShortenerRepository) FindOne(ctx context.Context, code string) (*models
 
 	return &user, nil
 }
+
+// FindInWorkspace works like FindOne, but only finds links of the given workspace.
+// An empty workspaceID finds links of every workspace.
+func (r *ShortenerRepository) FindInWorkspace(ctx context.Context, workspaceID, code string) (*models.URL, error) {
+	var url models.URL
+
+	err := r.collection.FindOne(ctx, inWorkspace(bson.M{"code": code}, workspaceID)).Decode(&url)
+	if err == mongo.ErrNoDocuments {
+		return nil, fmt.Errorf("%w: %s", models.ErrNotFound, code)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &url, nil
+}
+
+// inWorkspace limits filter to the links of a workspace, unless workspaceID is empty.
+func inWorkspace(filter bson.M, workspaceID string) bson.M {
+	if workspaceID != "" {
+		filter["workspace_id"] = workspaceID
+	}
+	return filter
+}
+
+// FindByOriginalURL returns a plain link to originalURL in the given workspace: one that doesn't expire,
+// has no click limit, uses the default redirect type, has no tags and isn't disabled. Links with restrictions were created for
+// a specific purpose, so they are never handed out to someone else.
+// Unlike the other lookups, an empty workspaceID only matches links without a workspace:
+// a link is never shared across workspaces.
+func (r *ShortenerRepository) FindByOriginalURL(ctx context.Context, workspaceID, originalURL string) (*models.URL, error) {
+	var workspace interface{} = workspaceID
+	if workspaceID == "" {
+		workspace = bson.M{"$exists": false}
+	}
+
+	filter := bson.M{
+		"workspace_id":  workspace,
+		"original_url":  originalURL,
+		"expires_at":    bson.M{"$exists": false},
+		"max_clicks":    bson.M{"$exists": false},
+		"redirect_type": bson.M{"$exists": false},
+		"tags":          bson.M{"$exists": false},
+		"disabled":      bson.M{"$ne": true},
+	}
+
+	var url models.URL
+	err := r.collection.FindOne(ctx, filter).Decode(&url)
+	if err == mongo.ErrNoDocuments {
+		return nil, fmt.Errorf("%w: %s", models.ErrNotFound, originalURL)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &url, nil
+}
+
+// Update applies the changes in update to the link with the given code and returns the updated link.
+// Fields that go back to their default are removed with $unset, the same way omitempty leaves them out on insert.
+// Only links of the given workspace can be changed, or of every workspace when workspaceID is empty.
+func (r *ShortenerRepository) Update(ctx context.Context, workspaceID, code string, update models.LinkUpdate) (*models.URL, error) {
+	set := bson.M{}
+	unset := bson.M{}
+
+	if update.OriginalURL != nil {
+		set["original_url"] = *update.OriginalURL
+	}
+	if update.Domain != nil {
+		set["domain"] = *update.Domain
+	}
+	if update.Tags != nil {
+		if len(update.Tags) == 0 {
+			unset["tags"] = ""
+		} else {
+			set["tags"] = update.Tags
+		}
+	}
+	if update.ExpiresAt != nil {
+		set["expires_at"] = *update.ExpiresAt
+	}
+	if update.RemoveExpiry {
+		unset["expires_at"] = ""
+	}
+	if update.MaxClicks != nil {
+		if *update.MaxClicks == 0 {
+			unset["max_clicks"] = ""
+		} else {
+			set["max_clicks"] = *update.MaxClicks
+		}
+	}
+	if update.RedirectType != nil {
+		if *update.RedirectType == 0 {
+			unset["redirect_type"] = ""
+		} else {
+			set["redirect_type"] = *update.RedirectType
+		}
+	}
+	if update.Disabled != nil {
+		if *update.Disabled {
+			set["disabled"] = true
+		} else {
+			unset["disabled"] = ""
+		}
+	}
+
+	changes := bson.M{}
+	if len(set) > 0 {
+		changes["$set"] = set
+	}
+	if len(unset) > 0 {
+		changes["$unset"] = unset
+	}
+
+	// Nothing to change, but the caller still expects the link back
+	if len(changes) == 0 {
+		return r.FindInWorkspace(ctx, workspaceID, code)
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var url models.URL
+	err := r.collection.FindOneAndUpdate(ctx, inWorkspace(bson.M{"code": code}, workspaceID), changes, opts).Decode(&url)
+	if err == mongo.ErrNoDocuments {
+		return nil, fmt.Errorf("%w: %s", models.ErrNotFound, code)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &url, nil
+}
+
+// Delete removes the link with the given code along with its click events.
+// Like Update, it only touches links of the given workspace.
+func (r *ShortenerRepository) Delete(ctx context.Context, workspaceID, code string) error {
+	result, err := r.collection.DeleteOne(ctx, inWorkspace(bson.M{"code": code}, workspaceID))
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("%w: %s", models.ErrNotFound, code)
+	}
+
+	// The clicks can't be reached once the link is gone. A new link can
+	// take the code later, so they would otherwise end up in its stats.
+	if _, err := r.clicks.DeleteMany(ctx, bson.M{"code": code}); err != nil {
+		return fmt.Errorf("deleting clicks of %s: %w", code, err)
+	}
+
+	return nil
+}
+
+// List returns up to query.Limit links matching the query, in the requested order.
+func (r *ShortenerRepository) List(ctx context.Context, query models.ListQuery) ([]*models.URL, error) {
+	filter, err := listFilter(query)
+	if err != nil {
+		return nil, err
+	}
+
+	field := "created_at"
+	if query.Sort == models.SortClicks {
+		field = "click_count"
+	}
+	direction := 1
+	if query.Descending {
+		direction = -1
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: field, Value: direction}, {Key: "_id", Value: direction}}).
+		SetLimit(int64(query.Limit))
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var links []*models.URL
+	if err := cursor.All(ctx, &links); err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
+
+// listFilter turns the filters of the query into a Mongo filter.
+// Every condition goes into $and, so the ones on the same field don't overwrite each other.
+func listFilter(query models.ListQuery) (bson.M, error) {
+	conditions := bson.A{}
+
+	if query.WorkspaceID != "" {
+		conditions = append(conditions, bson.M{"workspace_id": query.WorkspaceID})
+	}
+	if query.OwnerID != "" {
+		conditions = append(conditions, bson.M{"owner_id": query.OwnerID})
+	}
+	if query.Tag != "" {
+		conditions = append(conditions, bson.M{"tags": query.Tag})
+	}
+	if query.Domain != "" {
+		conditions = append(conditions, bson.M{"domain": query.Domain})
+	}
+	if query.Search != "" {
+		conditions = append(conditions, bson.M{"original_url": primitive.Regex{
+			Pattern: regexp.QuoteMeta(query.Search),
+			Options: "i",
+		}})
+	}
+
+	// A link is expired when its time is up or it used all its clicks
+	expired := bson.A{
+		bson.M{"expires_at": bson.M{"$lte": query.Now}},
+		bson.M{"max_clicks": bson.M{"$exists": true}, "$expr": bson.M{"$gte": bson.A{"$click_count", "$max_clicks"}}},
+	}
+
+	switch query.Status {
+	case models.StatusDisabled:
+		conditions = append(conditions, bson.M{"disabled": true})
+	case models.StatusExpired:
+		conditions = append(conditions, bson.M{"disabled": bson.M{"$ne": true}, "$or": expired})
+	case models.StatusActive:
+		conditions = append(conditions,
+			bson.M{"disabled": bson.M{"$ne": true}},
+			bson.M{"$or": bson.A{
+				bson.M{"expires_at": bson.M{"$exists": false}},
+				bson.M{"expires_at": bson.M{"$gt": query.Now}},
+			}},
+			bson.M{"$or": bson.A{
+				bson.M{"max_clicks": bson.M{"$exists": false}},
+				bson.M{"$expr": bson.M{"$lt": bson.A{"$click_count", "$max_clicks"}}},
+			}},
+		)
+	}
+
+	if query.After != nil {
+		after, err := afterCursor(query)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, after)
+	}
+
+	if len(conditions) == 0 {
+		return bson.M{}, nil
+	}
+	return bson.M{"$and": conditions}, nil
+}
+
+// afterCursor matches the links that come after the cursor in the sort order:
+// a later sort value, or the same value and a later _id.
+func afterCursor(query models.ListQuery) (bson.M, error) {
+	id, err := primitive.ObjectIDFromHex(query.After.ID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", models.ErrInvalidListQuery)
+	}
+
+	op := "$gt"
+	if query.Descending {
+		op = "$lt"
+	}
+
+	field := "created_at"
+	var value interface{} = query.After.CreatedAt
+	if query.Sort == models.SortClicks {
+		field = "click_count"
+		value = query.After.Clicks
+	}
+
+	return bson.M{"$or": bson.A{
+		bson.M{field: bson.M{op: value}},
+		bson.M{field: value, "_id": bson.M{op: id}},
+	}}, nil
+}
+
+// IncrementClickCount bumps the click counter of a link by one.
+// $inc is applied atomically by Mongo, so concurrent redirects never lose a click.
+// The filter only matches links that still have clicks left, so two visitors can never
+// both get the last click of a link with max_clicks. When nothing matches we return ErrExpired.
+func (r *ShortenerRepository) IncrementClickCount(ctx context.Context, code string) error {
+	filter := bson.M{
+		"code": code,
+		"$or": bson.A{
+			bson.M{"max_clicks": bson.M{"$exists": false}},
+			bson.M{"$expr": bson.M{"$lt": bson.A{"$click_count", "$max_clicks"}}},
+		},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, bson.M{"$inc": bson.M{"click_count": 1}})
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%w: %s has no clicks left", models.ErrExpired, code)
+	}
+
+	return nil
+}
+
+// CreateClick stores a single click event in the clicks collection.
+func (r *ShortenerRepository) CreateClick(ctx context.Context, click models.Click) error {
+	_, err := r.clicks.InsertOne(ctx, click)
+	return err
+}
+
+// NextSequence increments the counter called name and returns its new value.
+// The counter document is created on first use thanks to the upsert.
+func (r *ShortenerRepository) NextSequence(ctx context.Context, name string) (int64, error) {
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var counter struct {
+		Seq int64 `bson:"seq"`
+	}
+	err := r.counters.FindOneAndUpdate(ctx, bson.M{"_id": name}, bson.M{"$inc": bson.M{"seq": 1}}, opts).Decode(&counter)
+	if err != nil {
+		return 0, err
+	}
+
+	return counter.Seq, nil
+}
+
+// RaiseSequence raises the counter called name to value, unless it's already higher.
+// $max only ever moves the counter up, so instances racing to raise it can't lower it.
+func (r *ShortenerRepository) RaiseSequence(ctx context.Context, name string, value int64) (int64, error) {
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var counter struct {
+		Seq int64 `bson:"seq"`
+	}
+	err := r.counters.FindOneAndUpdate(ctx, bson.M{"_id": name}, bson.M{"$max": bson.M{"seq": value}}, opts).Decode(&counter)
+	if err != nil {
+		return 0, err
+	}
+
+	return counter.Seq, nil
+}
+
+// ClickStats aggregates the clicks of a link in a single round trip.
+// $facet runs several sub-pipelines over the same matched clicks,
+// so the totals, the time series and the top lists all see the same data.
+func (r *ShortenerRepository) ClickStats(ctx context.Context, query models.StatsQuery) (*models.LinkStats, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"code":      query.Code,
+			"timestamp": bson.M{"$gte": query.From, "$lt": query.To},
+		}}},
+		{{Key: "$facet", Value: bson.M{
+			"total": bson.A{
+				bson.M{"$count": "clicks"},
+			},
+			"unique": bson.A{
+				bson.M{"$match": bson.M{"ip_hash": bson.M{"$exists": true, "$ne": ""}}},
+				bson.M{"$group": bson.M{"_id": "$ip_hash"}},
+				bson.M{"$count": "visitors"},
+			},
+			"series": bson.A{
+				bson.M{"$group": bson.M{
+					"_id":    bson.M{"$dateTrunc": bson.M{"date": "$timestamp", "unit": query.Interval}},
+					"clicks": bson.M{"$sum": 1},
+				}},
+				bson.M{"$sort": bson.M{"_id": 1}},
+			},
+			"referrers": topValues("$referrer", "(direct)", query.Limit),
+			"browsers":  topValues("$browser", "Unknown", query.Limit),
+		}}},
+	}
+
+	cursor, err := r.clicks.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var facets struct {
+		Total []struct {
+			Clicks int64 `bson:"clicks"`
+		} `bson:"total"`
+		Unique []struct {
+			Visitors int64 `bson:"visitors"`
+		} `bson:"unique"`
+		Series    []models.StatsBucket `bson:"series"`
+		Referrers []models.StatsCount  `bson:"referrers"`
+		Browsers  []models.StatsCount  `bson:"browsers"`
+	}
+
+	// $facet always produces exactly one document
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(&facets); err != nil {
+			return nil, err
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	stats := &models.LinkStats{
+		Code:          query.Code,
+		From:          query.From,
+		To:            query.To,
+		Interval:      query.Interval,
+		Series:        facets.Series,
+		TopReferrers:  facets.Referrers,
+		TopUserAgents: facets.Browsers,
+	}
+	if len(facets.Total) > 0 {
+		stats.TotalClicks = facets.Total[0].Clicks
+	}
+	if len(facets.Unique) > 0 {
+		stats.UniqueVisitors = facets.Unique[0].Visitors
+	}
+
+	return stats, nil
+}
+
+// topValues builds a facet that counts clicks per value of field and keeps the most common ones.
+// Clicks without the field are counted under fallback.
+func topValues(field, fallback string, limit int) bson.A {
+	return bson.A{
+		bson.M{"$group": bson.M{
+			"_id":    bson.M{"$ifNull": bson.A{field, fallback}},
+			"clicks": bson.M{"$sum": 1},
+		}},
+		bson.M{"$sort": bson.D{{Key: "clicks", Value: -1}, {Key: "_id", Value: 1}}},
+		bson.M{"$limit": limit},
+	}
+}
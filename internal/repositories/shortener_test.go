@@ -2,10 +2,13 @@ package repositories
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/topboyasante/trunc8/internal/models"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
 )
 
@@ -66,6 +69,58 @@ func TestShortenerRepository_Create(t *testing.T) {
 		if id != "" {
 			t.Error("Expected empty ID when error occurs")
 		}
+
+		if !errors.Is(err, models.ErrDuplicateCode) {
+			t.Errorf("Expected ErrDuplicateCode, got %v", err)
+		}
+	})
+
+	mt.Run("other write error", func(mt *mtest.T) {
+		repo := &ShortenerRepository{collection: mt.Coll}
+
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{
+			Index:   0,
+			Code:    1,
+			Message: "write error",
+		}))
+
+		_, err := repo.Create(context.Background(), models.URL{Code: "TEST"})
+
+		if err == nil {
+			t.Fatal("Expected error, got nil")
+		}
+
+		if errors.Is(err, models.ErrDuplicateCode) {
+			t.Error("Expected only duplicate key errors to be reported as ErrDuplicateCode")
+		}
+	})
+}
+
+func TestShortenerRepository_EnsureIndexes(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("success", func(mt *mtest.T) {
+		repo := &ShortenerRepository{collection: mt.Coll, clicks: mt.Coll}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(), mtest.CreateSuccessResponse())
+
+		if err := repo.EnsureIndexes(context.Background()); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	})
+
+	mt.Run("index error", func(mt *mtest.T) {
+		repo := &ShortenerRepository{collection: mt.Coll, clicks: mt.Coll}
+
+		// Existing duplicates make it impossible to build the unique index
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{
+			Code:    11000,
+			Message: "E11000 duplicate key error",
+		}))
+
+		if err := repo.EnsureIndexes(context.Background()); err == nil {
+			t.Fatal("Expected error, got nil")
+		}
 	})
 }
 
@@ -77,10 +132,10 @@ func TestShortenerRepository_FindOne(t *testing.T) {
 		
 		// Mock successful find
 		first := mtest.CreateCursorResponse(1, "trunc8-db.links", mtest.FirstBatch, bson.D{
-			{"_id", "507f1f77bcf86cd799439011"},
-			{"original_url", "https://example.com"},
-			{"code", "TEST"},
-			{"click_count", 5},
+			{Key: "_id", Value: "507f1f77bcf86cd799439011"},
+			{Key: "original_url", Value: "https://example.com"},
+			{Key: "code", Value: "TEST"},
+			{Key: "click_count", Value: 5},
 		})
 		killCursor := mtest.CreateCursorResponse(0, "trunc8-db.links", mtest.NextBatch)
 		mt.AddMockResponses(first, killCursor)
@@ -116,8 +171,8 @@ func TestShortenerRepository_FindOne(t *testing.T) {
 		
 		url, err := repo.FindOne(context.Background(), "NOTFOUND")
 		
-		if err != nil {
-			t.Fatalf("Expected no error, got %v", err)
+		if !errors.Is(err, models.ErrNotFound) {
+			t.Fatalf("Expected ErrNotFound, got %v", err)
 		}
 		
 		if url != nil {
@@ -144,4 +199,450 @@ func TestShortenerRepository_FindOne(t *testing.T) {
 			t.Error("Expected nil URL when error occurs")
 		}
 	})
-}
\ No newline at end of file
+}
+
+func TestShortenerRepository_FindByOriginalURL(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("success", func(mt *mtest.T) {
+		repo := &ShortenerRepository{collection: mt.Coll}
+
+		first := mtest.CreateCursorResponse(1, "trunc8-db.links", mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: "507f1f77bcf86cd799439011"},
+			{Key: "original_url", Value: "https://example.com"},
+			{Key: "code", Value: "TEST"},
+		})
+		killCursor := mtest.CreateCursorResponse(0, "trunc8-db.links", mtest.NextBatch)
+		mt.AddMockResponses(first, killCursor)
+
+		url, err := repo.FindByOriginalURL(context.Background(), "", "https://example.com")
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if url.Code != "TEST" {
+			t.Errorf("Expected code 'TEST', got '%s'", url.Code)
+		}
+	})
+
+	mt.Run("not found", func(mt *mtest.T) {
+		repo := &ShortenerRepository{collection: mt.Coll}
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "trunc8-db.links", mtest.FirstBatch))
+
+		url, err := repo.FindByOriginalURL(context.Background(), "", "https://example.com")
+
+		if !errors.Is(err, models.ErrNotFound) {
+			t.Fatalf("Expected ErrNotFound, got %v", err)
+		}
+
+		if url != nil {
+			t.Error("Expected nil URL when not found")
+		}
+	})
+}
+
+func TestShortenerRepository_IncrementClickCount(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("success", func(mt *mtest.T) {
+		repo := &ShortenerRepository{collection: mt.Coll}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(
+			bson.E{Key: "n", Value: 1},
+			bson.E{Key: "nModified", Value: 1},
+		))
+
+		err := repo.IncrementClickCount(context.Background(), "TEST")
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	})
+
+	mt.Run("no clicks left", func(mt *mtest.T) {
+		repo := &ShortenerRepository{collection: mt.Coll}
+
+		// The max_clicks condition in the filter didn't match
+		mt.AddMockResponses(mtest.CreateSuccessResponse(
+			bson.E{Key: "n", Value: 0},
+			bson.E{Key: "nModified", Value: 0},
+		))
+
+		err := repo.IncrementClickCount(context.Background(), "TEST")
+
+		if !errors.Is(err, models.ErrExpired) {
+			t.Fatalf("Expected ErrExpired, got %v", err)
+		}
+	})
+
+	mt.Run("database error", func(mt *mtest.T) {
+		repo := &ShortenerRepository{collection: mt.Coll}
+
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{
+			Code:    1,
+			Message: "database connection error",
+		}))
+
+		err := repo.IncrementClickCount(context.Background(), "TEST")
+
+		if err == nil {
+			t.Fatal("Expected error, got nil")
+		}
+	})
+}
+
+func TestShortenerRepository_CreateClick(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("success", func(mt *mtest.T) {
+		repo := &ShortenerRepository{clicks: mt.Coll}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+
+		err := repo.CreateClick(context.Background(), models.Click{
+			Code:      "TEST",
+			Timestamp: time.Now(),
+			Referrer:  "https://news.example.org",
+		})
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	})
+
+	mt.Run("insert error", func(mt *mtest.T) {
+		repo := &ShortenerRepository{clicks: mt.Coll}
+
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{
+			Index:   0,
+			Code:    1,
+			Message: "write error",
+		}))
+
+		err := repo.CreateClick(context.Background(), models.Click{Code: "TEST"})
+
+		if err == nil {
+			t.Fatal("Expected error, got nil")
+		}
+	})
+}
+
+func TestShortenerRepository_ClickStats(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	query := models.StatsQuery{
+		Code:     "TEST",
+		From:     from,
+		To:       from.Add(24 * time.Hour),
+		Interval: "hour",
+		Limit:    10,
+	}
+
+	mt.Run("success", func(mt *mtest.T) {
+		repo := &ShortenerRepository{clicks: mt.Coll}
+
+		first := mtest.CreateCursorResponse(1, "trunc8-db.clicks", mtest.FirstBatch, bson.D{
+			{Key: "total", Value: bson.A{bson.D{{Key: "clicks", Value: 3}}}},
+			{Key: "unique", Value: bson.A{bson.D{{Key: "visitors", Value: 2}}}},
+			{Key: "series", Value: bson.A{
+				bson.D{{Key: "_id", Value: from}, {Key: "clicks", Value: 3}},
+			}},
+			{Key: "referrers", Value: bson.A{
+				bson.D{{Key: "_id", Value: "(direct)"}, {Key: "clicks", Value: 2}},
+				bson.D{{Key: "_id", Value: "https://news.example.org"}, {Key: "clicks", Value: 1}},
+			}},
+			{Key: "browsers", Value: bson.A{
+				bson.D{{Key: "_id", Value: "Firefox"}, {Key: "clicks", Value: 3}},
+			}},
+		})
+		killCursor := mtest.CreateCursorResponse(0, "trunc8-db.clicks", mtest.NextBatch)
+		mt.AddMockResponses(first, killCursor)
+
+		stats, err := repo.ClickStats(context.Background(), query)
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if stats.TotalClicks != 3 {
+			t.Errorf("Expected 3 total clicks, got %d", stats.TotalClicks)
+		}
+
+		if stats.UniqueVisitors != 2 {
+			t.Errorf("Expected 2 unique visitors, got %d", stats.UniqueVisitors)
+		}
+
+		if len(stats.Series) != 1 || !stats.Series[0].Time.Equal(from) {
+			t.Errorf("Unexpected series: %v", stats.Series)
+		}
+
+		if len(stats.TopReferrers) != 2 || stats.TopReferrers[0].Value != "(direct)" {
+			t.Errorf("Unexpected top referrers: %v", stats.TopReferrers)
+		}
+
+		if len(stats.TopUserAgents) != 1 || stats.TopUserAgents[0].Value != "Firefox" {
+			t.Errorf("Unexpected top user agents: %v", stats.TopUserAgents)
+		}
+	})
+
+	mt.Run("no clicks", func(mt *mtest.T) {
+		repo := &ShortenerRepository{clicks: mt.Coll}
+
+		first := mtest.CreateCursorResponse(1, "trunc8-db.clicks", mtest.FirstBatch, bson.D{
+			{Key: "total", Value: bson.A{}},
+			{Key: "unique", Value: bson.A{}},
+			{Key: "series", Value: bson.A{}},
+			{Key: "referrers", Value: bson.A{}},
+			{Key: "browsers", Value: bson.A{}},
+		})
+		killCursor := mtest.CreateCursorResponse(0, "trunc8-db.clicks", mtest.NextBatch)
+		mt.AddMockResponses(first, killCursor)
+
+		stats, err := repo.ClickStats(context.Background(), query)
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if stats.TotalClicks != 0 || stats.UniqueVisitors != 0 {
+			t.Errorf("Expected empty stats, got %d clicks and %d visitors", stats.TotalClicks, stats.UniqueVisitors)
+		}
+	})
+
+	mt.Run("database error", func(mt *mtest.T) {
+		repo := &ShortenerRepository{clicks: mt.Coll}
+
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{
+			Code:    1,
+			Message: "database connection error",
+		}))
+
+		stats, err := repo.ClickStats(context.Background(), query)
+
+		if err == nil {
+			t.Fatal("Expected error, got nil")
+		}
+
+		if stats != nil {
+			t.Error("Expected nil stats when error occurs")
+		}
+	})
+}
+
+func TestShortenerRepository_NextSequence(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("success", func(mt *mtest.T) {
+		repo := &ShortenerRepository{counters: mt.Coll}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(
+			bson.E{Key: "value", Value: bson.D{
+				{Key: "_id", Value: "links"},
+				{Key: "seq", Value: int64(42)},
+			}},
+		))
+
+		seq, err := repo.NextSequence(context.Background(), "links")
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if seq != 42 {
+			t.Errorf("Expected sequence 42, got %d", seq)
+		}
+	})
+
+	mt.Run("database error", func(mt *mtest.T) {
+		repo := &ShortenerRepository{counters: mt.Coll}
+
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{
+			Code:    1,
+			Message: "database connection error",
+		}))
+
+		if _, err := repo.NextSequence(context.Background(), "links"); err == nil {
+			t.Fatal("Expected error, got nil")
+		}
+	})
+}
+
+func TestShortenerRepository_Update(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("success", func(mt *mtest.T) {
+		repo := &ShortenerRepository{collection: mt.Coll}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(
+			bson.E{Key: "value", Value: bson.D{
+				{Key: "original_url", Value: "https://example.com/new"},
+				{Key: "code", Value: "TEST"},
+				{Key: "redirect_type", Value: 307},
+			}},
+		))
+
+		newURL := "https://example.com/new"
+		redirectType := 307
+		url, err := repo.Update(context.Background(), "", "TEST", models.LinkUpdate{
+			OriginalURL:  &newURL,
+			RedirectType: &redirectType,
+		})
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if url.OriginalURL != "https://example.com/new" || url.RedirectType != 307 {
+			t.Errorf("Unexpected link returned: %+v", url)
+		}
+	})
+
+	mt.Run("not found", func(mt *mtest.T) {
+		repo := &ShortenerRepository{collection: mt.Coll}
+
+		// findAndModify answers with a null value when no document matches
+		mt.AddMockResponses(mtest.CreateSuccessResponse(
+			bson.E{Key: "value", Value: nil},
+		))
+
+		disabled := true
+		_, err := repo.Update(context.Background(), "", "MISSING", models.LinkUpdate{Disabled: &disabled})
+
+		if !errors.Is(err, models.ErrNotFound) {
+			t.Fatalf("Expected ErrNotFound, got %v", err)
+		}
+	})
+
+	mt.Run("database error", func(mt *mtest.T) {
+		repo := &ShortenerRepository{collection: mt.Coll}
+
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{
+			Code:    1,
+			Message: "database connection error",
+		}))
+
+		maxClicks := 0
+		if _, err := repo.Update(context.Background(), "", "TEST", models.LinkUpdate{MaxClicks: &maxClicks}); err == nil {
+			t.Fatal("Expected error, got nil")
+		}
+	})
+}
+
+func TestShortenerRepository_Delete(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("success", func(mt *mtest.T) {
+		repo := &ShortenerRepository{collection: mt.Coll, clicks: mt.Coll}
+
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 12}),
+		)
+
+		if err := repo.Delete(context.Background(), "", "TEST"); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	})
+
+	mt.Run("not found", func(mt *mtest.T) {
+		repo := &ShortenerRepository{collection: mt.Coll, clicks: mt.Coll}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}))
+
+		err := repo.Delete(context.Background(), "", "MISSING")
+
+		if !errors.Is(err, models.ErrNotFound) {
+			t.Fatalf("Expected ErrNotFound, got %v", err)
+		}
+	})
+}
+
+func TestShortenerRepository_List(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("success", func(mt *mtest.T) {
+		repo := &ShortenerRepository{collection: mt.Coll}
+
+		first := mtest.CreateCursorResponse(1, "trunc8-db.links", mtest.FirstBatch,
+			bson.D{{Key: "code", Value: "B"}, {Key: "original_url", Value: "https://example.com/b"}},
+			bson.D{{Key: "code", Value: "A"}, {Key: "original_url", Value: "https://example.com/a"}},
+		)
+		killCursor := mtest.CreateCursorResponse(0, "trunc8-db.links", mtest.NextBatch)
+		mt.AddMockResponses(first, killCursor)
+
+		links, err := repo.List(context.Background(), models.ListQuery{
+			Tag:        "newsletter",
+			Status:     models.StatusActive,
+			Sort:       models.SortCreatedAt,
+			Descending: true,
+			Limit:      3,
+			After:      &models.ListCursor{ID: "665f1f77bcf86cd799439012", CreatedAt: time.Now()},
+			Now:        time.Now(),
+		})
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if len(links) != 2 || links[0].Code != "B" {
+			t.Errorf("Unexpected links: %+v", links)
+		}
+	})
+
+	mt.Run("malformed cursor", func(mt *mtest.T) {
+		repo := &ShortenerRepository{collection: mt.Coll}
+
+		_, err := repo.List(context.Background(), models.ListQuery{
+			Sort:  models.SortClicks,
+			Limit: 3,
+			After: &models.ListCursor{ID: "not-an-object-id"},
+		})
+
+		if !errors.Is(err, models.ErrInvalidListQuery) {
+			t.Fatalf("Expected ErrInvalidListQuery, got %v", err)
+		}
+	})
+}
+
+func TestListFilter(t *testing.T) {
+	now := time.Now()
+
+	filter, err := listFilter(models.ListQuery{Now: now})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(filter) != 0 {
+		t.Errorf("Expected an empty filter without conditions, got %v", filter)
+	}
+
+	filter, err = listFilter(models.ListQuery{OwnerID: "team-growth", Domain: "example.com", Search: "a.b", Now: now})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	conditions, ok := filter["$and"].(bson.A)
+	if !ok || len(conditions) != 3 {
+		t.Fatalf("Expected 3 conditions, got %v", filter)
+	}
+
+	// The search is a literal substring, not a pattern
+	search := conditions[2].(bson.M)["original_url"].(primitive.Regex)
+	if search.Pattern != `a\.b` || search.Options != "i" {
+		t.Errorf("Expected an escaped, case insensitive pattern, got %+v", search)
+	}
+}
+
+func TestInWorkspace(t *testing.T) {
+	filter := inWorkspace(bson.M{"code": "TEST"}, "ws-1")
+	if filter["workspace_id"] != "ws-1" || filter["code"] != "TEST" {
+		t.Errorf("Expected the filter to be limited to ws-1, got %v", filter)
+	}
+
+	filter = inWorkspace(bson.M{"code": "TEST"}, "")
+	if _, ok := filter["workspace_id"]; ok {
+		t.Errorf("Expected no workspace condition for an empty workspace, got %v", filter)
+	}
+}
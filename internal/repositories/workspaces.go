@@ -0,0 +1,128 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/topboyasante/trunc8/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type WorkspaceRepository struct {
+	workspaces *mongo.Collection
+	users      *mongo.Collection
+}
+
+func NewWorkspaceRepository(db *mongo.Database) *WorkspaceRepository {
+	return &WorkspaceRepository{
+		workspaces: db.Collection("workspaces"),
+		users:      db.Collection("users"),
+	}
+}
+
+// EnsureIndexes makes emails unique, so a person can't end up in two workspaces by accident.
+func (r *WorkspaceRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.users.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "email", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "workspace_id", Value: 1}},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("creating users indexes: %w", err)
+	}
+	return nil
+}
+
+func (r *WorkspaceRepository) CreateWorkspace(ctx context.Context, workspace models.Workspace) (string, error) {
+	return insertOne(ctx, r.workspaces, workspace)
+}
+
+func (r *WorkspaceRepository) FindWorkspace(ctx context.Context, id string) (*models.Workspace, error) {
+	var workspace models.Workspace
+	if err := findByID(ctx, r.workspaces, id, &workspace); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("%w: %s", models.ErrWorkspaceNotFound, id)
+		}
+		return nil, err
+	}
+	return &workspace, nil
+}
+
+func (r *WorkspaceRepository) ListWorkspaces(ctx context.Context) ([]*models.Workspace, error) {
+	var workspaces []*models.Workspace
+	if err := findAll(ctx, r.workspaces, bson.M{}, &workspaces); err != nil {
+		return nil, err
+	}
+	return workspaces, nil
+}
+
+func (r *WorkspaceRepository) CreateUser(ctx context.Context, user models.User) (string, error) {
+	id, err := insertOne(ctx, r.users, user)
+	if mongo.IsDuplicateKeyError(err) {
+		return "", fmt.Errorf("%w: %s", models.ErrEmailTaken, user.Email)
+	}
+	return id, err
+}
+
+func (r *WorkspaceRepository) FindUser(ctx context.Context, id string) (*models.User, error) {
+	var user models.User
+	if err := findByID(ctx, r.users, id, &user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("%w: %s", models.ErrUserNotFound, id)
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// ListUsers returns the members of a workspace, oldest first.
+func (r *WorkspaceRepository) ListUsers(ctx context.Context, workspaceID string) ([]*models.User, error) {
+	var users []*models.User
+	if err := findAll(ctx, r.users, bson.M{"workspace_id": workspaceID}, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// insertOne inserts document and returns the ObjectID Mongo gave it, as a hex string.
+func insertOne(ctx context.Context, collection *mongo.Collection, document interface{}) (string, error) {
+	result, err := collection.InsertOne(ctx, document)
+	if err != nil {
+		return "", err
+	}
+	id, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return "", fmt.Errorf("expected ObjectID for InsertedID, got %T", result.InsertedID)
+	}
+	return id.Hex(), nil
+}
+
+// findByID decodes the document with the given hex ObjectID into v.
+// An ID that isn't an ObjectID can't match anything, so it's reported as mongo.ErrNoDocuments.
+func findByID(ctx context.Context, collection *mongo.Collection, id string, v interface{}) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return mongo.ErrNoDocuments
+	}
+	return collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(v)
+}
+
+// findAll decodes every document matching filter into v, oldest first.
+func findAll(ctx context.Context, collection *mongo.Collection, filter bson.M, v interface{}) error {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	return cursor.All(ctx, v)
+}
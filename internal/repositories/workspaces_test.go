@@ -0,0 +1,95 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/topboyasante/trunc8/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestWorkspaceRepository_FindUser(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("success", func(mt *mtest.T) {
+		repo := &WorkspaceRepository{users: mt.Coll}
+
+		first := mtest.CreateCursorResponse(1, "trunc8-db.users", mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: "665f1f77bcf86cd799439011"},
+			{Key: "workspace_id", Value: "665f1f77bcf86cd799439099"},
+			{Key: "name", Value: "Ama"},
+		})
+		killCursor := mtest.CreateCursorResponse(0, "trunc8-db.users", mtest.NextBatch)
+		mt.AddMockResponses(first, killCursor)
+
+		user, err := repo.FindUser(context.Background(), "665f1f77bcf86cd799439011")
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if user.WorkspaceID != "665f1f77bcf86cd799439099" {
+			t.Errorf("Unexpected user: %+v", user)
+		}
+	})
+
+	mt.Run("not found", func(mt *mtest.T) {
+		repo := &WorkspaceRepository{users: mt.Coll}
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "trunc8-db.users", mtest.FirstBatch))
+
+		_, err := repo.FindUser(context.Background(), "665f1f77bcf86cd799439011")
+
+		if !errors.Is(err, models.ErrUserNotFound) {
+			t.Fatalf("Expected ErrUserNotFound, got %v", err)
+		}
+	})
+
+	mt.Run("malformed id", func(mt *mtest.T) {
+		repo := &WorkspaceRepository{users: mt.Coll}
+
+		_, err := repo.FindUser(context.Background(), "user-1")
+
+		if !errors.Is(err, models.ErrUserNotFound) {
+			t.Fatalf("Expected ErrUserNotFound, got %v", err)
+		}
+	})
+}
+
+func TestWorkspaceRepository_CreateUser(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("duplicate email", func(mt *mtest.T) {
+		repo := &WorkspaceRepository{users: mt.Coll}
+
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{
+			Index:   0,
+			Code:    11000,
+			Message: "duplicate key error",
+		}))
+
+		_, err := repo.CreateUser(context.Background(), models.User{Email: "ama@example.com"})
+
+		if !errors.Is(err, models.ErrEmailTaken) {
+			t.Fatalf("Expected ErrEmailTaken, got %v", err)
+		}
+	})
+}
+
+func TestWorkspaceRepository_FindWorkspace(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("not found", func(mt *mtest.T) {
+		repo := &WorkspaceRepository{workspaces: mt.Coll}
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "trunc8-db.workspaces", mtest.FirstBatch))
+
+		_, err := repo.FindWorkspace(context.Background(), "665f1f77bcf86cd799439011")
+
+		if !errors.Is(err, models.ErrWorkspaceNotFound) {
+			t.Fatalf("Expected ErrWorkspaceNotFound, got %v", err)
+		}
+	})
+}
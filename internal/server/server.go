@@ -1,32 +1,157 @@
 package server
 
 import (
+	"context"
+	"fmt"
+	"log/slog"
 	"net/http"
 
+	"github.com/topboyasante/trunc8/internal/codegen"
 	"github.com/topboyasante/trunc8/internal/config"
 	"github.com/topboyasante/trunc8/internal/handlers"
+	"github.com/topboyasante/trunc8/internal/metrics"
+	"github.com/topboyasante/trunc8/internal/middleware"
+	"github.com/topboyasante/trunc8/internal/models"
+	"github.com/topboyasante/trunc8/internal/ratelimit"
 	"github.com/topboyasante/trunc8/internal/repositories"
 	"github.com/topboyasante/trunc8/internal/services"
 )
 
-func InitServer(cfg *config.Config) *http.Server {
-	// Initialize repository
-	repo := repositories.NewShortnerRepository()
+// InitServer wires the services and handlers on top of repo, which the caller owns and closes.
+// Requests, redirects and the cache are reported to m; the caller instruments repo itself,
+// so storage calls made outside of requests are measured too. ctx bounds the state loaded from repo on startup.
+func InitServer(ctx context.Context, cfg *config.Config, repo repositories.Repository, m *metrics.Metrics) (*http.Server, error) {
+	generator, err := newCodeGenerator(cfg, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	// Redirects look links up through the cache, so only the shortener service goes through it
+	links := repo
+	// Left nil when the cache is off, a nil *CachedRepository would not compare equal to nil in the handler
+	var cacheStats handlers.CacheStatsReporter
+	if cfg.Cache.Size > 0 {
+		cached := repositories.NewCachedRepository(repo, cfg.Cache.Size, cfg.Cache.TTL, cfg.Cache.NegativeTTL)
+		links, cacheStats = cached, cached
+		m.RegisterCache(cached)
+	}
 
 	// Initialize service with repository
-	service := services.NewShortnerService(repo)
+	service := services.NewShortnerService(links, generator, cfg)
+	service.SetMetrics(m)
+	if err := service.LoadCodeLength(ctx); err != nil {
+		return nil, err
+	}
 
 	// Initialize handler with service
-	handler := handlers.NewShortnerHandler(service)
+	handler := handlers.NewShortnerHandler(services.NewTracedShortnerService(service))
+
+	workspaceHandler := handlers.NewWorkspaceHandler(services.NewWorkspaceService(repo))
+
+	keyService := services.NewAPIKeyService(repo, repo, cfg)
+	keyHandler := handlers.NewAPIKeyHandler(keyService)
+
+	healthHandler := handlers.NewHealthHandler(repo, cacheStats)
+
+	if cfg.Auth.AdminAPIKey == "" {
+		slog.Warn("ADMIN_API_KEY is not set, only API keys that already exist can be used")
+	}
+	if cfg.Analytics.IPHashSecret == "" {
+		slog.Warn("IP_HASH_SECRET is not set, unique visitors are only recognized until the next restart and per instance")
+	}
 
 	mux := http.NewServeMux()
 
+	mux.HandleFunc("/healthz", healthHandler.Healthz)
+	mux.HandleFunc("/readyz", healthHandler.Readyz)
+	mux.Handle("/metrics", m.Handler())
 	mux.HandleFunc("/shorten", handler.ShortenURL)
 	mux.HandleFunc("/{code}", handler.RedirectURL)
+	mux.HandleFunc("/api/links", handler.ListLinks)
+	// POST only, so a link with the code "bulk" can still be read, changed and deleted
+	mux.HandleFunc("POST /api/links/bulk", handler.BulkShortenURLs)
+	mux.HandleFunc("/api/links/{code}", handler.Link)
+	mux.HandleFunc("/api/links/{code}/stats", handler.LinkStats)
+	mux.HandleFunc("/api/links/{code}/disable", handler.DisableLink)
+	mux.HandleFunc("/api/links/{code}/enable", handler.EnableLink)
+	mux.HandleFunc("/api/keys", keyHandler.APIKeys)
+	mux.HandleFunc("/api/keys/{id}", keyHandler.APIKey)
+	mux.HandleFunc("/api/workspaces", workspaceHandler.Workspaces)
+	mux.HandleFunc("/api/workspaces/{id}/users", workspaceHandler.Users)
 
 	server := &http.Server{
-		Addr:    ":" + cfg.Server.Port,
-		Handler: mux,
+		Addr: ":" + cfg.Server.Port,
+		Handler: middleware.Chain(mux,
+			// The first one runs first. Tracing comes before everything so the rest sees the span,
+			// and the client IP is found before it is logged or rate limited. Tracing, logging and
+			// metrics all run before authentication and rate limiting, so the requests those
+			// reject are traced, logged and counted too.
+			middleware.Tracing(mux),
+			middleware.ClientIP(cfg.Server.TrustedProxies),
+			middleware.RequestLog(mux),
+			middleware.Metrics(mux, m),
+			middleware.RequireAPIKey(mux, keyService, routeScopes),
+			// After authentication, so clients with a key are limited per key
+			middleware.RateLimit(mux, newRateLimiters(cfg), perItemRoutes),
+		),
+	}
+	return server, nil
+}
+
+// routeScopes is the scope an API key needs for each route.
+// Routes that aren't listed, like /api/keys and /api/workspaces, need the admin scope.
+// /metrics is public because scrapers don't send API keys; block it at the load balancer if traffic figures are sensitive.
+var routeScopes = map[string]string{
+	"/{code}":                        middleware.Public,
+	"/healthz":                       middleware.Public,
+	"/readyz":                        middleware.Public,
+	"/metrics":                       middleware.Public,
+	"/shorten":                       models.ScopeLinksWrite,
+	"GET /api/links":                 models.ScopeLinksRead,
+	"POST /api/links/bulk":           models.ScopeLinksWrite,
+	"GET /api/links/{code}":          models.ScopeLinksRead,
+	"PATCH /api/links/{code}":        models.ScopeLinksWrite,
+	"DELETE /api/links/{code}":       models.ScopeLinksWrite,
+	"POST /api/links/{code}/disable": models.ScopeLinksWrite,
+	"POST /api/links/{code}/enable":  models.ScopeLinksWrite,
+	"GET /api/links/{code}/stats":    models.ScopeStatsRead,
+}
+
+// newRateLimiters builds the limiter of each rate limited route.
+func newRateLimiters(cfg *config.Config) map[string]*ratelimit.Limiter {
+	limiters := map[string]*ratelimit.Limiter{}
+	if cfg.RateLimit.Shorten > 0 {
+		// Bulk requests draw from the same budget, one token per link, so they can't be used to get around it
+		shorten := ratelimit.New(cfg.RateLimit.Shorten, cfg.RateLimit.ShortenBurst)
+		limiters["/shorten"] = shorten
+		limiters["POST /api/links/bulk"] = shorten
+	}
+	if cfg.RateLimit.Redirect > 0 {
+		limiters["/{code}"] = ratelimit.New(cfg.RateLimit.Redirect, cfg.RateLimit.RedirectBurst)
+	}
+	return limiters
+}
+
+// perItemRoutes are the rate limited routes whose handler charges a token per item rather than per request.
+var perItemRoutes = map[string]bool{
+	"POST /api/links/bulk": true,
+}
+
+// newCodeGenerator builds the code generation strategy picked in the config.
+func newCodeGenerator(cfg *config.Config, sequence codegen.Sequence) (services.CodeGenerator, error) {
+	switch cfg.Shortener.CodeStrategy {
+	case "random":
+		return codegen.NewRandom(), nil
+	case "counter":
+		return codegen.NewCounter(sequence), nil
+	case "hashids":
+		if cfg.Shortener.HashidsSalt == "" {
+			slog.Warn("HASHIDS_SALT is not set, hashids codes will be easy to decode")
+		}
+		return codegen.NewHashids(sequence, cfg.Shortener.HashidsSalt, cfg.Shortener.CodeLength), nil
+	case "hash":
+		return codegen.NewURLHash(), nil
+	default:
+		return nil, fmt.Errorf("unknown code strategy %q", cfg.Shortener.CodeStrategy)
 	}
-	return server
 }
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/topboyasante/trunc8/internal/auth"
+	"github.com/topboyasante/trunc8/internal/config"
+	"github.com/topboyasante/trunc8/internal/models"
+	"github.com/topboyasante/trunc8/internal/types"
+)
+
+// APIKeyRepositoryInterface defines the interface for API key storage
+type APIKeyRepositoryInterface interface {
+	CreateAPIKey(ctx context.Context, key models.APIKey) (string, error)
+	FindAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error)
+	ListAPIKeys(ctx context.Context) ([]*models.APIKey, error)
+	DeleteAPIKey(ctx context.Context, id string) error
+}
+
+// UserFinder looks up the user a key is issued to
+type UserFinder interface {
+	FindUser(ctx context.Context, id string) (*models.User, error)
+}
+
+type APIKeyService struct {
+	repository APIKeyRepositoryInterface
+	users      UserFinder
+
+	// adminKeyHash is the hash of ADMIN_API_KEY, empty when it isn't set
+	adminKeyHash string
+}
+
+// adminKey is the key requests made with ADMIN_API_KEY are attributed to
+var adminKey = &models.APIKey{
+	ID:     "admin",
+	Name:   "ADMIN_API_KEY",
+	Scopes: []string{models.ScopeAdmin},
+}
+
+func NewAPIKeyService(repository APIKeyRepositoryInterface, users UserFinder, cfg *config.Config) *APIKeyService {
+	service := &APIKeyService{repository: repository, users: users}
+	if cfg.Auth.AdminAPIKey != "" {
+		service.adminKeyHash = auth.HashKey(cfg.Auth.AdminAPIKey)
+	}
+	return service
+}
+
+// Authenticate returns the API key matching key, or ErrAPIKeyNotFound.
+// ADMIN_API_KEY always works, so there is a way in before any key has been created.
+func (s *APIKeyService) Authenticate(ctx context.Context, key string) (*models.APIKey, error) {
+	if key == "" {
+		return nil, models.ErrAPIKeyNotFound
+	}
+
+	hash := auth.HashKey(key)
+	if s.adminKeyHash != "" && subtle.ConstantTimeCompare([]byte(hash), []byte(s.adminKeyHash)) == 1 {
+		return adminKey, nil
+	}
+
+	apiKey, err := s.repository.FindAPIKeyByHash(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only admin keys may see every workspace, a key stored without one would otherwise see them all
+	if apiKey.WorkspaceID == "" && !apiKey.HasScope(models.ScopeAdmin) {
+		slog.WarnContext(ctx, "Rejected an API key without a workspace, issue a new one to a user", "prefix", apiKey.Prefix)
+		return nil, fmt.Errorf("%w: key has no workspace", models.ErrAPIKeyNotFound)
+	}
+
+	return apiKey, nil
+}
+
+// CreateAPIKey creates a key with the requested scopes.
+// The response holds the key itself; only its hash is stored, so it can't be shown again.
+func (s *APIKeyService) CreateAPIKey(ctx context.Context, req types.CreateAPIKeyRequest) (*types.CreateAPIKeyResponse, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 100 {
+		return nil, fmt.Errorf("%w: name must be 1 to 100 characters", models.ErrInvalidAPIKey)
+	}
+
+	if len(req.Scopes) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", models.ErrInvalidAPIKey)
+	}
+	for _, scope := range req.Scopes {
+		if !models.Scopes[scope] {
+			return nil, fmt.Errorf("%w: unknown scope %q", models.ErrInvalidAPIKey, scope)
+		}
+	}
+
+	apiKey := &models.APIKey{
+		Name:      name,
+		Scopes:    req.Scopes,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	// Admin keys manage the whole deployment, including other keys, so they can't belong to
+	// a workspace. Every other key is limited to the workspace of its user.
+	if apiKey.HasScope(models.ScopeAdmin) {
+		if req.UserID != "" {
+			return nil, fmt.Errorf("%w: admin keys can't be issued to a user", models.ErrInvalidAPIKey)
+		}
+	} else {
+		if req.UserID == "" {
+			return nil, fmt.Errorf("%w: user_id is required for keys without the admin scope", models.ErrInvalidAPIKey)
+		}
+		user, err := s.users.FindUser(ctx, req.UserID)
+		if err != nil {
+			return nil, err
+		}
+		apiKey.UserID = user.ID
+		apiKey.WorkspaceID = user.WorkspaceID
+	}
+
+	key, err := auth.GenerateKey()
+	if err != nil {
+		return nil, fmt.Errorf("generating key: %w", err)
+	}
+
+	apiKey.Prefix = key[:auth.PrefixLength]
+	apiKey.KeyHash = auth.HashKey(key)
+
+	id, err := s.repository.CreateAPIKey(ctx, *apiKey)
+	if err != nil {
+		return nil, err
+	}
+	apiKey.ID = id
+
+	return &types.CreateAPIKeyResponse{APIKey: apiKey, Key: key}, nil
+}
+
+func (s *APIKeyService) ListAPIKeys(ctx context.Context) ([]*models.APIKey, error) {
+	keys, err := s.repository.ListAPIKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if keys == nil {
+		keys = []*models.APIKey{}
+	}
+	return keys, nil
+}
+
+func (s *APIKeyService) DeleteAPIKey(ctx context.Context, id string) error {
+	return s.repository.DeleteAPIKey(ctx, id)
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/topboyasante/trunc8/internal/auth"
+	"github.com/topboyasante/trunc8/internal/config"
+	"github.com/topboyasante/trunc8/internal/models"
+	"github.com/topboyasante/trunc8/internal/types"
+)
+
+// Mock API key repository for testing
+type mockAPIKeyRepository struct {
+	createAPIKeyFunc     func(ctx context.Context, key models.APIKey) (string, error)
+	findAPIKeyByHashFunc func(ctx context.Context, hash string) (*models.APIKey, error)
+	listAPIKeysFunc      func(ctx context.Context) ([]*models.APIKey, error)
+	deleteAPIKeyFunc     func(ctx context.Context, id string) error
+}
+
+func (m *mockAPIKeyRepository) CreateAPIKey(ctx context.Context, key models.APIKey) (string, error) {
+	if m.createAPIKeyFunc != nil {
+		return m.createAPIKeyFunc(ctx, key)
+	}
+	return "key-id", nil
+}
+
+func (m *mockAPIKeyRepository) FindAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	if m.findAPIKeyByHashFunc != nil {
+		return m.findAPIKeyByHashFunc(ctx, hash)
+	}
+	return nil, models.ErrAPIKeyNotFound
+}
+
+func (m *mockAPIKeyRepository) ListAPIKeys(ctx context.Context) ([]*models.APIKey, error) {
+	if m.listAPIKeysFunc != nil {
+		return m.listAPIKeysFunc(ctx)
+	}
+	return nil, nil
+}
+
+func (m *mockAPIKeyRepository) DeleteAPIKey(ctx context.Context, id string) error {
+	if m.deleteAPIKeyFunc != nil {
+		return m.deleteAPIKeyFunc(ctx, id)
+	}
+	return nil
+}
+
+func TestCreateAPIKey_Success(t *testing.T) {
+	var stored models.APIKey
+	mockRepo := &mockAPIKeyRepository{
+		createAPIKeyFunc: func(ctx context.Context, key models.APIKey) (string, error) {
+			stored = key
+			return "key-id", nil
+		},
+	}
+
+	service := NewAPIKeyService(mockRepo, &mockWorkspaceRepository{}, &config.Config{})
+
+	res, err := service.CreateAPIKey(context.Background(), types.CreateAPIKeyRequest{
+		Name:   " newsletter tooling ",
+		Scopes: []string{models.ScopeLinksWrite, models.ScopeStatsRead},
+		UserID: "user-1",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// The key can only reach the workspace of its user
+	if stored.UserID != "user-1" || stored.WorkspaceID != "ws-1" {
+		t.Errorf("Expected the key to belong to user-1 in ws-1, got %s in %s", stored.UserID, stored.WorkspaceID)
+	}
+
+	if res.ID != "key-id" || res.Name != "newsletter tooling" {
+		t.Errorf("Unexpected key: %+v", res.APIKey)
+	}
+
+	// Only the hash of the key is stored
+	if stored.KeyHash != auth.HashKey(res.Key) {
+		t.Error("Expected the hash of the returned key to be stored")
+	}
+
+	if !strings.HasPrefix(res.Key, stored.Prefix) || len(stored.Prefix) >= len(res.Key) {
+		t.Errorf("Expected prefix '%s' to be the start of the key", stored.Prefix)
+	}
+}
+
+func TestCreateAPIKey_InvalidRequest(t *testing.T) {
+	tests := []types.CreateAPIKeyRequest{
+		{Name: "", Scopes: []string{models.ScopeLinksRead}},
+		{Name: "ci", Scopes: nil},
+		{Name: "ci", Scopes: []string{"links:delete"}, UserID: "user-1"},
+		{Name: "ci", Scopes: []string{models.ScopeLinksWrite}},
+		{Name: "ops", Scopes: []string{models.ScopeAdmin}, UserID: "user-1"},
+	}
+
+	for _, req := range tests {
+		service := NewAPIKeyService(&mockAPIKeyRepository{}, &mockWorkspaceRepository{}, &config.Config{})
+
+		_, err := service.CreateAPIKey(context.Background(), req)
+		if !errors.Is(err, models.ErrInvalidAPIKey) {
+			t.Errorf("Expected ErrInvalidAPIKey for %+v, got %v", req, err)
+		}
+	}
+}
+
+func TestCreateAPIKey_UnknownUser(t *testing.T) {
+	users := &mockWorkspaceRepository{
+		findUserFunc: func(ctx context.Context, id string) (*models.User, error) {
+			return nil, models.ErrUserNotFound
+		},
+	}
+
+	service := NewAPIKeyService(&mockAPIKeyRepository{}, users, &config.Config{})
+
+	_, err := service.CreateAPIKey(context.Background(), types.CreateAPIKeyRequest{
+		Name:   "ci",
+		Scopes: []string{models.ScopeLinksWrite},
+		UserID: "missing",
+	})
+	if !errors.Is(err, models.ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound, got %v", err)
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	mockRepo := &mockAPIKeyRepository{
+		findAPIKeyByHashFunc: func(ctx context.Context, hash string) (*models.APIKey, error) {
+			if hash == auth.HashKey("t8_stored") {
+				return &models.APIKey{Name: "stored", Scopes: []string{models.ScopeLinksRead}, WorkspaceID: "ws-1"}, nil
+			}
+			if hash == auth.HashKey("t8_unscoped") {
+				return &models.APIKey{Name: "unscoped", Scopes: []string{models.ScopeLinksRead}}, nil
+			}
+			return nil, models.ErrAPIKeyNotFound
+		},
+	}
+
+	cfg := &config.Config{Auth: config.AuthConfig{AdminAPIKey: "bootstrap-admin-key-0123"}}
+	service := NewAPIKeyService(mockRepo, &mockWorkspaceRepository{}, cfg)
+
+	key, err := service.Authenticate(context.Background(), "t8_stored")
+	if err != nil || key.Name != "stored" {
+		t.Errorf("Expected the stored key, got %v, %v", key, err)
+	}
+
+	key, err = service.Authenticate(context.Background(), "bootstrap-admin-key-0123")
+	if err != nil || !key.HasScope(models.ScopeAdmin) {
+		t.Errorf("Expected ADMIN_API_KEY to authenticate as admin, got %v, %v", key, err)
+	}
+
+	for _, unknown := range []string{"", "t8_unknown", "t8_unscoped"} {
+		if _, err := service.Authenticate(context.Background(), unknown); !errors.Is(err, models.ErrAPIKeyNotFound) {
+			t.Errorf("Expected ErrAPIKeyNotFound for '%s', got %v", unknown, err)
+		}
+	}
+}
+
+func TestAuthenticate_NoAdminKey(t *testing.T) {
+	service := NewAPIKeyService(&mockAPIKeyRepository{}, &mockWorkspaceRepository{}, &config.Config{})
+
+	// Without ADMIN_API_KEY, the hash of an empty key must not match anything
+	if _, err := service.Authenticate(context.Background(), " "); !errors.Is(err, models.ErrAPIKeyNotFound) {
+		t.Errorf("Expected ErrAPIKeyNotFound, got %v", err)
+	}
+}
+
+func TestListAPIKeys_Empty(t *testing.T) {
+	service := NewAPIKeyService(&mockAPIKeyRepository{}, &mockWorkspaceRepository{}, &config.Config{})
+
+	keys, err := service.ListAPIKeys(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if keys == nil {
+		t.Error("Expected an empty, non-nil list")
+	}
+}
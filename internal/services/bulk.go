@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/topboyasante/trunc8/internal/auth"
+	"github.com/topboyasante/trunc8/internal/models"
+	"github.com/topboyasante/trunc8/internal/types"
+)
+
+const (
+	defaultBulkLimit = 500
+
+	// bulkBatchSize is how many links are stored per CreateMany call,
+	// which keeps a single insert well under the parameter limit of Postgres
+	bulkBatchSize = 100
+)
+
+// CheckBulkSize tells whether a batch of n links can be shortened at once, before anything is done with it.
+func (s *ShortnerService) CheckBulkSize(n int) error {
+	if n == 0 {
+		return fmt.Errorf("%w: no links to shorten", models.ErrInvalidBulk)
+	}
+	if n > s.bulkLimit {
+		return fmt.Errorf("%w: at most %d links can be shortened at once, got %d", models.ErrInvalidBulk, s.bulkLimit, n)
+	}
+	return nil
+}
+
+// ShortenMany creates a link for every request, like ShortenURL would, but stores them in batches.
+// links[i] and errs[i] are the outcome of reqs[i]: a request that fails doesn't stop the others.
+// err is only set when the batch as a whole is refused, like when it has too many requests.
+func (s *ShortnerService) ShortenMany(ctx context.Context, reqs []types.ShortenRequest) (links []*models.URL, errs []error, err error) {
+	if err := s.CheckBulkSize(len(reqs)); err != nil {
+		return nil, nil, err
+	}
+
+	links = make([]*models.URL, len(reqs))
+	errs = make([]error, len(reqs))
+
+	// A plain request for a URL that an earlier request of the batch asked for gets the same link
+	firstPlain := map[string]int{}
+	sameAs := map[int]int{}
+
+	var pending []int
+	for i, req := range reqs {
+		req, err := s.prepareRequest(req)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+
+		if isPlainRequest(req) {
+			if first, ok := firstPlain[req.URL]; ok {
+				sameAs[i] = first
+				continue
+			}
+			firstPlain[req.URL] = i
+
+			existing, err := s.repository.FindByOriginalURL(ctx, auth.WorkspaceID(ctx), req.URL)
+			if err == nil {
+				links[i] = existing
+				continue
+			}
+			if !errors.Is(err, models.ErrNotFound) {
+				errs[i] = err
+				continue
+			}
+		}
+
+		// Links without an alias get their code in createMany
+		links[i] = newURL(ctx, req, req.Alias)
+		pending = append(pending, i)
+	}
+
+	s.createMany(ctx, links, errs, pending)
+
+	for i, first := range sameAs {
+		links[i], errs[i] = links[first], errs[first]
+	}
+	return links, errs, nil
+}
+
+// createMany stores links[i] for every i in pending, and records what went wrong in errs[i].
+// Links without a code get a generated one, which is replaced when it turns out to be taken,
+// the same way ShortenURL retries: up to maxCodeAttempts times, growing the codes when they keep
+// colliding unless the generator derives them from the URL.
+func (s *ShortnerService) createMany(ctx context.Context, links []*models.URL, errs []error, pending []int) {
+	generated := make(map[int]bool, len(pending))
+	for _, i := range pending {
+		generated[i] = links[i].Code == ""
+	}
+	// attempts counts the codes each link has been given so far, and taken holds the codes
+	// of the batch, so a link isn't given a code another one of the batch already has
+	attempts := make(map[int]int, len(pending))
+	taken := make(map[string]bool, len(pending))
+	for _, i := range pending {
+		if !generated[i] {
+			taken[links[i].Code] = true
+		}
+	}
+
+	fail := func(i int, err error) {
+		links[i], errs[i] = nil, err
+	}
+
+	for round := 0; len(pending) > 0; round++ {
+		if round == maxCodeAttempts {
+			for _, i := range pending {
+				fail(i, fmt.Errorf("could not generate a unique code after %d attempts", maxCodeAttempts))
+			}
+			return
+		}
+
+		// Every link still pending has collided on each round so far
+		length := int(s.codeLength.Load())
+		if round > 0 && round%collisionsBeforeGrowth == 0 && s.growCodes {
+			s.growCodeLength(ctx, length)
+			length = int(s.codeLength.Load())
+		}
+
+		var batch []int
+		for _, i := range pending {
+			if generated[i] {
+				code, attempt, err := s.generateUnused(ctx, links[i].OriginalURL, length, attempts[i], taken)
+				attempts[i] = attempt
+				if err != nil {
+					fail(i, fmt.Errorf("generating code: %w", err))
+					continue
+				}
+				links[i].Code = code
+				taken[code] = true
+			}
+			batch = append(batch, i)
+		}
+
+		var retry []int
+		for start := 0; start < len(batch); start += bulkBatchSize {
+			chunk := batch[start:min(start+bulkBatchSize, len(batch))]
+
+			urls := make([]models.URL, len(chunk))
+			for n, i := range chunk {
+				urls[n] = *links[i]
+			}
+
+			ids, createErrs := s.repository.CreateMany(ctx, urls)
+			for n, i := range chunk {
+				switch err := createErrs[n]; {
+				case err == nil:
+					links[i].ID = ids[n]
+				case errors.Is(err, models.ErrDuplicateCode) && generated[i]:
+					s.metrics.CodeRetry()
+					attempts[i]++
+					retry = append(retry, i)
+				case errors.Is(err, models.ErrDuplicateCode):
+					fail(i, fmt.Errorf("%w: %s", models.ErrAliasTaken, links[i].Code))
+				default:
+					fail(i, err)
+				}
+			}
+		}
+		pending = retry
+	}
+}
+
+// generateUnused generates a code starting at the given attempt, skipping the codes already taken
+// by other links of the batch, and returns the attempt it was made on. A generator that derives codes
+// from the URL gives the rows of a batch with the same URL the same code until their attempts differ.
+func (s *ShortnerService) generateUnused(ctx context.Context, originalURL string, length, attempt int, taken map[string]bool) (string, int, error) {
+	for {
+		code, err := s.generator.Generate(ctx, originalURL, length, attempt)
+		if err != nil || !taken[code] || attempt >= maxCodeAttempts {
+			return code, attempt, err
+		}
+		attempt++
+	}
+}
@@ -0,0 +1,221 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/topboyasante/trunc8/internal/codegen"
+	"github.com/topboyasante/trunc8/internal/config"
+	"github.com/topboyasante/trunc8/internal/models"
+	"github.com/topboyasante/trunc8/internal/types"
+)
+
+func TestShortenMany_ReportsEachRow(t *testing.T) {
+	var batches []int
+	mockRepo := &mockShortenerRepository{
+		createManyFunc: func(ctx context.Context, urls []models.URL) ([]string, []error) {
+			batches = append(batches, len(urls))
+			ids := make([]string, len(urls))
+			errs := make([]error, len(urls))
+			for i, url := range urls {
+				if url.Code == "taken" {
+					errs[i] = fmt.Errorf("%w: %s", models.ErrDuplicateCode, url.Code)
+					continue
+				}
+				ids[i] = "id-" + url.Code
+			}
+			return ids, errs
+		},
+	}
+
+	service := NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{})
+
+	var reqs []types.ShortenRequest
+	for i := 0; i < bulkBatchSize+20; i++ {
+		reqs = append(reqs, types.ShortenRequest{URL: fmt.Sprintf("https://example.com/%d", i)})
+	}
+	reqs = append(reqs,
+		types.ShortenRequest{URL: "not a url"},
+		types.ShortenRequest{URL: "https://example.com/launch", Alias: "taken"},
+		types.ShortenRequest{URL: "https://example.com/launch", Alias: "launch-2026", Tags: []string{"Newsletter"}},
+	)
+
+	links, errs, err := service.ShortenMany(context.Background(), reqs)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(batches) != 2 || batches[0] != bulkBatchSize || batches[1] != 22 {
+		t.Errorf("Expected batches of %d and 22 links, got %v", bulkBatchSize, batches)
+	}
+
+	for i := 0; i < bulkBatchSize+20; i++ {
+		if errs[i] != nil || links[i] == nil || links[i].ID != "id-"+links[i].Code {
+			t.Fatalf("Expected row %d to be created, got %v and %v", i, links[i], errs[i])
+		}
+	}
+
+	last := len(reqs) - 1
+	if !errors.Is(errs[last-2], models.ErrInvalidURL) || links[last-2] != nil {
+		t.Errorf("Expected an invalid URL, got %v", errs[last-2])
+	}
+	if !errors.Is(errs[last-1], models.ErrAliasTaken) || links[last-1] != nil {
+		t.Errorf("Expected the alias to be taken, got %v", errs[last-1])
+	}
+	if errs[last] != nil || links[last].Code != "launch-2026" || links[last].Tags[0] != "newsletter" {
+		t.Errorf("Expected the link under its alias with normalized tags, got %v and %v", links[last], errs[last])
+	}
+}
+
+func TestShortenMany_RetriesDuplicateCodes(t *testing.T) {
+	var calls [][]string
+	mockRepo := &mockShortenerRepository{
+		createManyFunc: func(ctx context.Context, urls []models.URL) ([]string, []error) {
+			var codes []string
+			errs := make([]error, len(urls))
+			for i, url := range urls {
+				codes = append(codes, url.Code)
+				// The code of the first link collides the first time around
+				if len(calls) == 0 && i == 0 {
+					errs[i] = models.ErrDuplicateCode
+				}
+			}
+			calls = append(calls, codes)
+			return make([]string, len(urls)), errs
+		},
+	}
+
+	service := NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{})
+
+	links, errs, err := service.ShortenMany(context.Background(), []types.ShortenRequest{
+		{URL: "https://example.com"},
+		{URL: "https://example.org"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(calls) != 2 || len(calls[1]) != 1 {
+		t.Fatalf("Expected the colliding link alone to be inserted again, got %v", calls)
+	}
+	if errs[0] != nil || links[0].Code != calls[1][0] {
+		t.Errorf("Expected the first link under its second code '%s', got %v and %v", calls[1][0], links[0], errs[0])
+	}
+	if errs[1] != nil || links[1].Code != calls[0][1] {
+		t.Errorf("Expected the second link under its first code '%s', got %v and %v", calls[0][1], links[1], errs[1])
+	}
+}
+
+func TestShortenMany_ReusesPlainLinks(t *testing.T) {
+	created := 0
+	mockRepo := &mockShortenerRepository{
+		findByOriginalURLFunc: func(ctx context.Context, originalURL string) (*models.URL, error) {
+			if originalURL == "https://example.com" {
+				return &models.URL{ID: "existing-id", OriginalURL: originalURL, Code: "abcd"}, nil
+			}
+			return nil, models.ErrNotFound
+		},
+		createFunc: func(ctx context.Context, url models.URL) (string, error) {
+			created++
+			return "test-id", nil
+		},
+	}
+
+	service := NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{})
+
+	links, errs, err := service.ShortenMany(context.Background(), []types.ShortenRequest{
+		{URL: "https://example.com"},
+		{URL: "https://example.org"},
+		{URL: "https://EXAMPLE.org"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if errs[0] != nil || links[0].Code != "abcd" {
+		t.Errorf("Expected the existing link, got %v and %v", links[0], errs[0])
+	}
+	// The same destination twice in a batch is only created once
+	if created != 1 {
+		t.Errorf("Expected 1 link to be created, got %d", created)
+	}
+	if errs[1] != nil || errs[2] != nil || links[1] != links[2] {
+		t.Errorf("Expected both rows to share the new link, got %v and %v", links[1], links[2])
+	}
+}
+
+func TestShortenMany_InvalidBatch(t *testing.T) {
+	service := NewShortnerService(&mockShortenerRepository{}, codegen.NewRandom(), &config.Config{
+		Shortener: config.ShortenerConfig{BulkLimit: 2},
+	})
+
+	tests := map[string][]types.ShortenRequest{
+		"empty":    nil,
+		"too many": {{URL: "https://example.com/1"}, {URL: "https://example.com/2"}, {URL: "https://example.com/3"}},
+	}
+
+	for name, reqs := range tests {
+		if _, _, err := service.ShortenMany(context.Background(), reqs); !errors.Is(err, models.ErrInvalidBulk) {
+			t.Errorf("%s: expected ErrInvalidBulk, got %v", name, err)
+		}
+	}
+}
+
+func TestShortenMany_HashDuplicatesKeepCodeLength(t *testing.T) {
+	taken := map[string]bool{}
+	rounds := 0
+	mockRepo := &mockShortenerRepository{
+		createManyFunc: func(ctx context.Context, urls []models.URL) ([]string, []error) {
+			rounds++
+			errs := make([]error, len(urls))
+			for i, url := range urls {
+				if taken[url.Code] {
+					errs[i] = models.ErrDuplicateCode
+					continue
+				}
+				taken[url.Code] = true
+			}
+			return make([]string, len(urls)), errs
+		},
+	}
+
+	service := NewShortnerService(mockRepo, codegen.NewURLHash(), &config.Config{})
+	ctx := context.Background()
+
+	// The URL was shortened before, so its first code is taken already
+	first, err := codegen.NewURLHash().Generate(ctx, "https://example.com", minCodeLength, 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	taken[first] = true
+
+	// Rows with tags aren't merged, so each of them needs a code of its own
+	var reqs []types.ShortenRequest
+	for i := 0; i < 5; i++ {
+		reqs = append(reqs, types.ShortenRequest{URL: "https://example.com", Tags: []string{fmt.Sprintf("issue-%d", i)}})
+	}
+
+	links, errs, err := service.ShortenMany(ctx, reqs)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	codes := map[string]bool{}
+	for i := range reqs {
+		if errs[i] != nil || len(links[i].Code) != minCodeLength {
+			t.Fatalf("Expected row %d to be created with a %d character code, got %v and %v", i, minCodeLength, links[i], errs[i])
+		}
+		codes[links[i].Code] = true
+	}
+	if len(codes) != len(reqs) {
+		t.Errorf("Expected %d different codes, got %v", len(reqs), codes)
+	}
+	if rounds != 2 {
+		t.Errorf("Expected the row on the taken code alone to be inserted again, got %d rounds", rounds)
+	}
+	if length := service.codeLength.Load(); length != minCodeLength {
+		t.Errorf("Expected the code length of the server to stay at %d, got %d", minCodeLength, length)
+	}
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/topboyasante/trunc8/internal/auth"
+	"github.com/topboyasante/trunc8/internal/models"
+	"github.com/topboyasante/trunc8/internal/types"
+	"github.com/topboyasante/trunc8/internal/utils"
+)
+
+const (
+	defaultListLimit = 20
+	maxListLimit     = 100
+)
+
+// ListLinks returns one page of the links matching query.
+// Pages are linked with cursors rather than offsets, so links created while
+// someone pages through the list don't make them see a link twice.
+func (s *ShortnerService) ListLinks(ctx context.Context, query models.ListQuery) (*models.LinkPage, error) {
+	query, err := normalizeListQuery(query, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	query.WorkspaceID = auth.WorkspaceID(ctx)
+
+	// We ask for one extra link to know whether there is a next page
+	limit := query.Limit
+	query.Limit++
+
+	links, err := s.repository.List(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.LinkPage{Links: links}
+	if len(links) > limit {
+		page.Links = links[:limit]
+		page.NextCursor = encodeCursor(page.Links[limit-1], query.Sort)
+	}
+	if page.Links == nil {
+		page.Links = []*models.URL{}
+	}
+
+	return page, nil
+}
+
+var listStatuses = map[string]bool{
+	models.StatusActive:   true,
+	models.StatusDisabled: true,
+	models.StatusExpired:  true,
+}
+
+func normalizeListQuery(query models.ListQuery, now time.Time) (models.ListQuery, error) {
+	query.Now = now
+	query.Tag = strings.ToLower(strings.TrimSpace(query.Tag))
+	query.Domain = strings.ToLower(strings.TrimSpace(query.Domain))
+
+	if query.Status != "" && !listStatuses[query.Status] {
+		return query, fmt.Errorf("%w: status must be active, disabled or expired", models.ErrInvalidListQuery)
+	}
+
+	switch query.Sort {
+	case "":
+		query.Sort = models.SortCreatedAt
+	case models.SortCreatedAt, models.SortClicks:
+	default:
+		return query, fmt.Errorf("%w: sort must be created_at or clicks", models.ErrInvalidListQuery)
+	}
+
+	if query.Limit == 0 {
+		query.Limit = defaultListLimit
+	}
+	if query.Limit < 0 || query.Limit > maxListLimit {
+		return query, fmt.Errorf("%w: limit must be between 1 and %d", models.ErrInvalidListQuery, maxListLimit)
+	}
+
+	if query.Cursor != "" {
+		after, err := decodeCursor(query.Cursor)
+		if err != nil {
+			return query, err
+		}
+		// A cursor only makes sense in the order it was made for
+		if after.Sort != query.Sort {
+			return query, fmt.Errorf("%w: cursor was made for sorting by %s", models.ErrInvalidListQuery, after.Sort)
+		}
+		query.After = after
+	}
+
+	return query, nil
+}
+
+// encodeCursor points a cursor at link. Clients are not supposed to look inside,
+// so it's base64, but it's only JSON: nothing in it is secret.
+func encodeCursor(link *models.URL, sort string) string {
+	cursor := models.ListCursor{Sort: sort, ID: link.ID}
+	if sort == models.SortClicks {
+		cursor.Clicks = link.ClickCount
+	} else {
+		cursor.CreatedAt = link.CreatedAt
+	}
+
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(raw string) (*models.ListCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", models.ErrInvalidListQuery)
+	}
+
+	var cursor models.ListCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == "" {
+		return nil, fmt.Errorf("%w: malformed cursor", models.ErrInvalidListQuery)
+	}
+	return &cursor, nil
+}
+
+// GetLink returns the link stored under code, whatever its state.
+// Like every management call, it only sees links of the caller's workspace.
+func (s *ShortnerService) GetLink(ctx context.Context, code string) (*models.URL, error) {
+	if code == "" {
+		return nil, errors.New("code cannot be empty")
+	}
+
+	return s.repository.FindInWorkspace(ctx, auth.WorkspaceID(ctx), code)
+}
+
+// UpdateLink changes the destination, expiration or redirect type of a link.
+// The new values go through the same validation as when a link is created.
+func (s *ShortnerService) UpdateLink(ctx context.Context, code string, req types.UpdateLinkRequest) (*models.URL, error) {
+	if code == "" {
+		return nil, errors.New("code cannot be empty")
+	}
+
+	update := models.LinkUpdate{
+		ExpiresAt:    req.ExpiresAt,
+		RemoveExpiry: req.RemoveExpiry,
+		MaxClicks:    req.MaxClicks,
+		RedirectType: req.RedirectType,
+	}
+
+	if req.URL != nil {
+		originalURL, err := utils.NormalizeURL(*req.URL, s.allowedSchemes)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", models.ErrInvalidURL, err)
+		}
+		domain := domainOf(originalURL)
+		update.OriginalURL = &originalURL
+		update.Domain = &domain
+	}
+
+	if req.Tags != nil {
+		tags, err := normalizeTags(req.Tags)
+		if err != nil {
+			return nil, err
+		}
+		update.Tags = tags
+	}
+
+	if req.ExpiresAt != nil && req.RemoveExpiry {
+		return nil, fmt.Errorf("%w: expires_at and remove_expiry cannot be combined", models.ErrInvalidExpiration)
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w: expires_at must be in the future", models.ErrInvalidExpiration)
+	}
+	if req.MaxClicks != nil && *req.MaxClicks < 0 {
+		return nil, fmt.Errorf("%w: max_clicks cannot be negative", models.ErrInvalidExpiration)
+	}
+
+	if req.RedirectType != nil && *req.RedirectType != 0 && !models.RedirectTypes[*req.RedirectType] {
+		return nil, fmt.Errorf("%w: must be 301, 302, 307 or 308", models.ErrInvalidRedirect)
+	}
+
+	return s.repository.Update(ctx, auth.WorkspaceID(ctx), code, update)
+}
+
+// SetLinkDisabled disables or re-enables a link. Visitors of a disabled link get 410 Gone,
+// but unlike a deleted link it keeps its code and its stats.
+func (s *ShortnerService) SetLinkDisabled(ctx context.Context, code string, disabled bool) (*models.URL, error) {
+	if code == "" {
+		return nil, errors.New("code cannot be empty")
+	}
+
+	return s.repository.Update(ctx, auth.WorkspaceID(ctx), code, models.LinkUpdate{Disabled: &disabled})
+}
+
+// DeleteLink removes a link for good. Its code becomes free to use again.
+func (s *ShortnerService) DeleteLink(ctx context.Context, code string) error {
+	if code == "" {
+		return errors.New("code cannot be empty")
+	}
+
+	return s.repository.Delete(ctx, auth.WorkspaceID(ctx), code)
+}
@@ -0,0 +1,332 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/topboyasante/trunc8/internal/auth"
+	"github.com/topboyasante/trunc8/internal/codegen"
+	"github.com/topboyasante/trunc8/internal/config"
+	"github.com/topboyasante/trunc8/internal/models"
+	"github.com/topboyasante/trunc8/internal/types"
+)
+
+func TestUpdateLink_Success(t *testing.T) {
+	var got models.LinkUpdate
+	mockRepo := &mockShortenerRepository{
+		updateFunc: func(ctx context.Context, workspaceID, code string, update models.LinkUpdate) (*models.URL, error) {
+			if code != "TEST" {
+				t.Errorf("Expected code 'TEST', got '%s'", code)
+			}
+			got = update
+			return &models.URL{Code: code, OriginalURL: *update.OriginalURL}, nil
+		},
+	}
+
+	service := NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{})
+
+	url := "HTTPS://Example.com:443/summer-sale"
+	redirectType := 307
+	result, err := service.UpdateLink(context.Background(), "TEST", types.UpdateLinkRequest{
+		URL:          &url,
+		RedirectType: &redirectType,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// The new destination is stored in its canonical form, like on create
+	if result.OriginalURL != "https://example.com/summer-sale" {
+		t.Errorf("Expected normalized URL, got '%s'", result.OriginalURL)
+	}
+
+	if got.RedirectType == nil || *got.RedirectType != 307 {
+		t.Errorf("Expected redirect type 307 to be passed on, got %v", got.RedirectType)
+	}
+
+	if got.ExpiresAt != nil || got.MaxClicks != nil || got.Disabled != nil {
+		t.Errorf("Expected fields missing from the request to be left alone, got %+v", got)
+	}
+}
+
+func TestUpdateLink_InvalidValues(t *testing.T) {
+	badURL := "javascript:alert(1)"
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	negative := -1
+	seeOther := 303
+
+	tests := []struct {
+		name string
+		req  types.UpdateLinkRequest
+		err  error
+	}{
+		{"bad url", types.UpdateLinkRequest{URL: &badURL}, models.ErrInvalidURL},
+		{"expiry in the past", types.UpdateLinkRequest{ExpiresAt: &past}, models.ErrInvalidExpiration},
+		{"expiry and remove expiry", types.UpdateLinkRequest{ExpiresAt: &future, RemoveExpiry: true}, models.ErrInvalidExpiration},
+		{"negative max clicks", types.UpdateLinkRequest{MaxClicks: &negative}, models.ErrInvalidExpiration},
+		{"unsupported redirect type", types.UpdateLinkRequest{RedirectType: &seeOther}, models.ErrInvalidRedirect},
+	}
+
+	for _, tt := range tests {
+		mockRepo := &mockShortenerRepository{
+			updateFunc: func(ctx context.Context, workspaceID, code string, update models.LinkUpdate) (*models.URL, error) {
+				t.Errorf("%s: expected invalid update not to reach the repository", tt.name)
+				return nil, nil
+			},
+		}
+
+		service := NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{})
+
+		_, err := service.UpdateLink(context.Background(), "TEST", tt.req)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.err, err)
+		}
+	}
+}
+
+func TestUpdateLink_NotFound(t *testing.T) {
+	mockRepo := &mockShortenerRepository{
+		updateFunc: func(ctx context.Context, workspaceID, code string, update models.LinkUpdate) (*models.URL, error) {
+			return nil, models.ErrNotFound
+		},
+	}
+
+	service := NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{})
+
+	_, err := service.UpdateLink(context.Background(), "MISSING", types.UpdateLinkRequest{})
+	if !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestSetLinkDisabled(t *testing.T) {
+	for _, disabled := range []bool{true, false} {
+		mockRepo := &mockShortenerRepository{
+			updateFunc: func(ctx context.Context, workspaceID, code string, update models.LinkUpdate) (*models.URL, error) {
+				if update.Disabled == nil || *update.Disabled != disabled {
+					t.Errorf("Expected disabled to be set to %v, got %v", disabled, update.Disabled)
+				}
+				return &models.URL{Code: code, Disabled: *update.Disabled}, nil
+			},
+		}
+
+		service := NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{})
+
+		result, err := service.SetLinkDisabled(context.Background(), "TEST", disabled)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if result.Disabled != disabled {
+			t.Errorf("Expected disabled %v, got %v", disabled, result.Disabled)
+		}
+	}
+}
+
+func TestDeleteLink(t *testing.T) {
+	deleted := ""
+	mockRepo := &mockShortenerRepository{
+		deleteFunc: func(ctx context.Context, workspaceID, code string) error {
+			deleted = code
+			return nil
+		},
+	}
+
+	service := NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{})
+
+	if err := service.DeleteLink(context.Background(), "TEST"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if deleted != "TEST" {
+		t.Errorf("Expected 'TEST' to be deleted, got '%s'", deleted)
+	}
+
+	if err := service.DeleteLink(context.Background(), ""); err == nil {
+		t.Error("Expected error for empty code, got nil")
+	}
+}
+
+func TestListLinks_Pagination(t *testing.T) {
+	created := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	links := []*models.URL{
+		{ID: "665f1f77bcf86cd799439013", Code: "C", CreatedAt: created.Add(2 * time.Hour)},
+		{ID: "665f1f77bcf86cd799439012", Code: "B", CreatedAt: created.Add(time.Hour)},
+		{ID: "665f1f77bcf86cd799439011", Code: "A", CreatedAt: created},
+	}
+
+	var queries []models.ListQuery
+	mockRepo := &mockShortenerRepository{
+		listFunc: func(ctx context.Context, query models.ListQuery) ([]*models.URL, error) {
+			queries = append(queries, query)
+			// Pretend the repository holds the links above, newest first
+			start := 0
+			if query.After != nil {
+				for i, link := range links {
+					if link.ID == query.After.ID {
+						start = i + 1
+					}
+				}
+			}
+			end := start + query.Limit
+			if end > len(links) {
+				end = len(links)
+			}
+			return links[start:end], nil
+		},
+	}
+
+	service := NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{})
+
+	page, err := service.ListLinks(context.Background(), models.ListQuery{Limit: 2, Descending: true})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(page.Links) != 2 || page.Links[0].Code != "C" || page.Links[1].Code != "B" {
+		t.Fatalf("Unexpected first page: %+v", page.Links)
+	}
+
+	if page.NextCursor == "" {
+		t.Fatal("Expected a cursor to the next page")
+	}
+
+	// One extra link is fetched to find out whether there is a next page
+	if queries[0].Limit != 3 {
+		t.Errorf("Expected the repository to be asked for 3 links, got %d", queries[0].Limit)
+	}
+
+	if queries[0].Sort != models.SortCreatedAt {
+		t.Errorf("Expected to sort by created_at by default, got '%s'", queries[0].Sort)
+	}
+
+	page, err = service.ListLinks(context.Background(), models.ListQuery{Limit: 2, Descending: true, Cursor: page.NextCursor})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if after := queries[1].After; after == nil || after.ID != "665f1f77bcf86cd799439012" || !after.CreatedAt.Equal(links[1].CreatedAt) {
+		t.Errorf("Expected the cursor to point at link B, got %+v", after)
+	}
+
+	if len(page.Links) != 1 || page.Links[0].Code != "A" {
+		t.Fatalf("Unexpected last page: %+v", page.Links)
+	}
+
+	if page.NextCursor != "" {
+		t.Errorf("Expected no cursor on the last page, got '%s'", page.NextCursor)
+	}
+}
+
+func TestListLinks_Empty(t *testing.T) {
+	service := NewShortnerService(&mockShortenerRepository{}, codegen.NewRandom(), &config.Config{})
+
+	page, err := service.ListLinks(context.Background(), models.ListQuery{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// An empty page is [] in JSON, not null
+	if page.Links == nil || len(page.Links) != 0 {
+		t.Errorf("Expected an empty, non-nil list, got %v", page.Links)
+	}
+}
+
+func TestListLinks_InvalidQuery(t *testing.T) {
+	clicksCursor := encodeCursor(&models.URL{ID: "665f1f77bcf86cd799439011", ClickCount: 3}, models.SortClicks)
+
+	tests := []struct {
+		name  string
+		query models.ListQuery
+	}{
+		{"unknown status", models.ListQuery{Status: "archived"}},
+		{"unknown sort", models.ListQuery{Sort: "code"}},
+		{"limit too large", models.ListQuery{Limit: 1000}},
+		{"malformed cursor", models.ListQuery{Cursor: "not a cursor!"}},
+		{"cursor for another sort", models.ListQuery{Sort: models.SortCreatedAt, Cursor: clicksCursor}},
+	}
+
+	for _, tt := range tests {
+		service := NewShortnerService(&mockShortenerRepository{}, codegen.NewRandom(), &config.Config{})
+
+		_, err := service.ListLinks(context.Background(), tt.query)
+		if !errors.Is(err, models.ErrInvalidListQuery) {
+			t.Errorf("%s: expected ErrInvalidListQuery, got %v", tt.name, err)
+		}
+	}
+}
+
+func TestGetLink_KeyWithoutWorkspace(t *testing.T) {
+	ctx := auth.WithKey(context.Background(), &models.APIKey{Scopes: []string{models.ScopeLinksRead}})
+
+	mockRepo := &mockShortenerRepository{
+		findInWorkspaceFunc: func(ctx context.Context, workspaceID, code string) (*models.URL, error) {
+			// Like the repositories: "" would match the link of any workspace
+			link := &models.URL{Code: code, WorkspaceID: "ws-2"}
+			if workspaceID != "" && workspaceID != link.WorkspaceID {
+				return nil, models.ErrNotFound
+			}
+			return link, nil
+		},
+	}
+
+	service := NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{})
+
+	if link, err := service.GetLink(ctx, "TEST"); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("Expected the link of another workspace to stay hidden, got %v, %v", link, err)
+	}
+}
+
+func TestLinks_ScopedToWorkspace(t *testing.T) {
+	ctx := auth.WithKey(context.Background(), &models.APIKey{
+		UserID:      "user-1",
+		WorkspaceID: "ws-1",
+		Scopes:      []string{models.ScopeLinksWrite, models.ScopeLinksRead},
+	})
+
+	var workspaces []string
+	mockRepo := &mockShortenerRepository{
+		findInWorkspaceFunc: func(ctx context.Context, workspaceID, code string) (*models.URL, error) {
+			workspaces = append(workspaces, "find:"+workspaceID)
+			return &models.URL{Code: code, WorkspaceID: workspaceID}, nil
+		},
+		updateFunc: func(ctx context.Context, workspaceID, code string, update models.LinkUpdate) (*models.URL, error) {
+			workspaces = append(workspaces, "update:"+workspaceID)
+			return &models.URL{Code: code}, nil
+		},
+		deleteFunc: func(ctx context.Context, workspaceID, code string) error {
+			workspaces = append(workspaces, "delete:"+workspaceID)
+			return nil
+		},
+		listFunc: func(ctx context.Context, query models.ListQuery) ([]*models.URL, error) {
+			workspaces = append(workspaces, "list:"+query.WorkspaceID)
+			return nil, nil
+		},
+		createFunc: func(ctx context.Context, url models.URL) (string, error) {
+			workspaces = append(workspaces, "create:"+url.WorkspaceID)
+			if url.OwnerID != "user-1" {
+				t.Errorf("Expected the link to be owned by user-1, got '%s'", url.OwnerID)
+			}
+			return "test-id", nil
+		},
+	}
+
+	service := NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{})
+
+	service.GetLink(ctx, "TEST")
+	service.UpdateLink(ctx, "TEST", types.UpdateLinkRequest{})
+	service.SetLinkDisabled(ctx, "TEST", true)
+	service.DeleteLink(ctx, "TEST")
+	service.ListLinks(ctx, models.ListQuery{})
+	service.LinkStats(ctx, models.StatsQuery{Code: "TEST"})
+	service.ShortenURL(ctx, types.ShortenRequest{URL: "https://example.com", Alias: "launch-2026"})
+
+	expected := []string{"find:ws-1", "update:ws-1", "update:ws-1", "delete:ws-1", "list:ws-1", "find:ws-1", "create:ws-1"}
+	if strings.Join(workspaces, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected every call to be scoped to ws-1, got %v", workspaces)
+	}
+}
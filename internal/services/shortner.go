@@ -2,72 +2,483 @@ package services
 
 import (
 	"context"
+	"crypto/rand"
 	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
 
+	"github.com/topboyasante/trunc8/internal/auth"
+	"github.com/topboyasante/trunc8/internal/config"
 	"github.com/topboyasante/trunc8/internal/models"
+	"github.com/topboyasante/trunc8/internal/types"
 	"github.com/topboyasante/trunc8/internal/utils"
 )
 
 // ShortenerRepositoryInterface defines the interface for shortener repository operations
 type ShortenerRepositoryInterface interface {
 	Create(ctx context.Context, url models.URL) (string, error)
+	CreateMany(ctx context.Context, urls []models.URL) ([]string, []error)
 	FindOne(ctx context.Context, code string) (*models.URL, error)
+	FindInWorkspace(ctx context.Context, workspaceID, code string) (*models.URL, error)
+	FindByOriginalURL(ctx context.Context, workspaceID, originalURL string) (*models.URL, error)
+	IncrementClickCount(ctx context.Context, code string) error
+	CreateClick(ctx context.Context, click models.Click) error
+	ClickStats(ctx context.Context, query models.StatsQuery) (*models.LinkStats, error)
+	Update(ctx context.Context, workspaceID, code string, update models.LinkUpdate) (*models.URL, error)
+	Delete(ctx context.Context, workspaceID, code string) error
+	List(ctx context.Context, query models.ListQuery) ([]*models.URL, error)
+	RaiseSequence(ctx context.Context, name string, value int64) (int64, error)
+}
+
+// CodeGenerator creates short codes for new links.
+// length is a hint: strategies like the counter decide on their own how long a code is.
+// attempt counts the codes of this link that were already taken, so strategies that derive
+// the code from the URL can come up with another one.
+type CodeGenerator interface {
+	Generate(ctx context.Context, originalURL string, length, attempt int) (string, error)
+}
+
+// urlDerivedGenerator is a CodeGenerator whose codes are derived from the URL, like the hash strategy.
+// Its collisions are mostly a URL meeting its own earlier links, so they don't make codes longer.
+type urlDerivedGenerator interface {
+	DerivedFromURL() bool
+}
+
+// ShortenerMetrics is told what happens to redirects and generated codes
+type ShortenerMetrics interface {
+	Redirect(outcome string)
+	CodeRetry()
+}
+
+// noMetrics is used until SetMetrics is called
+type noMetrics struct{}
+
+func (noMetrics) Redirect(outcome string) {}
+func (noMetrics) CodeRetry()              {}
+
+const (
+	minCodeLength   = 4
+	maxCodeLength   = 12
+	maxCodeAttempts = 10
+
+	// After this many collisions in a row we assume the keyspace is getting crowded.
+	// With a keyspace that is half full, two random codes in a row collide 25% of the time.
+	collisionsBeforeGrowth = 2
+
+	// codeLengthSequence is the counter the code length is stored in,
+	// so it survives restarts and is shared between instances
+	codeLengthSequence = "code_length"
+)
+
+// Aliases are used as URL paths, so we keep them to characters that never need escaping
+var aliasPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{3,32}$`)
+
+// Tags follow the same rules as aliases, except that they are always lowercase
+var tagPattern = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+const maxTags = 10
+
+// reservedAliases would shadow our own routes if someone took them as a code
+var reservedAliases = map[string]bool{
+	"shorten": true,
+	"api":     true,
+	"healthz": true,
+	"readyz":  true,
+	"metrics": true,
 }
 
 // This defines a new struct type called ShortnerService (like creating a blueprint).
 // It doesn't initialize an instance - just defines what the struct looks like.
 type ShortnerService struct {
-	repository ShortenerRepositoryInterface // This field holds a ShortenerRepositoryInterface
+	repository          ShortenerRepositoryInterface // This field holds a ShortenerRepositoryInterface
+	generator           CodeGenerator
+	allowedSchemes      []string
+	defaultRedirectType int
+	bulkLimit           int
+	ipHashSecret        []byte
+	metrics             ShortenerMetrics
+
+	// growCodes is whether collisions make codes longer, see urlDerivedGenerator
+	growCodes bool
+
+	// codeLength is shared by all requests, so it's an atomic instead of a plain int
+	codeLength atomic.Int64
 }
 
 // This function creates a new ShortnerService instance and returns a pointer to it.
 // The *ShortnerService return type means it returns a pointer, not the struct value itself.
-func NewShortnerService(repository ShortenerRepositoryInterface) *ShortnerService {
+func NewShortnerService(repository ShortenerRepositoryInterface, generator CodeGenerator, cfg *config.Config) *ShortnerService {
 	// &ShortnerService{...} creates a new struct instance and returns its memory address (a pointer to it)
-	return &ShortnerService{
-		repository: repository,
+	service := &ShortnerService{
+		repository:          repository,
+		generator:           generator,
+		allowedSchemes:      cfg.Shortener.AllowedSchemes,
+		defaultRedirectType: cfg.Server.DefaultRedirectType,
+		bulkLimit:           cfg.Shortener.BulkLimit,
+		ipHashSecret:        []byte(cfg.Analytics.IPHashSecret),
+		metrics:             noMetrics{},
+		growCodes:           true,
+	}
+	if g, ok := generator.(urlDerivedGenerator); ok && g.DerivedFromURL() {
+		service.growCodes = false
+	}
+	if len(service.allowedSchemes) == 0 {
+		service.allowedSchemes = []string{"http", "https"}
+	}
+	if service.defaultRedirectType == 0 {
+		service.defaultRedirectType = http.StatusFound
+	}
+	if service.bulkLimit == 0 {
+		service.bulkLimit = defaultBulkLimit
+	}
+	if len(service.ipHashSecret) == 0 {
+		// Visitors are still counted, but only until the next restart and only by this instance
+		service.ipHashSecret = make([]byte, 32)
+		rand.Read(service.ipHashSecret)
 	}
+
+	codeLength := cfg.Shortener.CodeLength
+	if codeLength == 0 {
+		codeLength = minCodeLength
+	}
+	service.codeLength.Store(int64(codeLength))
+
+	return service
 }
 
-func (s *ShortnerService) ShortenURL(ctx context.Context, originalURL string) (*models.URL, error) {
+// SetMetrics reports redirects and code generation retries to metrics.
+// Call it before the service is used.
+func (s *ShortnerService) SetMetrics(metrics ShortenerMetrics) {
+	s.metrics = metrics
+}
 
-	if originalURL == "" {
-		return nil, errors.New("original URL cannot be empty")
+func (s *ShortnerService) ShortenURL(ctx context.Context, req types.ShortenRequest) (*models.URL, error) {
+	req, err := s.prepareRequest(req)
+	if err != nil {
+		return nil, err
 	}
 
-	encodedURL := utils.GenerateURLCode()
+	if req.Alias != "" {
+		return s.createWithAlias(ctx, req)
+	}
 
-	// This creates a NEW instance of models.URL and returns a pointer to it.
-	// It's not pointing to some existing URL struct in the models package - we're creating a fresh one here.
-	url := &models.URL{
-		OriginalURL: originalURL,
-		Code:        encodedURL,
+	// Shortening the same URL again gives back the link we already have
+	if isPlainRequest(req) {
+		existing, err := s.repository.FindByOriginalURL(ctx, auth.WorkspaceID(ctx), req.URL)
+		if err == nil {
+			return existing, nil
+		}
+		if !errors.Is(err, models.ErrNotFound) {
+			return nil, err
+		}
+	}
+
+	collisions := 0
+	for attempt := 0; attempt < maxCodeAttempts; attempt++ {
+		length := int(s.codeLength.Load())
+		encodedURL, err := s.generator.Generate(ctx, req.URL, length, attempt)
+		if err != nil {
+			return nil, fmt.Errorf("generating code: %w", err)
+		}
+
+		// This creates a NEW instance of models.URL and returns a pointer to it.
+		// It's not pointing to some existing URL struct in the models package - we're creating a fresh one here.
+		url := newURL(ctx, req, encodedURL)
+
+		// *url dereferences the pointer, converting it from *models.URL to models.URL
+		// The Create function expects a models.URL value, not a pointer, so we use * to get the actual struct
+		id, err := s.repository.Create(ctx, *url)
+
+		// The unique index rejected the code, so try again with a new one
+		if errors.Is(err, models.ErrDuplicateCode) {
+			s.metrics.CodeRetry()
+			collisions++
+			if collisions >= collisionsBeforeGrowth && s.growCodes {
+				s.growCodeLength(ctx, length)
+				collisions = 0
+			}
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		// Set the ID field of the URL struct
+		url.ID = id
+
+		return url, nil
+	}
+
+	return nil, fmt.Errorf("could not generate a unique code after %d attempts", maxCodeAttempts)
+}
+
+// prepareRequest checks the request and returns it in the form links are stored in,
+// with the URL normalized and the tags lowercased.
+func (s *ShortnerService) prepareRequest(req types.ShortenRequest) (types.ShortenRequest, error) {
+	if req.URL == "" {
+		return req, fmt.Errorf("%w: original URL cannot be empty", models.ErrInvalidURL)
+	}
+
+	// We store the canonical form, so the same destination always looks the same
+	originalURL, err := utils.NormalizeURL(req.URL, s.allowedSchemes)
+	if err != nil {
+		return req, fmt.Errorf("%w: %v", models.ErrInvalidURL, err)
+	}
+	req.URL = originalURL
+
+	if err := validateExpiration(req, time.Now()); err != nil {
+		return req, err
+	}
+
+	if req.RedirectType != 0 && !models.RedirectTypes[req.RedirectType] {
+		return req, fmt.Errorf("%w: must be 301, 302, 307 or 308", models.ErrInvalidRedirect)
+	}
+
+	if req.Tags, err = normalizeTags(req.Tags); err != nil {
+		return req, err
+	}
+
+	if req.Alias != "" {
+		if err := validateAlias(req.Alias); err != nil {
+			return req, err
+		}
+	}
+
+	return req, nil
+}
+
+// isPlainRequest reports whether the request asks for a link without any restrictions.
+// Only those can share a link with other callers: two people who both want a link that
+// expires tomorrow still expect to be able to count their clicks separately.
+func isPlainRequest(req types.ShortenRequest) bool {
+	return req.Alias == "" && req.ExpiresAt == nil && req.MaxClicks == 0 && req.RedirectType == 0 && len(req.Tags) == 0
+}
+
+// newURL builds the link described by the request, stored under code.
+// req.URL must already be normalized. The link belongs to whoever made the request.
+func newURL(ctx context.Context, req types.ShortenRequest, code string) *models.URL {
+	return &models.URL{
+		OriginalURL: req.URL,
+		Code:        code,
 		ClickCount:  0,
+		ExpiresAt:   req.ExpiresAt,
+		MaxClicks:   req.MaxClicks,
+
+		RedirectType: req.RedirectType,
+
+		OwnerID:     auth.UserID(ctx),
+		WorkspaceID: auth.WorkspaceID(ctx),
+
+		Tags:      req.Tags,
+		Domain:    domainOf(req.URL),
+		CreatedAt: time.Now().UTC(),
 	}
+}
 
-	// *url dereferences the pointer, converting it from *models.URL to models.URL
-	// The Create function expects a models.URL value, not a pointer, so we use * to get the actual struct
-	id, err := s.repository.Create(ctx, *url)
+// domainOf returns the host of a normalized URL, which is what links are filtered by.
+func domainOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+// createWithAlias stores a link under the code the caller picked.
+// Unlike generated codes, there's nothing to retry when the alias is taken.
+func (s *ShortnerService) createWithAlias(ctx context.Context, req types.ShortenRequest) (*models.URL, error) {
+	url := newURL(ctx, req, req.Alias)
 
+	id, err := s.repository.Create(ctx, *url)
+	if errors.Is(err, models.ErrDuplicateCode) {
+		return nil, fmt.Errorf("%w: %s", models.ErrAliasTaken, req.Alias)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	// Set the ID field of the URL struct
 	url.ID = id
 
 	return url, nil
 }
 
-func (s *ShortnerService) RedirectURL(ctx context.Context, code string) (string, error) {
-	if code == "" {
-		return "", errors.New("code cannot be empty")
+func validateAlias(alias string) error {
+	if !aliasPattern.MatchString(alias) {
+		return fmt.Errorf("%w: must be 3 to 32 letters, digits, '-' or '_'", models.ErrInvalidAlias)
+	}
+	if reservedAliases[strings.ToLower(alias)] {
+		return fmt.Errorf("%w: %q is reserved", models.ErrInvalidAlias, alias)
+	}
+	return nil
+}
+
+// normalizeTags lowercases the tags and drops duplicates, so "Newsletter" and "newsletter" are the same tag.
+func normalizeTags(tags []string) ([]string, error) {
+	if len(tags) > maxTags {
+		return nil, fmt.Errorf("%w: a link can have at most %d tags", models.ErrInvalidTags, maxTags)
+	}
+
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if !tagPattern.MatchString(tag) {
+			return nil, fmt.Errorf("%w: %q must be 1 to 32 letters, digits, '-' or '_'", models.ErrInvalidTags, tag)
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized, nil
+}
+
+func validateExpiration(req types.ShortenRequest, now time.Time) error {
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(now) {
+		return fmt.Errorf("%w: expires_at must be in the future", models.ErrInvalidExpiration)
+	}
+	if req.MaxClicks < 0 {
+		return fmt.Errorf("%w: max_clicks cannot be negative", models.ErrInvalidExpiration)
+	}
+	return nil
+}
+
+// LoadCodeLength picks up the code length this instance grew to before a restart, or another instance grew to,
+// so it doesn't have to collide its way up again. CODE_LENGTH stays the floor. Call it before the service is used.
+func (s *ShortnerService) LoadCodeLength(ctx context.Context) error {
+	stored, err := s.repository.RaiseSequence(ctx, codeLengthSequence, 0)
+	if err != nil {
+		return fmt.Errorf("loading code length: %w", err)
+	}
+	s.adoptCodeLength(stored)
+	return nil
+}
+
+// growCodeLength makes new codes one character longer than from, and stores the new length.
+// CompareAndSwap makes sure concurrent requests that hit collisions at the same length only grow it once.
+func (s *ShortnerService) growCodeLength(ctx context.Context, from int) {
+	if from >= maxCodeLength {
+		return
+	}
+	if !s.codeLength.CompareAndSwap(int64(from), int64(from+1)) {
+		return
 	}
+	slog.WarnContext(ctx, "Short code keyspace is filling up, growing code length", "length", from+1)
 
-	url, err := s.repository.FindOne(ctx, code)
+	// Another instance may have grown it further already, in which case we catch up
+	stored, err := s.repository.RaiseSequence(ctx, codeLengthSequence, int64(from+1))
 	if err != nil {
-		return "", err
+		slog.ErrorContext(ctx, "Failed to store code length", "length", from+1, "error", err)
+		return
+	}
+	s.adoptCodeLength(stored)
+}
+
+// adoptCodeLength moves the code length up to length. It never goes down, nor past maxCodeLength.
+func (s *ShortnerService) adoptCodeLength(length int64) {
+	length = min(length, maxCodeLength)
+	for {
+		current := s.codeLength.Load()
+		if length <= current || s.codeLength.CompareAndSwap(current, length) {
+			return
+		}
 	}
+}
+
+// RedirectURL looks up the link a visitor should be sent to and counts the click.
+// The returned link always has a RedirectType, falling back to the server default.
+func (s *ShortnerService) RedirectURL(ctx context.Context, req types.RedirectRequest) (*models.URL, error) {
+	url, err := s.redirect(ctx, req)
+	s.metrics.Redirect(redirectOutcome(err))
+	return url, err
+}
 
-	return url.OriginalURL, nil
+// redirectOutcome sorts the result of a redirect for the metrics.
+func redirectOutcome(err error) string {
+	switch {
+	case err == nil:
+		return models.RedirectHit
+	case errors.Is(err, models.ErrNotFound):
+		return models.RedirectMiss
+	case errors.Is(err, models.ErrExpired):
+		return models.RedirectExpired
+	case errors.Is(err, models.ErrDisabled):
+		return models.RedirectDisabled
+	default:
+		return models.RedirectError
+	}
+}
+
+func (s *ShortnerService) redirect(ctx context.Context, req types.RedirectRequest) (*models.URL, error) {
+	if req.Code == "" {
+		return nil, errors.New("code cannot be empty")
+	}
+
+	url, err := s.repository.FindOne(ctx, req.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	if url.RedirectType == 0 {
+		url.RedirectType = s.defaultRedirectType
+	}
+
+	// For anything but a 307/308 link, a POST to /{code} is just a request to a page that doesn't exist
+	if !url.AcceptsMethod(req.Method) {
+		return nil, fmt.Errorf("%w: %s does not accept %s requests", models.ErrNotFound, url.Code, req.Method)
+	}
+
+	if url.Disabled {
+		return nil, fmt.Errorf("%w: %s", models.ErrDisabled, url.Code)
+	}
+
+	// The TTL index only removes expired links about once a minute, so we check ourselves too
+	if url.Expired(time.Now()) {
+		return nil, fmt.Errorf("%w: %s", models.ErrExpired, url.Code)
+	}
+
+	if err := s.countClick(ctx, url.Code); err != nil {
+		return nil, err
+	}
+
+	s.recordClick(ctx, url.Code, req)
+
+	return url, nil
+}
+
+// countClick bumps the link's counter. The only error it returns is ErrExpired,
+// for links that used up their clicks. Analytics should never stop a visitor from being
+// redirected, so any other failure is logged instead of returned.
+func (s *ShortnerService) countClick(ctx context.Context, code string) error {
+	err := s.repository.IncrementClickCount(ctx, code)
+	if errors.Is(err, models.ErrExpired) {
+		return err
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to increment click count", "code", code, "error", err)
+	}
+	return nil
+}
+
+// recordClick stores the click event, logging failures like countClick does.
+func (s *ShortnerService) recordClick(ctx context.Context, code string, req types.RedirectRequest) {
+	now := time.Now().UTC()
+	click := models.Click{
+		Code:      code,
+		Timestamp: now,
+		Referrer:  req.Referrer,
+		UserAgent: req.UserAgent,
+		Browser:   utils.UserAgentFamily(req.UserAgent),
+		IPHash:    utils.HashIP(s.ipHashSecret, req.ClientIP, now),
+	}
+	if err := s.repository.CreateClick(ctx, click); err != nil {
+		slog.ErrorContext(ctx, "Failed to record click", "code", code, "error", err)
+	}
 }
@@ -3,15 +3,32 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/topboyasante/trunc8/internal/codegen"
+	"github.com/topboyasante/trunc8/internal/config"
 	"github.com/topboyasante/trunc8/internal/models"
+	"github.com/topboyasante/trunc8/internal/types"
+	"github.com/topboyasante/trunc8/internal/utils"
 )
 
 // Mock repository for testing
 type mockShortenerRepository struct {
-	createFunc  func(ctx context.Context, url models.URL) (string, error)
-	findOneFunc func(ctx context.Context, code string) (*models.URL, error)
+	createFunc              func(ctx context.Context, url models.URL) (string, error)
+	createManyFunc          func(ctx context.Context, urls []models.URL) ([]string, []error)
+	findOneFunc             func(ctx context.Context, code string) (*models.URL, error)
+	findInWorkspaceFunc     func(ctx context.Context, workspaceID, code string) (*models.URL, error)
+	findByOriginalURLFunc   func(ctx context.Context, originalURL string) (*models.URL, error)
+	incrementClickCountFunc func(ctx context.Context, code string) error
+	createClickFunc         func(ctx context.Context, click models.Click) error
+	clickStatsFunc          func(ctx context.Context, query models.StatsQuery) (*models.LinkStats, error)
+	updateFunc              func(ctx context.Context, workspaceID, code string, update models.LinkUpdate) (*models.URL, error)
+	deleteFunc              func(ctx context.Context, workspaceID, code string) error
+	listFunc                func(ctx context.Context, query models.ListQuery) ([]*models.URL, error)
+	raiseSequenceFunc       func(ctx context.Context, name string, value int64) (int64, error)
 }
 
 func (m *mockShortenerRepository) Create(ctx context.Context, url models.URL) (string, error) {
@@ -21,6 +38,19 @@ func (m *mockShortenerRepository) Create(ctx context.Context, url models.URL) (s
 	return "mock-id", nil
 }
 
+// CreateMany falls back to Create, so tests that don't care about batches only mock one insert
+func (m *mockShortenerRepository) CreateMany(ctx context.Context, urls []models.URL) ([]string, []error) {
+	if m.createManyFunc != nil {
+		return m.createManyFunc(ctx, urls)
+	}
+	ids := make([]string, len(urls))
+	errs := make([]error, len(urls))
+	for i, url := range urls {
+		ids[i], errs[i] = m.Create(ctx, url)
+	}
+	return ids, errs
+}
+
 func (m *mockShortenerRepository) FindOne(ctx context.Context, code string) (*models.URL, error) {
 	if m.findOneFunc != nil {
 		return m.findOneFunc(ctx, code)
@@ -33,9 +63,82 @@ func (m *mockShortenerRepository) FindOne(ctx context.Context, code string) (*mo
 	}, nil
 }
 
+// FindInWorkspace falls back to FindOne, so tests that don't care about workspaces only mock one lookup
+func (m *mockShortenerRepository) FindInWorkspace(ctx context.Context, workspaceID, code string) (*models.URL, error) {
+	if m.findInWorkspaceFunc != nil {
+		return m.findInWorkspaceFunc(ctx, workspaceID, code)
+	}
+	return m.FindOne(ctx, code)
+}
+
+func (m *mockShortenerRepository) FindByOriginalURL(ctx context.Context, workspaceID, originalURL string) (*models.URL, error) {
+	if m.findByOriginalURLFunc != nil {
+		return m.findByOriginalURLFunc(ctx, originalURL)
+	}
+	return nil, models.ErrNotFound
+}
+
+func (m *mockShortenerRepository) IncrementClickCount(ctx context.Context, code string) error {
+	if m.incrementClickCountFunc != nil {
+		return m.incrementClickCountFunc(ctx, code)
+	}
+	return nil
+}
+
+func (m *mockShortenerRepository) CreateClick(ctx context.Context, click models.Click) error {
+	if m.createClickFunc != nil {
+		return m.createClickFunc(ctx, click)
+	}
+	return nil
+}
+
+func (m *mockShortenerRepository) ClickStats(ctx context.Context, query models.StatsQuery) (*models.LinkStats, error) {
+	if m.clickStatsFunc != nil {
+		return m.clickStatsFunc(ctx, query)
+	}
+	return &models.LinkStats{Code: query.Code, From: query.From, To: query.To, Interval: query.Interval}, nil
+}
+
+func (m *mockShortenerRepository) Update(ctx context.Context, workspaceID, code string, update models.LinkUpdate) (*models.URL, error) {
+	if m.updateFunc != nil {
+		return m.updateFunc(ctx, workspaceID, code, update)
+	}
+	return &models.URL{ID: "mock-id", OriginalURL: "https://example.com", Code: code}, nil
+}
+
+func (m *mockShortenerRepository) Delete(ctx context.Context, workspaceID, code string) error {
+	if m.deleteFunc != nil {
+		return m.deleteFunc(ctx, workspaceID, code)
+	}
+	return nil
+}
+
+func (m *mockShortenerRepository) List(ctx context.Context, query models.ListQuery) ([]*models.URL, error) {
+	if m.listFunc != nil {
+		return m.listFunc(ctx, query)
+	}
+	return nil, nil
+}
+
+func (m *mockShortenerRepository) RaiseSequence(ctx context.Context, name string, value int64) (int64, error) {
+	if m.raiseSequenceFunc != nil {
+		return m.raiseSequenceFunc(ctx, name, value)
+	}
+	return value, nil
+}
+
+// Code generator that returns a fixed sequence of codes
+type mockCodeGenerator struct {
+	generateFunc func(ctx context.Context, originalURL string, length, attempt int) (string, error)
+}
+
+func (m *mockCodeGenerator) Generate(ctx context.Context, originalURL string, length, attempt int) (string, error) {
+	return m.generateFunc(ctx, originalURL, length, attempt)
+}
+
 func TestNewShortnerService(t *testing.T) {
 	mockRepo := &mockShortenerRepository{}
-	service := NewShortnerService(mockRepo)
+	service := NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{})
 	
 	if service == nil {
 		t.Error("Expected service to be non-nil")
@@ -62,10 +165,10 @@ func TestShortenURL_Success(t *testing.T) {
 		},
 	}
 	
-	service := NewShortnerService(mockRepo)
+	service := NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{})
 	ctx := context.Background()
 	
-	result, err := service.ShortenURL(ctx, "https://example.com")
+	result, err := service.ShortenURL(ctx, types.ShortenRequest{URL: "https://example.com"})
 	
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
@@ -94,10 +197,10 @@ func TestShortenURL_Success(t *testing.T) {
 
 func TestShortenURL_EmptyURL(t *testing.T) {
 	mockRepo := &mockShortenerRepository{}
-	service := NewShortnerService(mockRepo)
+	service := NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{})
 	ctx := context.Background()
 	
-	result, err := service.ShortenURL(ctx, "")
+	result, err := service.ShortenURL(ctx, types.ShortenRequest{URL: ""})
 	
 	if err == nil {
 		t.Fatal("Expected error for empty URL")
@@ -107,12 +210,153 @@ func TestShortenURL_EmptyURL(t *testing.T) {
 		t.Error("Expected result to be nil when error occurs")
 	}
 	
+	if !errors.Is(err, models.ErrInvalidURL) {
+		t.Errorf("Expected ErrInvalidURL, got %v", err)
+	}
+
 	expectedError := "original URL cannot be empty"
-	if err.Error() != expectedError {
+	if !strings.Contains(err.Error(), expectedError) {
 		t.Errorf("Expected error '%s', got '%s'", expectedError, err.Error())
 	}
 }
 
+func TestShortenURL_NormalizesURL(t *testing.T) {
+	mockRepo := &mockShortenerRepository{
+		createFunc: func(ctx context.Context, url models.URL) (string, error) {
+			if url.OriginalURL != "https://example.com/Path" {
+				t.Errorf("Expected normalized URL 'https://example.com/Path', got '%s'", url.OriginalURL)
+			}
+			return "test-id", nil
+		},
+	}
+
+	service := NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{})
+
+	result, err := service.ShortenURL(context.Background(), types.ShortenRequest{URL: "HTTPS://Example.COM:443/Path"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if result.OriginalURL != "https://example.com/Path" {
+		t.Errorf("Expected normalized URL 'https://example.com/Path', got '%s'", result.OriginalURL)
+	}
+}
+
+func TestShortenURL_RejectsInvalidURL(t *testing.T) {
+	invalid := []string{"javascript:alert(1)", "/relative/path", "not a url", "ftp://example.com"}
+
+	for _, raw := range invalid {
+		mockRepo := &mockShortenerRepository{
+			createFunc: func(ctx context.Context, url models.URL) (string, error) {
+				t.Errorf("Expected '%s' to be rejected before reaching the repository", raw)
+				return "test-id", nil
+			},
+		}
+
+		service := NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{})
+
+		_, err := service.ShortenURL(context.Background(), types.ShortenRequest{URL: raw})
+		if !errors.Is(err, models.ErrInvalidURL) {
+			t.Errorf("Expected ErrInvalidURL for '%s', got %v", raw, err)
+		}
+	}
+}
+
+func TestShortenURL_ConfiguredSchemes(t *testing.T) {
+	cfg := &config.Config{Shortener: config.ShortenerConfig{AllowedSchemes: []string{"https", "ftp"}}}
+	service := NewShortnerService(&mockShortenerRepository{}, codegen.NewRandom(), cfg)
+
+	if _, err := service.ShortenURL(context.Background(), types.ShortenRequest{URL: "ftp://example.com/file"}); err != nil {
+		t.Errorf("Expected ftp to be accepted, got %v", err)
+	}
+
+	if _, err := service.ShortenURL(context.Background(), types.ShortenRequest{URL: "http://example.com"}); !errors.Is(err, models.ErrInvalidURL) {
+		t.Errorf("Expected http to be rejected, got %v", err)
+	}
+}
+
+func TestShortenURL_ReturnsExistingLink(t *testing.T) {
+	mockRepo := &mockShortenerRepository{
+		findByOriginalURLFunc: func(ctx context.Context, originalURL string) (*models.URL, error) {
+			// The lookup should use the normalized URL
+			if originalURL != "https://example.com/release" {
+				t.Errorf("Expected lookup of 'https://example.com/release', got '%s'", originalURL)
+			}
+			return &models.URL{ID: "existing-id", OriginalURL: originalURL, Code: "REL1", ClickCount: 12}, nil
+		},
+		createFunc: func(ctx context.Context, url models.URL) (string, error) {
+			t.Error("Expected no new link to be created")
+			return "test-id", nil
+		},
+	}
+
+	service := NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{})
+
+	result, err := service.ShortenURL(context.Background(), types.ShortenRequest{URL: "https://EXAMPLE.com/release"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if result.ID != "existing-id" || result.Code != "REL1" {
+		t.Errorf("Expected the existing link, got %+v", result)
+	}
+}
+
+func TestShortenURL_RestrictedLinksAreNotDeduplicated(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour)
+
+	tests := map[string]types.ShortenRequest{
+		"alias":      {URL: "https://example.com", Alias: "launch-2026"},
+		"expiration": {URL: "https://example.com", ExpiresAt: &expiresAt},
+		"max clicks": {URL: "https://example.com", MaxClicks: 5},
+	}
+
+	for name, req := range tests {
+		t.Run(name, func(t *testing.T) {
+			created := false
+			mockRepo := &mockShortenerRepository{
+				findByOriginalURLFunc: func(ctx context.Context, originalURL string) (*models.URL, error) {
+					t.Error("Expected no lookup of an existing link")
+					return &models.URL{ID: "existing-id", OriginalURL: originalURL, Code: "REL1"}, nil
+				},
+				createFunc: func(ctx context.Context, url models.URL) (string, error) {
+					created = true
+					return "test-id", nil
+				},
+			}
+
+			service := NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{})
+
+			if _, err := service.ShortenURL(context.Background(), req); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			if !created {
+				t.Error("Expected a new link to be created")
+			}
+		})
+	}
+}
+
+func TestShortenURL_LookupError(t *testing.T) {
+	mockRepo := &mockShortenerRepository{
+		findByOriginalURLFunc: func(ctx context.Context, originalURL string) (*models.URL, error) {
+			return nil, errors.New("database error")
+		},
+	}
+
+	service := NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{})
+
+	result, err := service.ShortenURL(context.Background(), types.ShortenRequest{URL: "https://example.com"})
+	if err == nil {
+		t.Fatal("Expected error from repository")
+	}
+
+	if result != nil {
+		t.Error("Expected result to be nil when error occurs")
+	}
+}
+
 func TestShortenURL_RepositoryError(t *testing.T) {
 	mockRepo := &mockShortenerRepository{
 		createFunc: func(ctx context.Context, url models.URL) (string, error) {
@@ -120,10 +364,10 @@ func TestShortenURL_RepositoryError(t *testing.T) {
 		},
 	}
 	
-	service := NewShortnerService(mockRepo)
+	service := NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{})
 	ctx := context.Background()
 	
-	result, err := service.ShortenURL(ctx, "https://example.com")
+	result, err := service.ShortenURL(ctx, types.ShortenRequest{URL: "https://example.com"})
 	
 	if err == nil {
 		t.Fatal("Expected error from repository")
@@ -138,77 +382,822 @@ func TestShortenURL_RepositoryError(t *testing.T) {
 	}
 }
 
-func TestRedirectURL_Success(t *testing.T) {
+func TestShortenURL_RetriesOnDuplicateCode(t *testing.T) {
+	var codes []string
 	mockRepo := &mockShortenerRepository{
-		findOneFunc: func(ctx context.Context, code string) (*models.URL, error) {
-			if code != "TEST" {
-				t.Errorf("Expected code to be 'TEST', got '%s'", code)
+		createFunc: func(ctx context.Context, url models.URL) (string, error) {
+			codes = append(codes, url.Code)
+			if len(codes) == 1 {
+				return "", fmt.Errorf("%w: %s", models.ErrDuplicateCode, url.Code)
 			}
-			return &models.URL{
-				ID:          "test-id",
-				OriginalURL: "https://example.com",
-				Code:        "TEST",
-				ClickCount:  5,
-			}, nil
+			return "test-id", nil
 		},
 	}
-	
-	service := NewShortnerService(mockRepo)
+
+	service := NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{})
 	ctx := context.Background()
-	
-	result, err := service.RedirectURL(ctx, "TEST")
-	
+
+	result, err := service.ShortenURL(ctx, types.ShortenRequest{URL: "https://example.com"})
+
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
-	
-	if result != "https://example.com" {
-		t.Errorf("Expected result to be 'https://example.com', got '%s'", result)
+
+	if len(codes) != 2 {
+		t.Fatalf("Expected 2 attempts, got %d", len(codes))
+	}
+
+	if result.Code != codes[1] {
+		t.Errorf("Expected the code of the second attempt '%s', got '%s'", codes[1], result.Code)
+	}
+
+	// A single collision shouldn't make codes longer
+	if len(result.Code) != minCodeLength {
+		t.Errorf("Expected code length to be %d, got %d", minCodeLength, len(result.Code))
 	}
 }
 
-func TestRedirectURL_EmptyCode(t *testing.T) {
-	mockRepo := &mockShortenerRepository{}
-	service := NewShortnerService(mockRepo)
+func TestShortenURL_GrowsCodeLengthWhenCrowded(t *testing.T) {
+	attempts := 0
+	mockRepo := &mockShortenerRepository{
+		createFunc: func(ctx context.Context, url models.URL) (string, error) {
+			attempts++
+			// Every 4 character code is taken
+			if len(url.Code) == minCodeLength {
+				return "", models.ErrDuplicateCode
+			}
+			return "test-id", nil
+		},
+	}
+
+	service := NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{})
 	ctx := context.Background()
-	
-	result, err := service.RedirectURL(ctx, "")
-	
-	if err == nil {
-		t.Fatal("Expected error for empty code")
+
+	result, err := service.ShortenURL(ctx, types.ShortenRequest{URL: "https://example.com"})
+
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
 	}
-	
-	if result != "" {
-		t.Error("Expected result to be empty string when error occurs")
+
+	if len(result.Code) != minCodeLength+1 {
+		t.Errorf("Expected code length to grow to %d, got %d", minCodeLength+1, len(result.Code))
 	}
-	
-	expectedError := "code cannot be empty"
-	if err.Error() != expectedError {
-		t.Errorf("Expected error '%s', got '%s'", expectedError, err.Error())
+
+	if attempts != collisionsBeforeGrowth+1 {
+		t.Errorf("Expected %d attempts, got %d", collisionsBeforeGrowth+1, attempts)
+	}
+
+	// The longer length should stick for the next links
+	result, err = service.ShortenURL(ctx, types.ShortenRequest{URL: "https://example.org"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(result.Code) != minCodeLength+1 {
+		t.Errorf("Expected code length to stay at %d, got %d", minCodeLength+1, len(result.Code))
 	}
 }
 
-func TestRedirectURL_RepositoryError(t *testing.T) {
+func TestShortenURL_StoresGrownCodeLength(t *testing.T) {
+	stored := int64(0)
 	mockRepo := &mockShortenerRepository{
-		findOneFunc: func(ctx context.Context, code string) (*models.URL, error) {
-			return nil, errors.New("not found")
+		createFunc: func(ctx context.Context, url models.URL) (string, error) {
+			if len(url.Code) == minCodeLength {
+				return "", models.ErrDuplicateCode
+			}
+			return "test-id", nil
+		},
+		raiseSequenceFunc: func(ctx context.Context, name string, value int64) (int64, error) {
+			if name != codeLengthSequence {
+				t.Errorf("Expected the '%s' counter, got '%s'", codeLengthSequence, name)
+			}
+			stored = max(stored, value)
+			return stored, nil
 		},
 	}
-	
-	service := NewShortnerService(mockRepo)
+
+	service := NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{})
+	if _, err := service.ShortenURL(context.Background(), types.ShortenRequest{URL: "https://example.com"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if stored != minCodeLength+1 {
+		t.Errorf("Expected the grown length %d to be stored, got %d", minCodeLength+1, stored)
+	}
+
+	// After a restart, the stored length is picked up again
+	restarted := NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{})
+	if err := restarted.LoadCodeLength(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if length := restarted.codeLength.Load(); length != minCodeLength+1 {
+		t.Errorf("Expected code length %d after a restart, got %d", minCodeLength+1, length)
+	}
+}
+
+func TestShortenURL_HashCollisionsKeepCodeLength(t *testing.T) {
+	taken := map[string]bool{}
+	mockRepo := &mockShortenerRepository{
+		createFunc: func(ctx context.Context, url models.URL) (string, error) {
+			if taken[url.Code] {
+				return "", models.ErrDuplicateCode
+			}
+			taken[url.Code] = true
+			return "test-id", nil
+		},
+	}
+
+	service := NewShortnerService(mockRepo, codegen.NewURLHash(), &config.Config{})
 	ctx := context.Background()
-	
-	result, err := service.RedirectURL(ctx, "NOTFOUND")
-	
+
+	// Every request after the first starts on the code of the first one
+	for i := 0; i < 5; i++ {
+		result, err := service.ShortenURL(ctx, types.ShortenRequest{URL: "https://example.com", Tags: []string{fmt.Sprintf("issue-%d", i)}})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(result.Code) != minCodeLength {
+			t.Errorf("Expected code length to stay at %d, got '%s'", minCodeLength, result.Code)
+		}
+	}
+
+	if length := service.codeLength.Load(); length != minCodeLength {
+		t.Errorf("Expected the code length of the server to stay at %d, got %d", minCodeLength, length)
+	}
+}
+
+func TestLoadCodeLength_KeepsConfiguredFloor(t *testing.T) {
+	mockRepo := &mockShortenerRepository{
+		raiseSequenceFunc: func(ctx context.Context, name string, value int64) (int64, error) {
+			return 5, nil
+		},
+	}
+
+	service := NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{
+		Shortener: config.ShortenerConfig{CodeLength: 7},
+	})
+	if err := service.LoadCodeLength(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if length := service.codeLength.Load(); length != 7 {
+		t.Errorf("Expected CODE_LENGTH 7 to win over the stored 5, got %d", length)
+	}
+}
+
+func TestShortenURL_GivesUpAfterMaxAttempts(t *testing.T) {
+	attempts := 0
+	mockRepo := &mockShortenerRepository{
+		createFunc: func(ctx context.Context, url models.URL) (string, error) {
+			attempts++
+			return "", models.ErrDuplicateCode
+		},
+	}
+
+	service := NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{})
+	ctx := context.Background()
+
+	result, err := service.ShortenURL(ctx, types.ShortenRequest{URL: "https://example.com"})
+
 	if err == nil {
-		t.Fatal("Expected error from repository")
+		t.Fatal("Expected error when every code collides")
 	}
-	
-	if result != "" {
-		t.Error("Expected result to be empty string when error occurs")
+
+	if result != nil {
+		t.Error("Expected result to be nil when error occurs")
 	}
-	
-	if err.Error() != "not found" {
-		t.Errorf("Expected error 'not found', got '%s'", err.Error())
+
+	if attempts != maxCodeAttempts {
+		t.Errorf("Expected %d attempts, got %d", maxCodeAttempts, attempts)
+	}
+}
+
+func TestShortenURL_UsesCodeGenerator(t *testing.T) {
+	generator := &mockCodeGenerator{
+		generateFunc: func(ctx context.Context, originalURL string, length, attempt int) (string, error) {
+			if originalURL != "https://example.com" {
+				t.Errorf("Expected URL 'https://example.com', got '%s'", originalURL)
+			}
+			if length != 6 {
+				t.Errorf("Expected the configured length 6, got %d", length)
+			}
+			return "custom", nil
+		},
+	}
+
+	cfg := &config.Config{Shortener: config.ShortenerConfig{CodeLength: 6}}
+	service := NewShortnerService(&mockShortenerRepository{}, generator, cfg)
+
+	result, err := service.ShortenURL(context.Background(), types.ShortenRequest{URL: "https://example.com"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if result.Code != "custom" {
+		t.Errorf("Expected code 'custom', got '%s'", result.Code)
+	}
+}
+
+func TestShortenURL_CodeGeneratorError(t *testing.T) {
+	generator := &mockCodeGenerator{
+		generateFunc: func(ctx context.Context, originalURL string, length, attempt int) (string, error) {
+			return "", errors.New("sequence unavailable")
+		},
+	}
+
+	service := NewShortnerService(&mockShortenerRepository{}, generator, &config.Config{})
+
+	result, err := service.ShortenURL(context.Background(), types.ShortenRequest{URL: "https://example.com"})
+	if err == nil {
+		t.Fatal("Expected error from code generator")
+	}
+
+	if result != nil {
+		t.Error("Expected result to be nil when error occurs")
+	}
+}
+
+func TestShortenURL_WithAlias(t *testing.T) {
+	mockRepo := &mockShortenerRepository{
+		createFunc: func(ctx context.Context, url models.URL) (string, error) {
+			if url.Code != "launch-2026" {
+				t.Errorf("Expected code 'launch-2026', got '%s'", url.Code)
+			}
+			return "test-id", nil
+		},
 	}
-}
\ No newline at end of file
+
+	service := NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{})
+	ctx := context.Background()
+
+	result, err := service.ShortenURL(ctx, types.ShortenRequest{URL: "https://example.com", Alias: "launch-2026"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if result.Code != "launch-2026" {
+		t.Errorf("Expected code 'launch-2026', got '%s'", result.Code)
+	}
+
+	if result.ID != "test-id" {
+		t.Errorf("Expected ID to be 'test-id', got '%s'", result.ID)
+	}
+}
+
+func TestShortenURL_InvalidAlias(t *testing.T) {
+	aliases := []string{"ab", "has space", "emoji-🚀", "slash/path", strings.Repeat("a", 33), "api", "Shorten", "healthz"}
+
+	for _, alias := range aliases {
+		mockRepo := &mockShortenerRepository{
+			createFunc: func(ctx context.Context, url models.URL) (string, error) {
+				t.Errorf("Expected alias '%s' to be rejected before reaching the repository", alias)
+				return "test-id", nil
+			},
+		}
+
+		service := NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{})
+
+		_, err := service.ShortenURL(context.Background(), types.ShortenRequest{URL: "https://example.com", Alias: alias})
+		if !errors.Is(err, models.ErrInvalidAlias) {
+			t.Errorf("Expected ErrInvalidAlias for '%s', got %v", alias, err)
+		}
+	}
+}
+
+func TestShortenURL_AliasTaken(t *testing.T) {
+	attempts := 0
+	mockRepo := &mockShortenerRepository{
+		createFunc: func(ctx context.Context, url models.URL) (string, error) {
+			attempts++
+			return "", fmt.Errorf("%w: %s", models.ErrDuplicateCode, url.Code)
+		},
+	}
+
+	service := NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{})
+
+	result, err := service.ShortenURL(context.Background(), types.ShortenRequest{URL: "https://example.com", Alias: "launch-2026"})
+	if !errors.Is(err, models.ErrAliasTaken) {
+		t.Errorf("Expected ErrAliasTaken, got %v", err)
+	}
+
+	if result != nil {
+		t.Error("Expected result to be nil when error occurs")
+	}
+
+	// A taken alias should not be retried with a generated code
+	if attempts != 1 {
+		t.Errorf("Expected 1 attempt, got %d", attempts)
+	}
+}
+
+func TestShortenURL_WithExpiration(t *testing.T) {
+	expiresAt := time.Now().Add(24 * time.Hour)
+
+	mockRepo := &mockShortenerRepository{
+		createFunc: func(ctx context.Context, url models.URL) (string, error) {
+			if url.ExpiresAt == nil || !url.ExpiresAt.Equal(expiresAt) {
+				t.Errorf("Expected expires_at %v, got %v", expiresAt, url.ExpiresAt)
+			}
+			if url.MaxClicks != 10 {
+				t.Errorf("Expected max clicks 10, got %d", url.MaxClicks)
+			}
+			return "test-id", nil
+		},
+	}
+
+	service := NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{})
+
+	_, err := service.ShortenURL(context.Background(), types.ShortenRequest{
+		URL:       "https://example.com",
+		ExpiresAt: &expiresAt,
+		MaxClicks: 10,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+}
+
+func TestShortenURL_InvalidExpiration(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+
+	tests := map[string]types.ShortenRequest{
+		"expires in the past": {URL: "https://example.com", ExpiresAt: &past},
+		"negative max clicks": {URL: "https://example.com", MaxClicks: -1},
+	}
+
+	for name, req := range tests {
+		t.Run(name, func(t *testing.T) {
+			service := NewShortnerService(&mockShortenerRepository{}, codegen.NewRandom(), &config.Config{})
+
+			_, err := service.ShortenURL(context.Background(), req)
+			if !errors.Is(err, models.ErrInvalidExpiration) {
+				t.Errorf("Expected ErrInvalidExpiration, got %v", err)
+			}
+		})
+	}
+}
+
+func TestRedirectURL_Success(t *testing.T) {
+	mockRepo := &mockShortenerRepository{
+		findOneFunc: func(ctx context.Context, code string) (*models.URL, error) {
+			if code != "TEST" {
+				t.Errorf("Expected code to be 'TEST', got '%s'", code)
+			}
+			return &models.URL{
+				ID:          "test-id",
+				OriginalURL: "https://example.com",
+				Code:        "TEST",
+				ClickCount:  5,
+			}, nil
+		},
+	}
+	
+	service := NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{})
+	ctx := context.Background()
+	
+	result, err := service.RedirectURL(ctx, types.RedirectRequest{Code: "TEST"})
+	
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	
+	if result.OriginalURL != "https://example.com" {
+		t.Errorf("Expected result to be 'https://example.com', got '%s'", result.OriginalURL)
+	}
+}
+
+func TestRedirectURL_EmptyCode(t *testing.T) {
+	mockRepo := &mockShortenerRepository{}
+	service := NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{})
+	ctx := context.Background()
+	
+	result, err := service.RedirectURL(ctx, types.RedirectRequest{Code: ""})
+	
+	if err == nil {
+		t.Fatal("Expected error for empty code")
+	}
+	
+	if result != nil {
+		t.Error("Expected result to be nil when error occurs")
+	}
+	
+	expectedError := "code cannot be empty"
+	if err.Error() != expectedError {
+		t.Errorf("Expected error '%s', got '%s'", expectedError, err.Error())
+	}
+}
+
+func TestRedirectURL_RepositoryError(t *testing.T) {
+	mockRepo := &mockShortenerRepository{
+		findOneFunc: func(ctx context.Context, code string) (*models.URL, error) {
+			return nil, errors.New("not found")
+		},
+	}
+	
+	service := NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{})
+	ctx := context.Background()
+	
+	result, err := service.RedirectURL(ctx, types.RedirectRequest{Code: "NOTFOUND"})
+	
+	if err == nil {
+		t.Fatal("Expected error from repository")
+	}
+	
+	if result != nil {
+		t.Error("Expected result to be nil when error occurs")
+	}
+	
+	if err.Error() != "not found" {
+		t.Errorf("Expected error 'not found', got '%s'", err.Error())
+	}
+}
+
+func TestRedirectURL_RecordsClick(t *testing.T) {
+	incremented := ""
+	var recorded *models.Click
+
+	mockRepo := &mockShortenerRepository{
+		incrementClickCountFunc: func(ctx context.Context, code string) error {
+			incremented = code
+			return nil
+		},
+		createClickFunc: func(ctx context.Context, click models.Click) error {
+			recorded = &click
+			return nil
+		},
+	}
+
+	service := NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{
+		Analytics: config.AnalyticsConfig{IPHashSecret: "test-ip-hash-secret-123"},
+	})
+	ctx := context.Background()
+
+	_, err := service.RedirectURL(ctx, types.RedirectRequest{
+		Code:      "TEST",
+		Referrer:  "https://news.example.org",
+		UserAgent: "test-agent",
+		ClientIP:  "203.0.113.7",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if incremented != "TEST" {
+		t.Errorf("Expected click count of 'TEST' to be incremented, got '%s'", incremented)
+	}
+
+	if recorded == nil {
+		t.Fatal("Expected a click to be recorded")
+	}
+
+	if recorded.Code != "TEST" {
+		t.Errorf("Expected click code 'TEST', got '%s'", recorded.Code)
+	}
+
+	if recorded.Referrer != "https://news.example.org" {
+		t.Errorf("Expected referrer 'https://news.example.org', got '%s'", recorded.Referrer)
+	}
+
+	if recorded.UserAgent != "test-agent" {
+		t.Errorf("Expected user agent 'test-agent', got '%s'", recorded.UserAgent)
+	}
+
+	if recorded.IPHash != utils.HashIP([]byte("test-ip-hash-secret-123"), "203.0.113.7", recorded.Timestamp) {
+		t.Errorf("Expected the client IP to be stored hashed, got '%s'", recorded.IPHash)
+	}
+
+	if recorded.Timestamp.IsZero() {
+		t.Error("Expected click timestamp to be set")
+	}
+}
+
+func TestRedirectURL_ClickErrorsDoNotBlockRedirect(t *testing.T) {
+	mockRepo := &mockShortenerRepository{
+		incrementClickCountFunc: func(ctx context.Context, code string) error {
+			return errors.New("database error")
+		},
+		createClickFunc: func(ctx context.Context, click models.Click) error {
+			return errors.New("database error")
+		},
+	}
+
+	service := NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{})
+	ctx := context.Background()
+
+	result, err := service.RedirectURL(ctx, types.RedirectRequest{Code: "TEST"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if result.OriginalURL != "https://example.com" {
+		t.Errorf("Expected result to be 'https://example.com', got '%s'", result.OriginalURL)
+	}
+}
+
+func TestRedirectURL_Expired(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	clicked := false
+
+	mockRepo := &mockShortenerRepository{
+		findOneFunc: func(ctx context.Context, code string) (*models.URL, error) {
+			return &models.URL{OriginalURL: "https://example.com", Code: code, ExpiresAt: &past}, nil
+		},
+		incrementClickCountFunc: func(ctx context.Context, code string) error {
+			clicked = true
+			return nil
+		},
+	}
+
+	service := NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{})
+
+	result, err := service.RedirectURL(context.Background(), types.RedirectRequest{Code: "TEST"})
+	if !errors.Is(err, models.ErrExpired) {
+		t.Errorf("Expected ErrExpired, got %v", err)
+	}
+
+	if result != nil {
+		t.Error("Expected result to be nil when error occurs")
+	}
+
+	if clicked {
+		t.Error("Expected no click to be counted for an expired link")
+	}
+}
+
+func TestRedirectURL_NoClicksLeft(t *testing.T) {
+	recorded := false
+
+	mockRepo := &mockShortenerRepository{
+		findOneFunc: func(ctx context.Context, code string) (*models.URL, error) {
+			return &models.URL{OriginalURL: "https://example.com", Code: code, MaxClicks: 1, ClickCount: 1}, nil
+		},
+		incrementClickCountFunc: func(ctx context.Context, code string) error {
+			return fmt.Errorf("%w: %s has no clicks left", models.ErrExpired, code)
+		},
+		createClickFunc: func(ctx context.Context, click models.Click) error {
+			recorded = true
+			return nil
+		},
+	}
+
+	service := NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{})
+
+	_, err := service.RedirectURL(context.Background(), types.RedirectRequest{Code: "TEST"})
+	if !errors.Is(err, models.ErrExpired) {
+		t.Errorf("Expected ErrExpired, got %v", err)
+	}
+
+	if recorded {
+		t.Error("Expected no click event for a link without clicks left")
+	}
+}
+
+func TestRedirectURL_NotFound(t *testing.T) {
+	mockRepo := &mockShortenerRepository{
+		findOneFunc: func(ctx context.Context, code string) (*models.URL, error) {
+			return nil, fmt.Errorf("%w: %s", models.ErrNotFound, code)
+		},
+	}
+
+	service := NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{})
+
+	result, err := service.RedirectURL(context.Background(), types.RedirectRequest{Code: "NOTFOUND"})
+	if !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	if result != nil {
+		t.Error("Expected result to be nil when error occurs")
+	}
+}
+
+func TestRedirectURL_Disabled(t *testing.T) {
+	clicked := false
+
+	mockRepo := &mockShortenerRepository{
+		findOneFunc: func(ctx context.Context, code string) (*models.URL, error) {
+			return &models.URL{OriginalURL: "https://example.com", Code: code, Disabled: true}, nil
+		},
+		incrementClickCountFunc: func(ctx context.Context, code string) error {
+			clicked = true
+			return nil
+		},
+	}
+
+	service := NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{})
+
+	_, err := service.RedirectURL(context.Background(), types.RedirectRequest{Code: "TEST"})
+	if !errors.Is(err, models.ErrDisabled) {
+		t.Errorf("Expected ErrDisabled, got %v", err)
+	}
+
+	if clicked {
+		t.Error("Expected no click to be counted for a disabled link")
+	}
+}
+
+func TestRedirectURL_DefaultRedirectType(t *testing.T) {
+	cfg := &config.Config{Server: config.ServerConfig{DefaultRedirectType: 301}}
+	service := NewShortnerService(&mockShortenerRepository{}, codegen.NewRandom(), cfg)
+
+	result, err := service.RedirectURL(context.Background(), types.RedirectRequest{Code: "TEST"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if result.RedirectType != 301 {
+		t.Errorf("Expected the configured default 301, got %d", result.RedirectType)
+	}
+
+	// Without a configured default we don't want browsers to cache the redirect
+	service = NewShortnerService(&mockShortenerRepository{}, codegen.NewRandom(), &config.Config{})
+
+	result, err = service.RedirectURL(context.Background(), types.RedirectRequest{Code: "TEST"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if result.RedirectType != 302 {
+		t.Errorf("Expected 302, got %d", result.RedirectType)
+	}
+}
+
+func TestRedirectURL_LinkRedirectType(t *testing.T) {
+	mockRepo := &mockShortenerRepository{
+		findOneFunc: func(ctx context.Context, code string) (*models.URL, error) {
+			return &models.URL{OriginalURL: "https://api.example.com/hook", Code: code, RedirectType: 307}, nil
+		},
+	}
+
+	cfg := &config.Config{Server: config.ServerConfig{DefaultRedirectType: 301}}
+	service := NewShortnerService(mockRepo, codegen.NewRandom(), cfg)
+
+	result, err := service.RedirectURL(context.Background(), types.RedirectRequest{Code: "TEST", Method: "POST"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if result.RedirectType != 307 {
+		t.Errorf("Expected the link's own redirect type 307, got %d", result.RedirectType)
+	}
+}
+
+func TestRedirectURL_MethodNotAcceptedByLink(t *testing.T) {
+	clicked := false
+	mockRepo := &mockShortenerRepository{
+		findOneFunc: func(ctx context.Context, code string) (*models.URL, error) {
+			return &models.URL{OriginalURL: "https://example.com", Code: code, RedirectType: 302}, nil
+		},
+		incrementClickCountFunc: func(ctx context.Context, code string) error {
+			clicked = true
+			return nil
+		},
+	}
+
+	service := NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{})
+
+	_, err := service.RedirectURL(context.Background(), types.RedirectRequest{Code: "TEST", Method: "POST"})
+	if !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	if clicked {
+		t.Error("Expected no click to be counted for a rejected request")
+	}
+}
+
+func TestShortenURL_RedirectType(t *testing.T) {
+	mockRepo := &mockShortenerRepository{
+		findByOriginalURLFunc: func(ctx context.Context, originalURL string) (*models.URL, error) {
+			t.Error("Expected links with their own redirect type not to be deduplicated")
+			return nil, models.ErrNotFound
+		},
+		createFunc: func(ctx context.Context, url models.URL) (string, error) {
+			if url.RedirectType != 308 {
+				t.Errorf("Expected redirect type 308, got %d", url.RedirectType)
+			}
+			return "test-id", nil
+		},
+	}
+
+	service := NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{})
+
+	if _, err := service.ShortenURL(context.Background(), types.ShortenRequest{URL: "https://example.com", RedirectType: 308}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	_, err := service.ShortenURL(context.Background(), types.ShortenRequest{URL: "https://example.com", RedirectType: 303})
+	if !errors.Is(err, models.ErrInvalidRedirect) {
+		t.Errorf("Expected ErrInvalidRedirect for 303, got %v", err)
+	}
+}
+
+func TestShortenURL_Tags(t *testing.T) {
+	mockRepo := &mockShortenerRepository{
+		findByOriginalURLFunc: func(ctx context.Context, originalURL string) (*models.URL, error) {
+			t.Error("Expected tagged links not to be deduplicated")
+			return nil, models.ErrNotFound
+		},
+		createFunc: func(ctx context.Context, url models.URL) (string, error) {
+			if len(url.Tags) != 2 || url.Tags[0] != "newsletter" || url.Tags[1] != "2026-q1" {
+				t.Errorf("Expected normalized tags, got %v", url.Tags)
+			}
+			if url.Domain != "example.com" {
+				t.Errorf("Expected domain 'example.com', got '%s'", url.Domain)
+			}
+			if url.CreatedAt.IsZero() {
+				t.Error("Expected created_at to be set")
+			}
+			return "test-id", nil
+		},
+	}
+
+	service := NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{})
+
+	_, err := service.ShortenURL(context.Background(), types.ShortenRequest{
+		URL:  "https://Example.com/sale",
+		Tags: []string{" Newsletter", "2026-q1", "newsletter"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	_, err = service.ShortenURL(context.Background(), types.ShortenRequest{
+		URL:  "https://example.com/sale",
+		Tags: []string{"not a tag"},
+	})
+	if !errors.Is(err, models.ErrInvalidTags) {
+		t.Errorf("Expected ErrInvalidTags, got %v", err)
+	}
+}
+
+// Mock metrics for testing
+type mockShortenerMetrics struct {
+	redirects []string
+	retries   int
+}
+
+func (m *mockShortenerMetrics) Redirect(outcome string) {
+	m.redirects = append(m.redirects, outcome)
+}
+
+func (m *mockShortenerMetrics) CodeRetry() {
+	m.retries++
+}
+
+func TestShortenURL_ReportsRetries(t *testing.T) {
+	attempts := 0
+	mockRepo := &mockShortenerRepository{
+		createFunc: func(ctx context.Context, url models.URL) (string, error) {
+			attempts++
+			if attempts < 3 {
+				return "", fmt.Errorf("%w: %s", models.ErrDuplicateCode, url.Code)
+			}
+			return "test-id", nil
+		},
+	}
+
+	metrics := &mockShortenerMetrics{}
+	service := NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{})
+	service.SetMetrics(metrics)
+
+	if _, err := service.ShortenURL(context.Background(), types.ShortenRequest{URL: "https://example.com"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if metrics.retries != 2 {
+		t.Errorf("Expected 2 retries, got %d", metrics.retries)
+	}
+}
+
+func TestRedirectURL_ReportsOutcome(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	links := map[string]*models.URL{
+		"live":     {OriginalURL: "https://example.com", Code: "live"},
+		"expired":  {OriginalURL: "https://example.com", Code: "expired", ExpiresAt: &past},
+		"disabled": {OriginalURL: "https://example.com", Code: "disabled", Disabled: true},
+	}
+	mockRepo := &mockShortenerRepository{
+		findOneFunc: func(ctx context.Context, code string) (*models.URL, error) {
+			if code == "broken" {
+				return nil, errors.New("connection refused")
+			}
+			if url, ok := links[code]; ok {
+				return url, nil
+			}
+			return nil, fmt.Errorf("%w: %s", models.ErrNotFound, code)
+		},
+	}
+
+	metrics := &mockShortenerMetrics{}
+	service := NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{})
+	service.SetMetrics(metrics)
+
+	for _, code := range []string{"live", "missing", "expired", "disabled", "broken"} {
+		service.RedirectURL(context.Background(), types.RedirectRequest{Code: code})
+	}
+
+	expected := []string{models.RedirectHit, models.RedirectMiss, models.RedirectExpired, models.RedirectDisabled, models.RedirectError}
+	if strings.Join(metrics.redirects, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected outcomes %v, got %v", expected, metrics.redirects)
+	}
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/topboyasante/trunc8/internal/auth"
+	"github.com/topboyasante/trunc8/internal/models"
+)
+
+const (
+	defaultStatsRange = 7 * 24 * time.Hour
+	defaultStatsLimit = 10
+	maxStatsBuckets   = 24 * 92 // about three months of hourly buckets
+)
+
+// LinkStats returns the click analytics of a link between query.From and query.To.
+// Missing values are filled with defaults: the last 7 days, hourly buckets for ranges
+// up to two days and daily buckets for anything longer.
+func (s *ShortnerService) LinkStats(ctx context.Context, query models.StatsQuery) (*models.LinkStats, error) {
+	if query.Code == "" {
+		return nil, errors.New("code cannot be empty")
+	}
+
+	query, err := normalizeStatsQuery(query, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	// Make sure the link exists, otherwise we'd happily return empty stats for any code.
+	// This is also what keeps other workspaces from reading the stats.
+	query.WorkspaceID = auth.WorkspaceID(ctx)
+	link, err := s.repository.FindInWorkspace(ctx, query.WorkspaceID, query.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	// Clicks are stored by code, and the code may have belonged to an earlier link: Mongo's TTL index
+	// deletes expired links but not their clicks. Only the clicks made since this link was created are its own.
+	clicksQuery := query
+	if clicksQuery.From.Before(link.CreatedAt) {
+		clicksQuery.From = link.CreatedAt
+	}
+
+	stats, err := s.repository.ClickStats(ctx, clicksQuery)
+	if err != nil {
+		return nil, err
+	}
+	stats.From = query.From
+
+	stats.Series = fillSeries(stats.Series, query)
+
+	return stats, nil
+}
+
+func normalizeStatsQuery(query models.StatsQuery, now time.Time) (models.StatsQuery, error) {
+	if query.To.IsZero() {
+		query.To = now
+	}
+	if query.From.IsZero() {
+		query.From = query.To.Add(-defaultStatsRange)
+	}
+	if !query.From.Before(query.To) {
+		return query, fmt.Errorf("%w: from must be before to", models.ErrInvalidStatsQuery)
+	}
+
+	if query.Interval == "" {
+		query.Interval = "hour"
+		if query.To.Sub(query.From) > 48*time.Hour {
+			query.Interval = "day"
+		}
+	}
+
+	step, ok := intervalStep(query.Interval)
+	if !ok {
+		return query, fmt.Errorf("%w: interval must be 'hour' or 'day'", models.ErrInvalidStatsQuery)
+	}
+	if query.To.Sub(query.From)/step > maxStatsBuckets {
+		return query, fmt.Errorf("%w: range is too large for %s buckets", models.ErrInvalidStatsQuery, query.Interval)
+	}
+
+	if query.Limit <= 0 {
+		query.Limit = defaultStatsLimit
+	}
+
+	// The database truncates timestamps in UTC, so our buckets have to line up with that
+	query.From = query.From.UTC()
+	query.To = query.To.UTC()
+
+	return query, nil
+}
+
+func intervalStep(interval string) (time.Duration, bool) {
+	switch interval {
+	case "hour":
+		return time.Hour, true
+	case "day":
+		return 24 * time.Hour, true
+	default:
+		return 0, false
+	}
+}
+
+// fillSeries adds empty buckets for the hours or days without clicks,
+// so charts get a continuous series instead of having to fill the gaps themselves.
+func fillSeries(buckets []models.StatsBucket, query models.StatsQuery) []models.StatsBucket {
+	step, _ := intervalStep(query.Interval)
+
+	clicks := make(map[int64]int64, len(buckets))
+	for _, b := range buckets {
+		clicks[b.Time.Unix()] = b.Clicks
+	}
+
+	series := []models.StatsBucket{}
+	for t := query.From.Truncate(step); t.Before(query.To); t = t.Add(step) {
+		series = append(series, models.StatsBucket{Time: t, Clicks: clicks[t.Unix()]})
+	}
+	return series
+}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/topboyasante/trunc8/internal/codegen"
+	"github.com/topboyasante/trunc8/internal/config"
+	"github.com/topboyasante/trunc8/internal/models"
+)
+
+func TestLinkStats_Success(t *testing.T) {
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 3, 1, 4, 0, 0, 0, time.UTC)
+
+	mockRepo := &mockShortenerRepository{
+		clickStatsFunc: func(ctx context.Context, query models.StatsQuery) (*models.LinkStats, error) {
+			if query.Code != "TEST" {
+				t.Errorf("Expected code 'TEST', got '%s'", query.Code)
+			}
+			if query.Interval != "hour" {
+				t.Errorf("Expected interval 'hour', got '%s'", query.Interval)
+			}
+			if query.Limit != defaultStatsLimit {
+				t.Errorf("Expected limit %d, got %d", defaultStatsLimit, query.Limit)
+			}
+			return &models.LinkStats{
+				Code:        query.Code,
+				TotalClicks: 3,
+				Series: []models.StatsBucket{
+					{Time: from.Add(time.Hour), Clicks: 2},
+					{Time: from.Add(3 * time.Hour), Clicks: 1},
+				},
+			}, nil
+		},
+	}
+
+	service := NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{})
+
+	stats, err := service.LinkStats(context.Background(), models.StatsQuery{Code: "TEST", From: from, To: to})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if stats.TotalClicks != 3 {
+		t.Errorf("Expected 3 total clicks, got %d", stats.TotalClicks)
+	}
+
+	// Hours without clicks should be filled with empty buckets
+	expected := []int64{0, 2, 0, 1}
+	if len(stats.Series) != len(expected) {
+		t.Fatalf("Expected %d buckets, got %d", len(expected), len(stats.Series))
+	}
+	for i, clicks := range expected {
+		if stats.Series[i].Clicks != clicks {
+			t.Errorf("Expected %d clicks in bucket %d, got %d", clicks, i, stats.Series[i].Clicks)
+		}
+		if !stats.Series[i].Time.Equal(from.Add(time.Duration(i) * time.Hour)) {
+			t.Errorf("Unexpected time for bucket %d: %v", i, stats.Series[i].Time)
+		}
+	}
+}
+
+func TestLinkStats_OnlyClicksOfTheLink(t *testing.T) {
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 3, 1, 4, 0, 0, 0, time.UTC)
+	createdAt := from.Add(90 * time.Minute)
+
+	mockRepo := &mockShortenerRepository{
+		findInWorkspaceFunc: func(ctx context.Context, workspaceID, code string) (*models.URL, error) {
+			return &models.URL{Code: code, CreatedAt: createdAt}, nil
+		},
+		clickStatsFunc: func(ctx context.Context, query models.StatsQuery) (*models.LinkStats, error) {
+			// The code was reused: the clicks before createdAt belong to the link it had before
+			if !query.From.Equal(createdAt) {
+				t.Errorf("Expected clicks from %v, got %v", createdAt, query.From)
+			}
+			return &models.LinkStats{Code: query.Code, From: query.From, To: query.To}, nil
+		},
+	}
+
+	service := NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{})
+
+	stats, err := service.LinkStats(context.Background(), models.StatsQuery{Code: "TEST", From: from, To: to})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// The range asked for is still the one reported
+	if !stats.From.Equal(from) || len(stats.Series) != 4 {
+		t.Errorf("Expected 4 buckets from %v, got %d from %v", from, len(stats.Series), stats.From)
+	}
+}
+
+func TestLinkStats_Defaults(t *testing.T) {
+	mockRepo := &mockShortenerRepository{
+		clickStatsFunc: func(ctx context.Context, query models.StatsQuery) (*models.LinkStats, error) {
+			if query.To.Sub(query.From) != defaultStatsRange {
+				t.Errorf("Expected default range of %v, got %v", defaultStatsRange, query.To.Sub(query.From))
+			}
+			if query.Interval != "day" {
+				t.Errorf("Expected daily buckets for the default range, got '%s'", query.Interval)
+			}
+			return &models.LinkStats{Code: query.Code}, nil
+		},
+	}
+
+	service := NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{})
+
+	stats, err := service.LinkStats(context.Background(), models.StatsQuery{Code: "TEST"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// 7 days can span 8 calendar days once truncated to midnight
+	if len(stats.Series) < 7 || len(stats.Series) > 8 {
+		t.Errorf("Expected 7 or 8 daily buckets, got %d", len(stats.Series))
+	}
+}
+
+func TestLinkStats_InvalidQuery(t *testing.T) {
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := map[string]models.StatsQuery{
+		"from after to":    {Code: "TEST", From: from, To: from.Add(-time.Hour)},
+		"unknown interval": {Code: "TEST", From: from, To: from.Add(time.Hour), Interval: "minute"},
+		"range too large":  {Code: "TEST", From: from, To: from.AddDate(1, 0, 0), Interval: "hour"},
+	}
+
+	for name, query := range tests {
+		t.Run(name, func(t *testing.T) {
+			service := NewShortnerService(&mockShortenerRepository{}, codegen.NewRandom(), &config.Config{})
+
+			_, err := service.LinkStats(context.Background(), query)
+			if !errors.Is(err, models.ErrInvalidStatsQuery) {
+				t.Errorf("Expected ErrInvalidStatsQuery, got %v", err)
+			}
+		})
+	}
+}
+
+func TestLinkStats_RepositoryError(t *testing.T) {
+	mockRepo := &mockShortenerRepository{
+		clickStatsFunc: func(ctx context.Context, query models.StatsQuery) (*models.LinkStats, error) {
+			return nil, errors.New("database error")
+		},
+	}
+
+	service := NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{})
+
+	stats, err := service.LinkStats(context.Background(), models.StatsQuery{Code: "TEST"})
+	if err == nil {
+		t.Fatal("Expected error from repository")
+	}
+
+	if stats != nil {
+		t.Error("Expected stats to be nil when error occurs")
+	}
+}
+
+func TestLinkStats_NotFound(t *testing.T) {
+	mockRepo := &mockShortenerRepository{
+		findOneFunc: func(ctx context.Context, code string) (*models.URL, error) {
+			return nil, models.ErrNotFound
+		},
+		clickStatsFunc: func(ctx context.Context, query models.StatsQuery) (*models.LinkStats, error) {
+			t.Error("Expected stats not to be aggregated for an unknown link")
+			return nil, nil
+		},
+	}
+
+	service := NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{})
+
+	_, err := service.LinkStats(context.Background(), models.StatsQuery{Code: "NOTFOUND"})
+	if !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+
+	"github.com/topboyasante/trunc8/internal/models"
+	"github.com/topboyasante/trunc8/internal/tracing"
+	"github.com/topboyasante/trunc8/internal/types"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/topboyasante/trunc8/internal/services"
+
+// codeKey is the span attribute holding the short code a call is about
+const codeKey = attribute.Key("trunc8.link.code")
+
+// TracedShortnerService starts a span for every call to the service it wraps,
+// between the span of the request and those of the storage calls the service makes.
+type TracedShortnerService struct {
+	*ShortnerService
+}
+
+func NewTracedShortnerService(service *ShortnerService) *TracedShortnerService {
+	return &TracedShortnerService{ShortnerService: service}
+}
+
+func (s *TracedShortnerService) start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	// Looked up on every call, so the provider installed by tracing.Setup is used whenever it was installed
+	return otel.Tracer(tracerName).Start(ctx, "ShortnerService."+name, trace.WithAttributes(attrs...))
+}
+
+func (s *TracedShortnerService) ShortenURL(ctx context.Context, req types.ShortenRequest) (*models.URL, error) {
+	ctx, span := s.start(ctx, "ShortenURL")
+	url, err := s.ShortnerService.ShortenURL(ctx, req)
+	if err == nil {
+		span.SetAttributes(codeKey.String(url.Code))
+	}
+	tracing.End(span, err)
+	return url, err
+}
+
+func (s *TracedShortnerService) ShortenMany(ctx context.Context, reqs []types.ShortenRequest) ([]*models.URL, []error, error) {
+	ctx, span := s.start(ctx, "ShortenMany", attribute.Int("trunc8.bulk.size", len(reqs)))
+	links, errs, err := s.ShortnerService.ShortenMany(ctx, reqs)
+	if err == nil {
+		failed := 0
+		for _, e := range errs {
+			if e != nil {
+				failed++
+			}
+		}
+		span.SetAttributes(attribute.Int("trunc8.bulk.failed", failed))
+	}
+	tracing.End(span, err)
+	return links, errs, err
+}
+
+func (s *TracedShortnerService) RedirectURL(ctx context.Context, req types.RedirectRequest) (*models.URL, error) {
+	ctx, span := s.start(ctx, "RedirectURL", codeKey.String(req.Code))
+	url, err := s.ShortnerService.RedirectURL(ctx, req)
+	tracing.End(span, err)
+	return url, err
+}
+
+func (s *TracedShortnerService) LinkStats(ctx context.Context, query models.StatsQuery) (*models.LinkStats, error) {
+	ctx, span := s.start(ctx, "LinkStats", codeKey.String(query.Code))
+	stats, err := s.ShortnerService.LinkStats(ctx, query)
+	tracing.End(span, err)
+	return stats, err
+}
+
+func (s *TracedShortnerService) GetLink(ctx context.Context, code string) (*models.URL, error) {
+	ctx, span := s.start(ctx, "GetLink", codeKey.String(code))
+	url, err := s.ShortnerService.GetLink(ctx, code)
+	tracing.End(span, err)
+	return url, err
+}
+
+func (s *TracedShortnerService) UpdateLink(ctx context.Context, code string, req types.UpdateLinkRequest) (*models.URL, error) {
+	ctx, span := s.start(ctx, "UpdateLink", codeKey.String(code))
+	url, err := s.ShortnerService.UpdateLink(ctx, code, req)
+	tracing.End(span, err)
+	return url, err
+}
+
+func (s *TracedShortnerService) SetLinkDisabled(ctx context.Context, code string, disabled bool) (*models.URL, error) {
+	ctx, span := s.start(ctx, "SetLinkDisabled", codeKey.String(code), attribute.Bool("trunc8.link.disabled", disabled))
+	url, err := s.ShortnerService.SetLinkDisabled(ctx, code, disabled)
+	tracing.End(span, err)
+	return url, err
+}
+
+func (s *TracedShortnerService) DeleteLink(ctx context.Context, code string) error {
+	ctx, span := s.start(ctx, "DeleteLink", codeKey.String(code))
+	err := s.ShortnerService.DeleteLink(ctx, code)
+	tracing.End(span, err)
+	return err
+}
+
+func (s *TracedShortnerService) ListLinks(ctx context.Context, query models.ListQuery) (*models.LinkPage, error) {
+	ctx, span := s.start(ctx, "ListLinks")
+	page, err := s.ShortnerService.ListLinks(ctx, query)
+	tracing.End(span, err)
+	return page, err
+}
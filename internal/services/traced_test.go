@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/topboyasante/trunc8/internal/codegen"
+	"github.com/topboyasante/trunc8/internal/config"
+	"github.com/topboyasante/trunc8/internal/models"
+	"github.com/topboyasante/trunc8/internal/types"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracedShortnerService_RedirectURL(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(previous)
+
+	mockRepo := &mockShortenerRepository{
+		findOneFunc: func(ctx context.Context, code string) (*models.URL, error) {
+			if code == "broken" {
+				return nil, errors.New("connection refused")
+			}
+			return nil, fmt.Errorf("%w: %s", models.ErrNotFound, code)
+		},
+	}
+	service := NewTracedShortnerService(NewShortnerService(mockRepo, codegen.NewRandom(), &config.Config{}))
+
+	// The service span is a child of the one in the context
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "GET /{code}")
+	service.RedirectURL(ctx, types.RedirectRequest{Code: "missing"})
+	service.RedirectURL(ctx, types.RedirectRequest{Code: "broken"})
+	parent.End()
+
+	ended := recorder.Ended()
+	if len(ended) != 3 {
+		t.Fatalf("Expected 3 spans, got %d", len(ended))
+	}
+
+	missing, broken := ended[0], ended[1]
+	if missing.Name() != "ShortnerService.RedirectURL" || missing.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("Expected a RedirectURL span under the request span, got %s under %s", missing.Name(), missing.Parent().SpanID())
+	}
+	if missing.Status().Code != codes.Unset {
+		t.Errorf("Expected a missing link not to mark the span as failed, got %v", missing.Status())
+	}
+	if broken.Status().Code != codes.Error {
+		t.Errorf("Expected a storage failure to mark the span as failed, got %v", broken.Status())
+	}
+
+	found := false
+	for _, attr := range missing.Attributes() {
+		if attr == codeKey.String("missing") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected the code in the attributes, got %v", missing.Attributes())
+	}
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/topboyasante/trunc8/internal/models"
+	"github.com/topboyasante/trunc8/internal/types"
+)
+
+// WorkspaceRepositoryInterface defines the interface for workspace and user storage
+type WorkspaceRepositoryInterface interface {
+	CreateWorkspace(ctx context.Context, workspace models.Workspace) (string, error)
+	FindWorkspace(ctx context.Context, id string) (*models.Workspace, error)
+	ListWorkspaces(ctx context.Context) ([]*models.Workspace, error)
+	CreateUser(ctx context.Context, user models.User) (string, error)
+	FindUser(ctx context.Context, id string) (*models.User, error)
+	ListUsers(ctx context.Context, workspaceID string) ([]*models.User, error)
+}
+
+type WorkspaceService struct {
+	repository WorkspaceRepositoryInterface
+}
+
+func NewWorkspaceService(repository WorkspaceRepositoryInterface) *WorkspaceService {
+	return &WorkspaceService{repository: repository}
+}
+
+func (s *WorkspaceService) CreateWorkspace(ctx context.Context, req types.CreateWorkspaceRequest) (*models.Workspace, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 100 {
+		return nil, fmt.Errorf("%w: name must be 1 to 100 characters", models.ErrInvalidWorkspace)
+	}
+
+	workspace := &models.Workspace{
+		Name:      name,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	id, err := s.repository.CreateWorkspace(ctx, *workspace)
+	if err != nil {
+		return nil, err
+	}
+	workspace.ID = id
+
+	return workspace, nil
+}
+
+func (s *WorkspaceService) ListWorkspaces(ctx context.Context) ([]*models.Workspace, error) {
+	workspaces, err := s.repository.ListWorkspaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if workspaces == nil {
+		workspaces = []*models.Workspace{}
+	}
+	return workspaces, nil
+}
+
+// CreateUser adds a member to an existing workspace.
+func (s *WorkspaceService) CreateUser(ctx context.Context, workspaceID string, req types.CreateUserRequest) (*models.User, error) {
+	if _, err := s.repository.FindWorkspace(ctx, workspaceID); err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 100 {
+		return nil, fmt.Errorf("%w: name must be 1 to 100 characters", models.ErrInvalidUser)
+	}
+
+	address, err := mail.ParseAddress(req.Email)
+	if err != nil || address.Name != "" {
+		return nil, fmt.Errorf("%w: %q is not an email address", models.ErrInvalidUser, req.Email)
+	}
+
+	user := &models.User{
+		WorkspaceID: workspaceID,
+		Name:        name,
+		Email:       strings.ToLower(address.Address),
+		CreatedAt:   time.Now().UTC(),
+	}
+
+	id, err := s.repository.CreateUser(ctx, *user)
+	if err != nil {
+		return nil, err
+	}
+	user.ID = id
+
+	return user, nil
+}
+
+func (s *WorkspaceService) ListUsers(ctx context.Context, workspaceID string) ([]*models.User, error) {
+	if _, err := s.repository.FindWorkspace(ctx, workspaceID); err != nil {
+		return nil, err
+	}
+
+	users, err := s.repository.ListUsers(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if users == nil {
+		users = []*models.User{}
+	}
+	return users, nil
+}
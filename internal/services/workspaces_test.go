@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/topboyasante/trunc8/internal/models"
+	"github.com/topboyasante/trunc8/internal/types"
+)
+
+// Mock workspace repository for testing
+type mockWorkspaceRepository struct {
+	createWorkspaceFunc func(ctx context.Context, workspace models.Workspace) (string, error)
+	findWorkspaceFunc   func(ctx context.Context, id string) (*models.Workspace, error)
+	listWorkspacesFunc  func(ctx context.Context) ([]*models.Workspace, error)
+	createUserFunc      func(ctx context.Context, user models.User) (string, error)
+	findUserFunc        func(ctx context.Context, id string) (*models.User, error)
+	listUsersFunc       func(ctx context.Context, workspaceID string) ([]*models.User, error)
+}
+
+func (m *mockWorkspaceRepository) CreateWorkspace(ctx context.Context, workspace models.Workspace) (string, error) {
+	if m.createWorkspaceFunc != nil {
+		return m.createWorkspaceFunc(ctx, workspace)
+	}
+	return "ws-1", nil
+}
+
+func (m *mockWorkspaceRepository) FindWorkspace(ctx context.Context, id string) (*models.Workspace, error) {
+	if m.findWorkspaceFunc != nil {
+		return m.findWorkspaceFunc(ctx, id)
+	}
+	return &models.Workspace{ID: id, Name: "Growth"}, nil
+}
+
+func (m *mockWorkspaceRepository) ListWorkspaces(ctx context.Context) ([]*models.Workspace, error) {
+	if m.listWorkspacesFunc != nil {
+		return m.listWorkspacesFunc(ctx)
+	}
+	return nil, nil
+}
+
+func (m *mockWorkspaceRepository) CreateUser(ctx context.Context, user models.User) (string, error) {
+	if m.createUserFunc != nil {
+		return m.createUserFunc(ctx, user)
+	}
+	return "user-1", nil
+}
+
+func (m *mockWorkspaceRepository) FindUser(ctx context.Context, id string) (*models.User, error) {
+	if m.findUserFunc != nil {
+		return m.findUserFunc(ctx, id)
+	}
+	return &models.User{ID: id, WorkspaceID: "ws-1", Name: "Ama"}, nil
+}
+
+func (m *mockWorkspaceRepository) ListUsers(ctx context.Context, workspaceID string) ([]*models.User, error) {
+	if m.listUsersFunc != nil {
+		return m.listUsersFunc(ctx, workspaceID)
+	}
+	return nil, nil
+}
+
+func TestCreateWorkspace(t *testing.T) {
+	service := NewWorkspaceService(&mockWorkspaceRepository{})
+
+	workspace, err := service.CreateWorkspace(context.Background(), types.CreateWorkspaceRequest{Name: " Growth "})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if workspace.ID != "ws-1" || workspace.Name != "Growth" || workspace.CreatedAt.IsZero() {
+		t.Errorf("Unexpected workspace: %+v", workspace)
+	}
+
+	_, err = service.CreateWorkspace(context.Background(), types.CreateWorkspaceRequest{Name: "  "})
+	if !errors.Is(err, models.ErrInvalidWorkspace) {
+		t.Errorf("Expected ErrInvalidWorkspace, got %v", err)
+	}
+}
+
+func TestCreateUser(t *testing.T) {
+	var stored models.User
+	mockRepo := &mockWorkspaceRepository{
+		createUserFunc: func(ctx context.Context, user models.User) (string, error) {
+			stored = user
+			return "user-1", nil
+		},
+	}
+
+	service := NewWorkspaceService(mockRepo)
+
+	user, err := service.CreateUser(context.Background(), "ws-1", types.CreateUserRequest{Name: "Ama", Email: "Ama@Example.com"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if user.ID != "user-1" || stored.WorkspaceID != "ws-1" || stored.Email != "ama@example.com" {
+		t.Errorf("Unexpected user: %+v", stored)
+	}
+
+	for _, req := range []types.CreateUserRequest{
+		{Name: "", Email: "ama@example.com"},
+		{Name: "Ama", Email: "not an email"},
+		{Name: "Ama", Email: "Ama <ama@example.com>"},
+	} {
+		if _, err := service.CreateUser(context.Background(), "ws-1", req); !errors.Is(err, models.ErrInvalidUser) {
+			t.Errorf("Expected ErrInvalidUser for %+v, got %v", req, err)
+		}
+	}
+}
+
+func TestCreateUser_UnknownWorkspace(t *testing.T) {
+	mockRepo := &mockWorkspaceRepository{
+		findWorkspaceFunc: func(ctx context.Context, id string) (*models.Workspace, error) {
+			return nil, models.ErrWorkspaceNotFound
+		},
+		createUserFunc: func(ctx context.Context, user models.User) (string, error) {
+			t.Error("Expected no user to be created in a workspace that doesn't exist")
+			return "", nil
+		},
+	}
+
+	service := NewWorkspaceService(mockRepo)
+
+	_, err := service.CreateUser(context.Background(), "missing", types.CreateUserRequest{Name: "Ama", Email: "ama@example.com"})
+	if !errors.Is(err, models.ErrWorkspaceNotFound) {
+		t.Errorf("Expected ErrWorkspaceNotFound, got %v", err)
+	}
+}
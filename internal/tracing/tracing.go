@@ -0,0 +1,81 @@
+// Package tracing sets up OpenTelemetry: where spans are exported, how many traces are sampled,
+// and how trace context is read from and written to requests.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/topboyasante/trunc8/internal/config"
+	"github.com/topboyasante/trunc8/internal/models"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ServiceName is what trunc8 is called in traces, unless OTEL_SERVICE_NAME says otherwise
+const ServiceName = "trunc8"
+
+// Setup installs the global tracer provider and the W3C trace context propagator.
+// Spans go to w with the stdout exporter, or to the collector named by OTEL_EXPORTER_OTLP_ENDPOINT
+// (http://localhost:4318 by default) with the otlp one. With none, the trace context of incoming
+// requests is still read, so their IDs show up in our logs, but nothing is recorded.
+//
+// The returned function flushes the spans that haven't been exported yet; call it on shutdown.
+func Setup(ctx context.Context, cfg config.TracingConfig, w io.Writer) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	switch cfg.Exporter {
+	case "none":
+		return func(context.Context) error { return nil }, nil
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(w))
+	case "otlp":
+		exporter, err = otlptracehttp.New(ctx)
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("creating %s trace exporter: %w", cfg.Exporter, err)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(ServiceName)),
+		// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES win over the defaults above
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("describing the service for traces: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		// A request that comes with a trace is recorded if the caller recorded it, so traces are never cut in half
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// End ends span, recording err on it. Only failures mark the span as failed: domain errors
+// like a link that doesn't exist are answers, and are just noted.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		if !models.IsDomainError(err) {
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+	span.End()
+}
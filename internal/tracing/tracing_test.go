@@ -0,0 +1,99 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/topboyasante/trunc8/internal/config"
+	"github.com/topboyasante/trunc8/internal/models"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// restoreGlobals puts back the tracer provider and propagator Setup replaces
+func restoreGlobals(t *testing.T) {
+	t.Helper()
+
+	provider, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(provider)
+		otel.SetTextMapPropagator(propagator)
+	})
+}
+
+func TestSetup_Stdout(t *testing.T) {
+	restoreGlobals(t)
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	shutdown, err := Setup(ctx, config.TracingConfig{Exporter: "stdout", SampleRatio: 1}, &buf)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	_, span := otel.Tracer("test").Start(ctx, "GET /{code}")
+	span.End()
+
+	// Spans are exported in batches, shutting down flushes them
+	if err := shutdown(ctx); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if !strings.Contains(buf.String(), `"Name":"GET /{code}"`) || !strings.Contains(buf.String(), `"Value":"trunc8"`) {
+		t.Errorf("Expected the span of service trunc8 to be exported, got %s", buf.String())
+	}
+}
+
+func TestSetup_None(t *testing.T) {
+	restoreGlobals(t)
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	shutdown, err := Setup(ctx, config.TracingConfig{Exporter: "none"}, &buf)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	_, span := otel.Tracer("test").Start(ctx, "GET /{code}")
+	span.End()
+	shutdown(ctx)
+
+	if span.IsRecording() || buf.Len() != 0 {
+		t.Error("Expected nothing to be recorded")
+	}
+	if fields := otel.GetTextMapPropagator().Fields(); !slices.Contains(fields, "traceparent") {
+		t.Errorf("Expected the W3C trace context to be propagated, got %v", fields)
+	}
+}
+
+func TestEnd(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+
+	for _, err := range []error{
+		nil,
+		fmt.Errorf("%w: abc", models.ErrNotFound),
+		errors.New("connection refused"),
+	} {
+		_, span := tracer.Start(context.Background(), "span")
+		End(span, err)
+	}
+
+	ended := recorder.Ended()
+	expected := []codes.Code{codes.Unset, codes.Unset, codes.Error}
+	for i, code := range expected {
+		if ended[i].Status().Code != code {
+			t.Errorf("Span %d: expected status %v, got %v", i, code, ended[i].Status().Code)
+		}
+	}
+	if len(ended[1].Events()) != 1 {
+		t.Errorf("Expected a domain error to still be recorded as an event, got %v", ended[1].Events())
+	}
+}
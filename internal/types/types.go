@@ -1,7 +1,31 @@
 package types
 
+import (
+	"time"
+
+	"github.com/topboyasante/trunc8/internal/models"
+)
+
 type ShortenRequest struct {
-	URL string `json:"url"`
+	URL       string     `json:"url"`
+	Alias     string     `json:"alias,omitempty"`      // optional custom code, e.g. "launch-2026"
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // the link stops working after this time
+	MaxClicks int        `json:"max_clicks,omitempty"` // the link stops working after this many clicks
+
+	RedirectType int `json:"redirect_type,omitempty"` // 301, 302, 307 or 308, defaults to the server's setting
+
+	Tags []string `json:"tags,omitempty"` // labels to find the link by later, e.g. "newsletter"
+}
+
+// UpdateLinkRequest is the body of PATCH /api/links/{code}.
+// Only the fields that are present are changed.
+type UpdateLinkRequest struct {
+	URL          *string    `json:"url,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	RemoveExpiry bool       `json:"remove_expiry,omitempty"` // makes the link stop expiring
+	MaxClicks    *int       `json:"max_clicks,omitempty"`    // 0 removes the limit
+	RedirectType *int       `json:"redirect_type,omitempty"` // 0 goes back to the server's setting
+	Tags         []string   `json:"tags"`                    // replaces all tags, [] removes them
 }
 
 type ShortenResponse struct {
@@ -9,6 +33,73 @@ type ShortenResponse struct {
 	OriginalURL string `json:"original_url"`
 }
 
+// BulkShortenResponse is the body of a POST /api/links/bulk response.
+type BulkShortenResponse struct {
+	Created int                 `json:"created"` // links that were created, or already existed for plain URLs
+	Failed  int                 `json:"failed"`
+	Results []BulkShortenResult `json:"results"` // one per link, in the order they were sent
+}
+
+// BulkShortenResult is the outcome of one link of a bulk request: the link, or why there is none.
+type BulkShortenResult struct {
+	Row   int            `json:"row"` // position of the link in the request, from 1, not counting the CSV header
+	Link  *models.URL    `json:"link,omitempty"`
+	Error *ErrorResponse `json:"error,omitempty"`
+}
+
+// ErrorResponse is the body of every error returned by the API.
+type ErrorResponse struct {
+	Error   string `json:"error"`   // machine readable, e.g. "not_found"
+	Message string `json:"message"` // human readable explanation
+}
+
+// RedirectRequest carries the short code along with the details
+// about the visitor that we record for analytics.
 type RedirectRequest struct {
-	Code string
+	Code      string
+	Method    string
+	Referrer  string
+	UserAgent string
+	ClientIP  string
+}
+
+// CreateAPIKeyRequest is the body of POST /api/keys.
+// Keys are issued to a user and can only reach the links of the user's workspace.
+// Admin keys are the exception: they belong to nobody and reach every workspace.
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	UserID string   `json:"user_id,omitempty"`
+}
+
+// CreateAPIKeyResponse is the only time the key itself is shown.
+type CreateAPIKeyResponse struct {
+	*models.APIKey
+	Key string `json:"key"`
+}
+
+// CreateWorkspaceRequest is the body of POST /api/workspaces.
+type CreateWorkspaceRequest struct {
+	Name string `json:"name"`
+}
+
+// CreateUserRequest is the body of POST /api/workspaces/{id}/users.
+type CreateUserRequest struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// HealthResponse is the body of GET /healthz and GET /readyz.
+// Status is "ok" for /healthz, and "ready" or "not_ready" for /readyz.
+type HealthResponse struct {
+	Status string                 `json:"status"`
+	Checks map[string]HealthCheck `json:"checks,omitempty"`
+}
+
+// HealthCheck is the outcome of one of the checks behind /readyz.
+type HealthCheck struct {
+	Status     string                 `json:"status"` // "ok", "error", "pending" or "disabled"
+	Error      string                 `json:"error,omitempty"`
+	Migrations *models.MigrationState `json:"migrations,omitempty"`
+	Cache      *models.CacheStats     `json:"cache,omitempty"`
 }
@@ -1,7 +1,23 @@
 package utils
 
 import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/idna"
 )
 
 func GenerateURLCode() string {
@@ -15,3 +31,236 @@ func GenerateURLCode() string {
 
 	return result
 }
+
+type clientIPKey struct{}
+
+// WithClientIP returns a copy of ctx that carries the IP address of the client, as found by ForwardedClientIP.
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+// ClientIP returns the IP address of the client that sent the request: the one stored with
+// WithClientIP when there is one, or else the peer the request came from.
+func ClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey{}).(string); ok {
+		return ip
+	}
+	return peerIP(r)
+}
+
+// peerIP returns the address the request came from.
+// RemoteAddr looks like "203.0.113.7:51234", so we strip the port.
+func peerIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		// RemoteAddr had no port, use it as is
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ForwardedClientIP returns the IP address of the client behind the proxies in trusted.
+// Only a request that comes from a trusted proxy has its X-Forwarded-For (or else Forwarded)
+// header read, and only from the right, as far as the addresses are trusted proxies too:
+// everything to the left of the first address we don't trust was written by the client.
+func ForwardedClientIP(r *http.Request, trusted []netip.Prefix) string {
+	ip := peerIP(r)
+	if !isTrusted(ip, trusted) {
+		return ip
+	}
+
+	hops := forwardedHops(r.Header)
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(hops[i])
+		if err != nil {
+			// Whoever wrote it can't be trusted, the last proxy is as far as we can tell
+			break
+		}
+		ip = hop.Unmap().String()
+		if !isTrusted(ip, trusted) {
+			break
+		}
+	}
+	return ip
+}
+
+func isTrusted(ip string, trusted []netip.Prefix) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedHops lists the addresses the request was forwarded for, from the client to the last proxy.
+// Ports, the quotes and brackets of Forwarded, and the zone of IPv6 addresses are removed.
+func forwardedHops(header http.Header) []string {
+	var hops []string
+	if values := header.Values("X-Forwarded-For"); len(values) > 0 {
+		for _, value := range values {
+			for _, hop := range strings.Split(value, ",") {
+				hops = append(hops, strings.TrimSpace(hop))
+			}
+		}
+		return hops
+	}
+
+	// Forwarded: for=192.0.2.60;proto=https, for="[2001:db8::17]:4711"
+	for _, value := range header.Values("Forwarded") {
+		for _, element := range strings.Split(value, ",") {
+			hop := ""
+			for _, pair := range strings.Split(element, ";") {
+				name, v, _ := strings.Cut(strings.TrimSpace(pair), "=")
+				if strings.EqualFold(name, "for") {
+					hop = strings.Trim(v, `"`)
+				}
+			}
+			if host, _, err := net.SplitHostPort(hop); err == nil {
+				hop = host
+			}
+			hops = append(hops, strings.Trim(hop, "[]"))
+		}
+	}
+	return hops
+}
+
+// HashIP returns a hex encoded HMAC-SHA256 of an IP address, keyed with secret and the UTC day of t.
+// A plain hash could be reversed by hashing all 2^32 IPv4 addresses; without the secret that's not possible.
+// The key changes every day, so the same visitor can only be recognized within a day, which is all
+// counting unique visitors needs. An empty IP stays empty so we don't count every unknown visitor as the same person.
+func HashIP(secret []byte, ip string, t time.Time) string {
+	if ip == "" {
+		return ""
+	}
+
+	day := hmac.New(sha256.New, secret)
+	day.Write([]byte(t.UTC().Format(time.DateOnly)))
+
+	mac := hmac.New(sha256.New, day.Sum(nil))
+	mac.Write([]byte(ip))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// userAgentFamilies maps a marker found in a User-Agent header to its family.
+// Order matters: Edge and Opera also claim to be Chrome, and Chrome claims to be Safari.
+var userAgentFamilies = []struct {
+	marker string
+	family string
+}{
+	{"bot", "Bot"},
+	{"crawler", "Bot"},
+	{"spider", "Bot"},
+	{"curl/", "curl"},
+	{"wget/", "Wget"},
+	{"edg", "Edge"},
+	{"opr/", "Opera"},
+	{"opera", "Opera"},
+	{"firefox/", "Firefox"},
+	{"fxios/", "Firefox"},
+	{"chrome/", "Chrome"},
+	{"crios/", "Chrome"},
+	{"safari/", "Safari"},
+}
+
+// UserAgentFamily returns the browser family of a User-Agent header, e.g. "Chrome".
+// It's a rough classification that is good enough for analytics.
+func UserAgentFamily(userAgent string) string {
+	if userAgent == "" {
+		return "Unknown"
+	}
+
+	ua := strings.ToLower(userAgent)
+	for _, f := range userAgentFamilies {
+		if strings.Contains(ua, f.marker) {
+			return f.family
+		}
+	}
+	return "Other"
+}
+
+// defaultPorts are left out of normalized URLs, "https://example.com:443" is just "https://example.com"
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// NormalizeURL checks that rawURL is an absolute URL we're willing to redirect to
+// and returns it in a canonical form: lowercase scheme and host, internationalized
+// domain names in punycode and no default port.
+func NormalizeURL(rawURL string, allowedSchemes []string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return "", errors.New("URL could not be parsed")
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme == "" {
+		return "", errors.New("URL must be absolute, e.g. https://example.com")
+	}
+	if !slices.Contains(allowedSchemes, u.Scheme) {
+		return "", fmt.Errorf("scheme %q is not allowed", u.Scheme)
+	}
+
+	// "https://google.com@evil.example" goes to evil.example, a classic phishing trick
+	if u.User != nil {
+		return "", errors.New("URL must not contain credentials")
+	}
+
+	host, err := normalizeHost(u.Hostname())
+	if err != nil {
+		return "", err
+	}
+
+	port := u.Port()
+	if port != "" {
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return "", fmt.Errorf("invalid port %q", port)
+		}
+		if defaultPorts[u.Scheme] == port {
+			port = ""
+		}
+	}
+
+	// IPv6 addresses have to be wrapped in brackets again
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if port != "" {
+		host = host + ":" + port
+	}
+	u.Host = host
+
+	return u.String(), nil
+}
+
+// normalizeHost lowercases a host name and converts it to punycode.
+// IP addresses are accepted as they are, names need at least one dot (or be localhost).
+func normalizeHost(host string) (string, error) {
+	if host == "" {
+		return "", errors.New("URL must have a host")
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String(), nil
+	}
+
+	// The Lookup profile lowercases, converts "bücher.example" to "xn--bcher-kva.example"
+	// and rejects characters that aren't valid in a domain name
+	ascii, err := idna.Lookup.ToASCII(host)
+	if err != nil {
+		return "", fmt.Errorf("invalid host %q", host)
+	}
+	ascii = strings.TrimSuffix(ascii, ".")
+
+	if ascii != "localhost" && !strings.Contains(ascii, ".") {
+		return "", fmt.Errorf("invalid host %q", host)
+	}
+
+	return ascii, nil
+}